@@ -0,0 +1,139 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"context"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const DefaultExportTimeout = "10m"
+
+var exportServer string
+var exportOrganizationID string
+var exportFormat string
+var exportOutput string
+var exportQuery string
+var exportSeparator string
+var exportJoinLists bool
+var exportSingleLabels bool
+var exportFields []string
+var exportTimeout time.Duration
+
+var exportFormats = map[string]grpc_inventory_manager_go.ExportFormat{
+	"csv":   grpc_inventory_manager_go.ExportFormat_CSV,
+	"jsonl": grpc_inventory_manager_go.ExportFormat_JSONL,
+	"yaml":  grpc_inventory_manager_go.ExportFormat_YAML,
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the inventory of an organization",
+	Long:  `Export the inventory of an organization as CSV, JSON Lines or YAML`,
+	Run: func(cmd *cobra.Command, args []string) {
+		SetupLogging()
+		exportInventory()
+	},
+}
+
+func init() {
+	timeout, _ := time.ParseDuration(DefaultExportTimeout)
+
+	rootCmd.AddCommand(exportCmd)
+	exportCmd.Flags().StringVar(&exportServer, "server", "localhost:5510", "Inventory manager address (host:port)")
+	exportCmd.Flags().StringVar(&exportOrganizationID, "organizationId", "", "Organization identifier")
+	exportCmd.Flags().StringVar(&exportFormat, "format", "csv", "Output format: csv, jsonl or yaml")
+	exportCmd.Flags().StringVar(&exportOutput, "output", "", "Output file (stdout if empty)")
+	exportCmd.Flags().StringVar(&exportQuery, "query", "", "Export only the entities matching the query")
+	exportCmd.Flags().StringVar(&exportSeparator, "separator", ".", "Separator between the names of the nested fields")
+	exportCmd.Flags().BoolVar(&exportJoinLists, "joinLists", false, "Write the values of a list in a single field")
+	exportCmd.Flags().BoolVar(&exportSingleLabels, "singleLabels", false, "Write the labels in a single field")
+	exportCmd.Flags().StringSliceVar(&exportFields, "fields", []string{}, "Fields to export (all if empty)")
+	exportCmd.Flags().DurationVar(&exportTimeout, "timeout", timeout, "Maximum time to export the inventory")
+	exportCmd.MarkFlagRequired("organizationId")
+}
+
+func exportInventory() {
+	format, exists := exportFormats[strings.ToLower(exportFormat)]
+	if !exists {
+		log.Fatal().Str("format", exportFormat).Msg("unsupported export format")
+	}
+
+	conn, err := grpc.Dial(exportServer, grpc.WithInsecure())
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot connect with the inventory manager")
+	}
+	defer conn.Close()
+
+	lists := grpc_inventory_manager_go.ListFlattening_INDEX
+	if exportJoinLists {
+		lists = grpc_inventory_manager_go.ListFlattening_JOIN
+	}
+	labels := grpc_inventory_manager_go.LabelFlattening_COLUMNS
+	if exportSingleLabels {
+		labels = grpc_inventory_manager_go.LabelFlattening_SINGLE
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+	client := grpc_inventory_manager_go.NewInventoryClient(conn)
+	stream, err := client.Export(ctx, &grpc_inventory_manager_go.ExportRequest{
+		OrganizationId: exportOrganizationID,
+		Format:         format,
+		Flatten: &grpc_inventory_manager_go.FlattenOptions{
+			Separator: exportSeparator,
+			Lists:     lists,
+			Labels:    labels,
+			Fields:    exportFields,
+		},
+		Query: exportQuery,
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("cannot export the inventory")
+	}
+
+	var output io.Writer = os.Stdout
+	if exportOutput != "" {
+		file, err := os.Create(exportOutput)
+		if err != nil {
+			log.Fatal().Err(err).Str("output", exportOutput).Msg("cannot create output file")
+		}
+		defer file.Close()
+		output = file
+	}
+
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			log.Fatal().Err(err).Msg("error receiving the inventory")
+		}
+		_, err = output.Write(chunk.Data)
+		if err != nil {
+			log.Fatal().Err(err).Msg("cannot write the inventory")
+		}
+	}
+}
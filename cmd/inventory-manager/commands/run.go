@@ -26,6 +26,18 @@ import (
 
 const DefaultControllerStatusThreshold = "10m"
 const DefaultAssetStatusThreshold = "10m"
+const DefaultDeviceStatusThreshold = "10m"
+const DefaultReconciliationPeriod = "1h"
+const DefaultUninstallTimeout = "1h"
+const DefaultUninstallCheckPeriod = "5m"
+const DefaultInventoryTimeout = "30s"
+const DefaultSnapshotPeriod = "24h"
+const DefaultCacheTTL = "30s"
+const DefaultTrendPeriod = "1h"
+const DefaultTrendRetention = "8760h"
+const DefaultRetentionPeriod = "1h"
+const DefaultShutdownGracePeriod = "30s"
+const DefaultBusRetryBackoff = "1s"
 
 var cfg = config.Config{}
 
@@ -46,6 +58,18 @@ func init() {
 
 	controllerThreshold, _ := time.ParseDuration(DefaultControllerStatusThreshold)
 	assetThreshold, _ := time.ParseDuration(DefaultAssetStatusThreshold)
+	deviceThreshold, _ := time.ParseDuration(DefaultDeviceStatusThreshold)
+	reconciliationPeriod, _ := time.ParseDuration(DefaultReconciliationPeriod)
+	uninstallTimeout, _ := time.ParseDuration(DefaultUninstallTimeout)
+	uninstallCheckPeriod, _ := time.ParseDuration(DefaultUninstallCheckPeriod)
+	inventoryTimeout, _ := time.ParseDuration(DefaultInventoryTimeout)
+	snapshotPeriod, _ := time.ParseDuration(DefaultSnapshotPeriod)
+	cacheTTL, _ := time.ParseDuration(DefaultCacheTTL)
+	trendPeriod, _ := time.ParseDuration(DefaultTrendPeriod)
+	trendRetention, _ := time.ParseDuration(DefaultTrendRetention)
+	retentionPeriod, _ := time.ParseDuration(DefaultRetentionPeriod)
+	shutdownGracePeriod, _ := time.ParseDuration(DefaultShutdownGracePeriod)
+	busRetryBackoff, _ := time.ParseDuration(DefaultBusRetryBackoff)
 
 	rootCmd.AddCommand(runCmd)
 	runCmd.Flags().IntVar(&cfg.Port, "port", 5510, "Port to receive management communications")
@@ -69,5 +93,28 @@ func init() {
 	runCmd.Flags().StringVar(&cfg.CACertPath, "caCertPath", "", "CA certificate path")
 	runCmd.Flags().DurationVar(&cfg.ControllerThreshold, "controllerThreshold", controllerThreshold, "Threshold between ping to decide if a controller is offline/online")
 	runCmd.Flags().DurationVar(&cfg.AssetThreshold, "assetThreshold", assetThreshold, "Threshold between ping to decide if an asset is offline/online")
+	runCmd.Flags().DurationVar(&cfg.DeviceThreshold, "deviceThreshold", deviceThreshold, "Threshold between ping to decide if a device is offline/online")
+	runCmd.Flags().DurationVar(&cfg.ReconciliationPeriod, "reconciliationPeriod", reconciliationPeriod, "Time between reconciliation runs (0 to disable)")
+	runCmd.Flags().StringVar(&cfg.ReconciliationPolicy, "reconciliationPolicy", config.ReconciliationReportOnly, "Reconciliation policy: report or repairOrphans")
+	runCmd.Flags().DurationVar(&cfg.UninstallTimeout, "uninstallTimeout", uninstallTimeout, "Maximum time to wait for the confirmation of an agent uninstall")
+	runCmd.Flags().DurationVar(&cfg.UninstallCheckPeriod, "uninstallCheckPeriod", uninstallCheckPeriod, "Time between two checks of the pending agent uninstalls")
+	runCmd.Flags().StringVar(&cfg.UninstallTimeoutPolicy, "uninstallTimeoutPolicy", config.UninstallTimeoutFlag, "Action on uninstall timeout: remove or flag")
+	runCmd.Flags().StringSliceVar(&cfg.ForensicPlugins, "forensicPlugins", []string{}, "Plugins that can be triggered on a quarantined asset")
+	runCmd.Flags().IntVar(&cfg.InventoryParallelism, "inventoryParallelism", 10, "Maximum number of concurrent calls to assemble the inventory")
+	runCmd.Flags().DurationVar(&cfg.InventoryTimeout, "inventoryTimeout", inventoryTimeout, "Maximum time to assemble the inventory of an organization")
+	runCmd.Flags().StringVar(&cfg.StoragePath, "storagePath", "", "Directory to store the inventory manager data (empty to keep it in memory)")
+	runCmd.Flags().DurationVar(&cfg.SnapshotPeriod, "snapshotPeriod", snapshotPeriod, "Time between scheduled inventory snapshots (0 to disable)")
+	runCmd.Flags().DurationVar(&cfg.CacheTTL, "cacheTTL", cacheTTL, "Maximum time the inventory is cached (0 to disable)")
+	runCmd.Flags().IntVar(&cfg.WatchBufferSize, "watchBufferSize", 1000, "Number of inventory events kept per organization to resume a watch")
+	runCmd.Flags().IntVar(&cfg.OperationHistorySize, "operationHistorySize", 50, "Number of operation results kept per edge controller")
+	runCmd.Flags().DurationVar(&cfg.TrendPeriod, "trendPeriod", trendPeriod, "Time between records of the capacity of the organizations (0 to disable)")
+	runCmd.Flags().DurationVar(&cfg.TrendRetention, "trendRetention", trendRetention, "Maximum age of the capacity records")
+	runCmd.Flags().DurationVar(&cfg.RetentionPeriod, "retentionPeriod", retentionPeriod, "Time between evaluations of the retention policies (0 to disable)")
+	runCmd.Flags().DurationVar(&cfg.ShutdownGracePeriod, "shutdownGracePeriod", shutdownGracePeriod, "Maximum time to finish the in-flight requests and queue messages on shutdown")
+	runCmd.Flags().IntVar(&cfg.BusMaxAttempts, "busMaxAttempts", 5, "Maximum number of attempts to process a queue message before storing it as a dead letter")
+	runCmd.Flags().DurationVar(&cfg.BusRetryBackoff, "busRetryBackoff", busRetryBackoff, "Time to wait before retrying a queue message, doubled on every attempt")
+	runCmd.Flags().IntVar(&cfg.BusWorkers, "busWorkers", 4, "Number of workers processing each type of queue message")
+	runCmd.Flags().StringToIntVar(&cfg.BusTypeWorkers, "busTypeWorkers", map[string]int{}, "Number of workers of a type of queue message overriding busWorkers, e.g. AgentsAlive=16")
+	runCmd.Flags().IntVar(&cfg.BusBufferSize, "busBufferSize", 100, "Number of queue messages that can be pending per consumer and worker")
 
 }
@@ -0,0 +1,126 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"context"
+	"github.com/nalej/grpc-common-go"
+	"github.com/nalej/grpc-device-go"
+	"github.com/nalej/grpc-device-manager-go"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"google.golang.org/grpc"
+)
+
+// The clients below wrap the clients of system-model and the device manager so every modification made by the
+// inventory manager, either requested through the API or triggered by a bus message, updates the cache.
+
+type assetsClient struct {
+	grpc_inventory_go.AssetsClient
+	cache *InventoryCache
+}
+
+// NewAssetsClient returns an assets client that updates the cache when the assets are modified.
+func NewAssetsClient(client grpc_inventory_go.AssetsClient, cache *InventoryCache) grpc_inventory_go.AssetsClient {
+	return &assetsClient{AssetsClient: client, cache: cache}
+}
+
+func (ac *assetsClient) Add(ctx context.Context, in *grpc_inventory_go.AddAssetRequest, opts ...grpc.CallOption) (*grpc_inventory_go.Asset, error) {
+	added, err := ac.AssetsClient.Add(ctx, in, opts...)
+	if err == nil {
+		ac.cache.InvalidateAsset(in.OrganizationId, added.AssetId)
+	}
+	return added, err
+}
+
+func (ac *assetsClient) Remove(ctx context.Context, in *grpc_inventory_go.AssetId, opts ...grpc.CallOption) (*grpc_common_go.Success, error) {
+	// the asset may have been removed even if the call fails
+	defer ac.cache.InvalidateAsset(in.OrganizationId, in.AssetId)
+	return ac.AssetsClient.Remove(ctx, in, opts...)
+}
+
+func (ac *assetsClient) Update(ctx context.Context, in *grpc_inventory_go.UpdateAssetRequest, opts ...grpc.CallOption) (*grpc_inventory_go.Asset, error) {
+	updated, err := ac.AssetsClient.Update(ctx, in, opts...)
+	if entities.IsAssetAliveUpdate(in) {
+		if err == nil {
+			ac.cache.AssetAlive(in.OrganizationId, in.AssetId, in.LastAliveTimestamp, in.UpdateIp, in.EicNetIp)
+		}
+	} else {
+		ac.cache.InvalidateAsset(in.OrganizationId, in.AssetId)
+	}
+	return updated, err
+}
+
+type controllersClient struct {
+	grpc_inventory_go.ControllersClient
+	cache *InventoryCache
+}
+
+// NewControllersClient returns a controllers client that updates the cache when the controllers are modified.
+func NewControllersClient(client grpc_inventory_go.ControllersClient, cache *InventoryCache) grpc_inventory_go.ControllersClient {
+	return &controllersClient{ControllersClient: client, cache: cache}
+}
+
+func (cc *controllersClient) Add(ctx context.Context, in *grpc_inventory_go.AddEdgeControllerRequest, opts ...grpc.CallOption) (*grpc_inventory_go.EdgeController, error) {
+	added, err := cc.ControllersClient.Add(ctx, in, opts...)
+	if err == nil {
+		cc.cache.InvalidateController(in.OrganizationId, added.EdgeControllerId)
+	}
+	return added, err
+}
+
+func (cc *controllersClient) Remove(ctx context.Context, in *grpc_inventory_go.EdgeControllerId, opts ...grpc.CallOption) (*grpc_common_go.Success, error) {
+	defer cc.cache.InvalidateController(in.OrganizationId, in.EdgeControllerId)
+	return cc.ControllersClient.Remove(ctx, in, opts...)
+}
+
+func (cc *controllersClient) Update(ctx context.Context, in *grpc_inventory_go.UpdateEdgeControllerRequest, opts ...grpc.CallOption) (*grpc_inventory_go.EdgeController, error) {
+	updated, err := cc.ControllersClient.Update(ctx, in, opts...)
+	if entities.IsControllerAliveUpdate(in) {
+		if err == nil {
+			cc.cache.ControllerAlive(in.OrganizationId, in.EdgeControllerId, in.LastAliveTimestamp)
+		}
+	} else {
+		cc.cache.InvalidateController(in.OrganizationId, in.EdgeControllerId)
+	}
+	return updated, err
+}
+
+type devicesClient struct {
+	grpc_device_manager_go.DevicesClient
+	cache *InventoryCache
+}
+
+// NewDevicesClient returns a device manager client that updates the cache when the devices are modified.
+func NewDevicesClient(client grpc_device_manager_go.DevicesClient, cache *InventoryCache) grpc_device_manager_go.DevicesClient {
+	return &devicesClient{DevicesClient: client, cache: cache}
+}
+
+func (dc *devicesClient) UpdateDevice(ctx context.Context, in *grpc_device_manager_go.UpdateDeviceRequest, opts ...grpc.CallOption) (*grpc_device_manager_go.Device, error) {
+	defer dc.cache.InvalidateDevices(in.OrganizationId)
+	return dc.DevicesClient.UpdateDevice(ctx, in, opts...)
+}
+
+func (dc *devicesClient) UpdateDeviceLocation(ctx context.Context, in *grpc_device_manager_go.UpdateDeviceLocationRequest, opts ...grpc.CallOption) (*grpc_device_manager_go.Device, error) {
+	defer dc.cache.InvalidateDevices(in.OrganizationId)
+	return dc.DevicesClient.UpdateDeviceLocation(ctx, in, opts...)
+}
+
+func (dc *devicesClient) RemoveDevice(ctx context.Context, in *grpc_device_go.DeviceId, opts ...grpc.CallOption) (*grpc_common_go.Success, error) {
+	defer dc.cache.InvalidateDevices(in.OrganizationId)
+	return dc.DevicesClient.RemoveDevice(ctx, in, opts...)
+}
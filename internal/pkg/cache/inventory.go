@@ -0,0 +1,378 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"github.com/nalej/grpc-inventory-manager-go"
+	"sync"
+	"time"
+)
+
+// InventoryCache keeps the inventory retrieved from system-model and the device manager for a limited time. The
+// entries are invalidated when the entities are modified and the alive messages are applied to the cached entities,
+// so the status of an entity only depends on the TTL when it stops sending alive messages. The cached values are
+// shared between the callers and must not be modified. The entry of an organization is only created when a value is
+// stored and it is evicted once all its values have expired.
+type InventoryCache struct {
+	sync.Mutex
+	ttl time.Duration
+	// generation is increased on every invalidation so the values retrieved before it are not stored.
+	generation uint64
+	// missingInvalidated with the generation of the last invalidation of an organization without entry.
+	missingInvalidated uint64
+	// nextEviction is the time when the expired organizations are evicted next.
+	nextEviction time.Time
+	// organizations indexed by organization_id.
+	organizations map[string]*organizationEntry
+}
+
+type organizationEntry struct {
+	// invalidated with the generation of the last invalidation of the organization.
+	invalidated uint64
+	list        *grpc_inventory_manager_go.InventoryList
+	listExpires time.Time
+	// assets indexed by asset_id.
+	assets map[string]assetEntry
+	// controllers indexed by edge_controller_id.
+	controllers map[string]controllerEntry
+}
+
+type assetEntry struct {
+	asset   *grpc_inventory_manager_go.Asset
+	expires time.Time
+}
+
+type controllerEntry struct {
+	controller *grpc_inventory_manager_go.EdgeController
+	assets     []*grpc_inventory_manager_go.Asset
+	expires    time.Time
+}
+
+// NewInventoryCache creates a cache whose entries expire after the given TTL. A zero TTL disables the cache.
+func NewInventoryCache(ttl time.Duration) *InventoryCache {
+	return &InventoryCache{
+		ttl:           ttl,
+		organizations: make(map[string]*organizationEntry, 0),
+	}
+}
+
+func (c *InventoryCache) enabled() bool {
+	return c != nil && c.ttl > 0
+}
+
+// writableOrganization returns the entry of an organization to store values retrieved at the given generation,
+// creating it if needed. Nil is returned if the organization has been invalidated since then. The lock must be held.
+func (c *InventoryCache) writableOrganization(organizationID string, generation uint64) *organizationEntry {
+	c.evictExpired(time.Now())
+	entry, exists := c.organizations[organizationID]
+	if !exists {
+		if c.missingInvalidated > generation {
+			return nil
+		}
+		entry = &organizationEntry{
+			assets:      make(map[string]assetEntry, 0),
+			controllers: make(map[string]controllerEntry, 0),
+		}
+		c.organizations[organizationID] = entry
+		return entry
+	}
+	if entry.invalidated > generation {
+		return nil
+	}
+	return entry
+}
+
+// invalidate records the invalidation of an organization and returns its entry, or nil if it has none. The lock must
+// be held.
+func (c *InventoryCache) invalidate(organizationID string) *organizationEntry {
+	c.generation++
+	entry, exists := c.organizations[organizationID]
+	if !exists {
+		c.missingInvalidated = c.generation
+		return nil
+	}
+	entry.invalidated = c.generation
+	entry.list = nil
+	return entry
+}
+
+// evictExpired removes the expired values and the organizations without values. It runs at most once per TTL. The
+// lock must be held.
+func (c *InventoryCache) evictExpired(now time.Time) {
+	if now.Before(c.nextEviction) {
+		return
+	}
+	c.nextEviction = now.Add(c.ttl)
+	for organizationID, entry := range c.organizations {
+		if entry.list != nil && now.After(entry.listExpires) {
+			entry.list = nil
+		}
+		for assetID, cached := range entry.assets {
+			if now.After(cached.expires) {
+				delete(entry.assets, assetID)
+			}
+		}
+		for edgeControllerID, cached := range entry.controllers {
+			if now.After(cached.expires) {
+				delete(entry.controllers, edgeControllerID)
+			}
+		}
+		if entry.list == nil && len(entry.assets) == 0 && len(entry.controllers) == 0 {
+			// the values retrieved before its last invalidation must still be rejected
+			if entry.invalidated > c.missingInvalidated {
+				c.missingInvalidated = entry.invalidated
+			}
+			delete(c.organizations, organizationID)
+		}
+	}
+}
+
+// Generation returns the current generation of the cache. It must be obtained before retrieving the values that are
+// going to be stored.
+func (c *InventoryCache) Generation(organizationID string) uint64 {
+	if !c.enabled() {
+		return 0
+	}
+	c.Lock()
+	defer c.Unlock()
+	return c.generation
+}
+
+// GetList returns the cached inventory of an organization.
+func (c *InventoryCache) GetList(organizationID string) (*grpc_inventory_manager_go.InventoryList, bool) {
+	if !c.enabled() {
+		return nil, false
+	}
+	c.Lock()
+	defer c.Unlock()
+	entry, exists := c.organizations[organizationID]
+	if !exists || entry.list == nil || time.Now().After(entry.listExpires) {
+		return nil, false
+	}
+	return entry.list, true
+}
+
+// SetList stores the inventory of an organization unless it has been invalidated since the given generation.
+func (c *InventoryCache) SetList(organizationID string, generation uint64, list *grpc_inventory_manager_go.InventoryList) {
+	if !c.enabled() {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	entry := c.writableOrganization(organizationID, generation)
+	if entry == nil {
+		return
+	}
+	entry.list = list
+	entry.listExpires = time.Now().Add(c.ttl)
+}
+
+// GetAsset returns a cached asset.
+func (c *InventoryCache) GetAsset(organizationID string, assetID string) (*grpc_inventory_manager_go.Asset, bool) {
+	if !c.enabled() {
+		return nil, false
+	}
+	c.Lock()
+	defer c.Unlock()
+	entry, exists := c.organizations[organizationID]
+	if !exists {
+		return nil, false
+	}
+	cached, exists := entry.assets[assetID]
+	if !exists || time.Now().After(cached.expires) {
+		return nil, false
+	}
+	return cached.asset, true
+}
+
+// SetAsset stores an asset unless the organization has been invalidated since the given generation.
+func (c *InventoryCache) SetAsset(organizationID string, generation uint64, asset *grpc_inventory_manager_go.Asset) {
+	if !c.enabled() {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	entry := c.writableOrganization(organizationID, generation)
+	if entry == nil {
+		return
+	}
+	entry.assets[asset.AssetId] = assetEntry{asset: asset, expires: time.Now().Add(c.ttl)}
+}
+
+// GetController returns a cached controller with its assets.
+func (c *InventoryCache) GetController(organizationID string, edgeControllerID string) (*grpc_inventory_manager_go.EdgeController, []*grpc_inventory_manager_go.Asset, bool) {
+	if !c.enabled() {
+		return nil, nil, false
+	}
+	c.Lock()
+	defer c.Unlock()
+	entry, exists := c.organizations[organizationID]
+	if !exists {
+		return nil, nil, false
+	}
+	cached, exists := entry.controllers[edgeControllerID]
+	if !exists || time.Now().After(cached.expires) {
+		return nil, nil, false
+	}
+	return cached.controller, cached.assets, true
+}
+
+// SetController stores a controller with its assets unless the organization has been invalidated since the
+// given generation.
+func (c *InventoryCache) SetController(organizationID string, generation uint64, controller *grpc_inventory_manager_go.EdgeController, assets []*grpc_inventory_manager_go.Asset) {
+	if !c.enabled() {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	entry := c.writableOrganization(organizationID, generation)
+	if entry == nil {
+		return
+	}
+	entry.controllers[controller.EdgeControllerId] = controllerEntry{
+		controller: controller,
+		assets:     assets,
+		expires:    time.Now().Add(c.ttl),
+	}
+}
+
+// InvalidateAsset removes the cached values that may include an asset.
+func (c *InventoryCache) InvalidateAsset(organizationID string, assetID string) {
+	if !c.enabled() {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	entry := c.invalidate(organizationID)
+	if entry == nil {
+		return
+	}
+	delete(entry.assets, assetID)
+	// the controller of the asset may not be known, e.g. when it is removed
+	entry.controllers = make(map[string]controllerEntry, 0)
+}
+
+// InvalidateController removes the cached values that may include a controller or its assets.
+func (c *InventoryCache) InvalidateController(organizationID string, edgeControllerID string) {
+	if !c.enabled() {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	entry := c.invalidate(organizationID)
+	if entry == nil {
+		return
+	}
+	delete(entry.controllers, edgeControllerID)
+	for assetID, cached := range entry.assets {
+		if cached.asset.EdgeControllerId == edgeControllerID {
+			delete(entry.assets, assetID)
+		}
+	}
+}
+
+// InvalidateDevices removes the cached values that include the devices of an organization.
+func (c *InventoryCache) InvalidateDevices(organizationID string) {
+	if !c.enabled() {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.invalidate(organizationID)
+}
+
+// AssetAlive applies an alive message of an asset to the cached values. The entities are copied so the
+// values already returned are not modified.
+func (c *InventoryCache) AssetAlive(organizationID string, assetID string, timestamp int64, updateIP bool, ip string) {
+	if !c.enabled() {
+		return
+	}
+	update := func(asset *grpc_inventory_manager_go.Asset) *grpc_inventory_manager_go.Asset {
+		if asset.AssetId != assetID {
+			return asset
+		}
+		updated := *asset
+		updated.LastAliveTimestamp = timestamp
+		updated.Status = grpc_inventory_manager_go.ConnectedStatus_ONLINE
+		if updateIP {
+			updated.EicNetIp = ip
+		}
+		return &updated
+	}
+	c.Lock()
+	defer c.Unlock()
+	entry, exists := c.organizations[organizationID]
+	if !exists {
+		return
+	}
+	if entry.list != nil {
+		list := *entry.list
+		list.Assets = updateAssets(list.Assets, update)
+		entry.list = &list
+	}
+	if cached, exists := entry.assets[assetID]; exists {
+		cached.asset = update(cached.asset)
+		entry.assets[assetID] = cached
+	}
+	for edgeControllerID, cached := range entry.controllers {
+		cached.assets = updateAssets(cached.assets, update)
+		entry.controllers[edgeControllerID] = cached
+	}
+}
+
+// ControllerAlive applies an alive message of a controller to the cached values. The entities are copied so
+// the values already returned are not modified.
+func (c *InventoryCache) ControllerAlive(organizationID string, edgeControllerID string, timestamp int64) {
+	if !c.enabled() {
+		return
+	}
+	update := func(controller *grpc_inventory_manager_go.EdgeController) *grpc_inventory_manager_go.EdgeController {
+		updated := *controller
+		updated.LastAliveTimestamp = timestamp
+		updated.Status = grpc_inventory_manager_go.ConnectedStatus_ONLINE
+		return &updated
+	}
+	c.Lock()
+	defer c.Unlock()
+	entry, exists := c.organizations[organizationID]
+	if !exists {
+		return
+	}
+	if entry.list != nil {
+		list := *entry.list
+		list.Controllers = make([]*grpc_inventory_manager_go.EdgeController, 0, len(entry.list.Controllers))
+		for _, controller := range entry.list.Controllers {
+			if controller.EdgeControllerId == edgeControllerID {
+				controller = update(controller)
+			}
+			list.Controllers = append(list.Controllers, controller)
+		}
+		entry.list = &list
+	}
+	if cached, exists := entry.controllers[edgeControllerID]; exists {
+		cached.controller = update(cached.controller)
+		entry.controllers[edgeControllerID] = cached
+	}
+}
+
+func updateAssets(assets []*grpc_inventory_manager_go.Asset, update func(*grpc_inventory_manager_go.Asset) *grpc_inventory_manager_go.Asset) []*grpc_inventory_manager_go.Asset {
+	result := make([]*grpc_inventory_manager_go.Asset, 0, len(assets))
+	for _, asset := range assets {
+		result = append(result, update(asset))
+	}
+	return result
+}
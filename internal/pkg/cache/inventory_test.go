@@ -0,0 +1,131 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"time"
+)
+
+var _ = ginkgo.Describe("Inventory cache", func() {
+
+	const organizationID = "org"
+
+	var cache *InventoryCache
+	var list *grpc_inventory_manager_go.InventoryList
+
+	ginkgo.BeforeEach(func() {
+		cache = NewInventoryCache(time.Minute)
+		list = &grpc_inventory_manager_go.InventoryList{
+			Assets: []*grpc_inventory_manager_go.Asset{{
+				OrganizationId:   organizationID,
+				EdgeControllerId: "ec",
+				AssetId:          "asset",
+				Status:           grpc_inventory_manager_go.ConnectedStatus_OFFLINE,
+			}},
+			Controllers: []*grpc_inventory_manager_go.EdgeController{{
+				OrganizationId:   organizationID,
+				EdgeControllerId: "ec",
+				Status:           grpc_inventory_manager_go.ConnectedStatus_OFFLINE,
+			}},
+		}
+	})
+
+	ginkgo.It("should not store the values retrieved before an invalidation", func() {
+		generation := cache.Generation(organizationID)
+		cache.InvalidateAsset(organizationID, "asset")
+		cache.SetList(organizationID, generation, list)
+		_, exists := cache.GetList(organizationID)
+		gomega.Expect(exists).To(gomega.BeFalse())
+
+		cache.SetList(organizationID, cache.Generation(organizationID), list)
+		cached, exists := cache.GetList(organizationID)
+		gomega.Expect(exists).To(gomega.BeTrue())
+		gomega.Expect(cached).To(gomega.Equal(list))
+	})
+
+	ginkgo.It("should remove the assets of an invalidated controller", func() {
+		generation := cache.Generation(organizationID)
+		cache.SetAsset(organizationID, generation, list.Assets[0])
+		cache.SetController(organizationID, generation, list.Controllers[0], list.Assets)
+		cache.InvalidateController(organizationID, "ec")
+		_, exists := cache.GetAsset(organizationID, "asset")
+		gomega.Expect(exists).To(gomega.BeFalse())
+		_, _, exists = cache.GetController(organizationID, "ec")
+		gomega.Expect(exists).To(gomega.BeFalse())
+	})
+
+	ginkgo.It("should apply the alive messages without modifying the values already returned", func() {
+		cache.SetList(organizationID, cache.Generation(organizationID), list)
+		cache.AssetAlive(organizationID, "asset", 1000, true, "10.0.0.1")
+		cache.ControllerAlive(organizationID, "ec", 2000)
+
+		cached, exists := cache.GetList(organizationID)
+		gomega.Expect(exists).To(gomega.BeTrue())
+		gomega.Expect(cached.Assets[0].LastAliveTimestamp).To(gomega.Equal(int64(1000)))
+		gomega.Expect(cached.Assets[0].EicNetIp).To(gomega.Equal("10.0.0.1"))
+		gomega.Expect(cached.Assets[0].Status).To(gomega.Equal(grpc_inventory_manager_go.ConnectedStatus_ONLINE))
+		gomega.Expect(cached.Controllers[0].LastAliveTimestamp).To(gomega.Equal(int64(2000)))
+		gomega.Expect(list.Assets[0].Status).To(gomega.Equal(grpc_inventory_manager_go.ConnectedStatus_OFFLINE))
+		gomega.Expect(list.Controllers[0].LastAliveTimestamp).To(gomega.BeZero())
+	})
+
+	ginkgo.It("should only create the entries of the organizations when storing values", func() {
+		cache.Generation(organizationID)
+		cache.GetList(organizationID)
+		cache.GetAsset(organizationID, "asset")
+		cache.GetController(organizationID, "ec")
+		cache.AssetAlive(organizationID, "asset", 1000, false, "")
+		cache.InvalidateDevices(organizationID)
+		gomega.Expect(cache.organizations).To(gomega.BeEmpty())
+
+		cache.SetList(organizationID, cache.Generation(organizationID), list)
+		gomega.Expect(cache.organizations).To(gomega.HaveKey(organizationID))
+	})
+
+	ginkgo.It("should evict the organizations whose values have expired", func() {
+		short := NewInventoryCache(time.Millisecond)
+		short.SetAsset(organizationID, short.Generation(organizationID), list.Assets[0])
+		gomega.Expect(short.organizations).To(gomega.HaveKey(organizationID))
+		time.Sleep(5 * time.Millisecond)
+		short.SetList("other", short.Generation("other"), list)
+		gomega.Expect(short.organizations).NotTo(gomega.HaveKey(organizationID))
+		gomega.Expect(short.organizations).To(gomega.HaveKey("other"))
+	})
+
+	ginkgo.It("should not store the values retrieved before the invalidation of an evicted organization", func() {
+		short := NewInventoryCache(time.Millisecond)
+		short.SetList(organizationID, short.Generation(organizationID), list)
+		generation := short.Generation(organizationID)
+		short.InvalidateAsset(organizationID, "asset")
+		time.Sleep(5 * time.Millisecond)
+		short.SetList("other", short.Generation("other"), list)
+		gomega.Expect(short.organizations).NotTo(gomega.HaveKey(organizationID))
+
+		short.SetAsset(organizationID, generation, list.Assets[0])
+		gomega.Expect(short.organizations).NotTo(gomega.HaveKey(organizationID))
+	})
+
+	ginkgo.It("should not store values when it is disabled", func() {
+		disabled := NewInventoryCache(0)
+		disabled.SetList(organizationID, disabled.Generation(organizationID), list)
+		_, exists := disabled.GetList(organizationID)
+		gomega.Expect(exists).To(gomega.BeFalse())
+	})
+})
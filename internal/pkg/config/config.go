@@ -55,8 +55,67 @@ type Config struct {
 	ControllerThreshold time.Duration
 	// AssetThreshold maximum time (seconds) between ping to decide if an asset is offline or online
 	AssetThreshold time.Duration
+	// DeviceThreshold maximum time (seconds) between ping to decide if a device is offline or online
+	DeviceThreshold time.Duration
+	// ReconciliationPeriod time between two reconciliation runs. Zero disables the periodic reconciliation.
+	ReconciliationPeriod time.Duration
+	// ReconciliationPolicy determines if the inconsistencies found by the reconciliation are repaired or only reported.
+	ReconciliationPolicy string
+	// UninstallTimeout maximum time to wait for the confirmation of an agent uninstall.
+	UninstallTimeout time.Duration
+	// UninstallCheckPeriod time between two checks of the pending uninstalls. Each check lists the assets of all the
+	// organizations.
+	UninstallCheckPeriod time.Duration
+	// UninstallTimeoutPolicy determines what happens to the assets whose uninstall is not confirmed on time.
+	UninstallTimeoutPolicy string
+	// ForensicPlugins with the plugins that can be triggered on a quarantined asset.
+	ForensicPlugins []string
+	// InventoryParallelism maximum number of concurrent calls made to assemble the inventory.
+	InventoryParallelism int
+	// InventoryTimeout maximum time to assemble the inventory of an organization.
+	InventoryTimeout time.Duration
+	// StoragePath with the directory where the inventory manager keeps its own data. If empty the data is kept in memory.
+	StoragePath string
+	// SnapshotPeriod time between two scheduled inventory snapshots. Zero disables the scheduled snapshots.
+	SnapshotPeriod time.Duration
+	// CacheTTL maximum time the inventory retrieved from system-model and the device manager is kept in memory. Zero disables the cache.
+	CacheTTL time.Duration
+	// WatchBufferSize number of events kept for each organization so the watchers can resume after reconnecting.
+	WatchBufferSize int
+	// OperationHistorySize number of operation results kept for each edge controller.
+	OperationHistorySize int
+	// TrendPeriod time between two records of the capacity of the organizations. Zero disables the records.
+	TrendPeriod time.Duration
+	// TrendRetention maximum age of the capacity records.
+	TrendRetention time.Duration
+	// RetentionPeriod time between two evaluations of the retention policies. Zero disables the evaluation.
+	RetentionPeriod time.Duration
+	// ShutdownGracePeriod maximum time to finish the in-flight requests and messages on shutdown.
+	ShutdownGracePeriod time.Duration
+	// BusMaxAttempts maximum number of attempts to process a message received from the bus.
+	BusMaxAttempts int
+	// BusRetryBackoff time to wait before retrying a message, doubled on every attempt.
+	BusRetryBackoff time.Duration
+	// BusWorkers number of workers processing each type of message received from the bus.
+	BusWorkers int
+	// BusTypeWorkers number of workers of the message types that do not use BusWorkers, by message type name.
+	BusTypeWorkers map[string]int
+	// BusBufferSize number of messages received from the bus that can be pending per consumer and worker.
+	BusBufferSize int
 }
 
+// ReconciliationReportOnly policy to report inconsistencies without modifying any component.
+const ReconciliationReportOnly = "report"
+
+// ReconciliationRepairOrphans policy to remove the orphaned assets, VPN users and DNS entries.
+const ReconciliationRepairOrphans = "repairOrphans"
+
+// UninstallTimeoutRemove policy to remove the assets whose uninstall is not confirmed on time.
+const UninstallTimeoutRemove = "remove"
+
+// UninstallTimeoutFlag policy to flag the assets whose uninstall is not confirmed on time so they can be reviewed.
+const UninstallTimeoutFlag = "flag"
+
 func (conf *Config) Validate() derrors.Error {
 
 	if conf.Port <= 0 {
@@ -98,6 +157,72 @@ func (conf *Config) Validate() derrors.Error {
 		return derrors.NewInvalidArgumentError("caCertPath cannot be empty")
 	}
 
+	if conf.ReconciliationPeriod < 0 {
+		return derrors.NewInvalidArgumentError("reconciliationPeriod cannot be negative")
+	}
+	if conf.ReconciliationPolicy != ReconciliationReportOnly && conf.ReconciliationPolicy != ReconciliationRepairOrphans {
+		return derrors.NewInvalidArgumentError("reconciliationPolicy must be report or repairOrphans")
+	}
+
+	if conf.UninstallTimeout <= 0 {
+		return derrors.NewInvalidArgumentError("uninstallTimeout must be positive")
+	}
+	if conf.UninstallCheckPeriod <= 0 {
+		return derrors.NewInvalidArgumentError("uninstallCheckPeriod must be positive")
+	}
+	if conf.UninstallTimeoutPolicy != UninstallTimeoutRemove && conf.UninstallTimeoutPolicy != UninstallTimeoutFlag {
+		return derrors.NewInvalidArgumentError("uninstallTimeoutPolicy must be remove or flag")
+	}
+
+	if conf.InventoryParallelism <= 0 {
+		return derrors.NewInvalidArgumentError("inventoryParallelism must be positive")
+	}
+	if conf.InventoryTimeout <= 0 {
+		return derrors.NewInvalidArgumentError("inventoryTimeout must be positive")
+	}
+
+	if conf.SnapshotPeriod < 0 {
+		return derrors.NewInvalidArgumentError("snapshotPeriod cannot be negative")
+	}
+	if conf.CacheTTL < 0 {
+		return derrors.NewInvalidArgumentError("cacheTTL cannot be negative")
+	}
+	if conf.WatchBufferSize <= 0 {
+		return derrors.NewInvalidArgumentError("watchBufferSize must be positive")
+	}
+	if conf.OperationHistorySize <= 0 {
+		return derrors.NewInvalidArgumentError("operationHistorySize must be positive")
+	}
+	if conf.TrendPeriod < 0 {
+		return derrors.NewInvalidArgumentError("trendPeriod cannot be negative")
+	}
+	if conf.TrendRetention <= 0 {
+		return derrors.NewInvalidArgumentError("trendRetention must be positive")
+	}
+	if conf.RetentionPeriod < 0 {
+		return derrors.NewInvalidArgumentError("retentionPeriod cannot be negative")
+	}
+	if conf.ShutdownGracePeriod <= 0 {
+		return derrors.NewInvalidArgumentError("shutdownGracePeriod must be positive")
+	}
+	if conf.BusMaxAttempts < 1 {
+		return derrors.NewInvalidArgumentError("busMaxAttempts must be at least 1")
+	}
+	if conf.BusRetryBackoff <= 0 {
+		return derrors.NewInvalidArgumentError("busRetryBackoff must be positive")
+	}
+	if conf.BusWorkers < 1 {
+		return derrors.NewInvalidArgumentError("busWorkers must be at least 1")
+	}
+	for messageType, workers := range conf.BusTypeWorkers {
+		if workers < 1 {
+			return derrors.NewInvalidArgumentError("busTypeWorkers must be at least 1").WithParams(messageType)
+		}
+	}
+	if conf.BusBufferSize < 1 {
+		return derrors.NewInvalidArgumentError("busBufferSize must be at least 1")
+	}
+
 	err := conf.loadCACert()
 	if err != nil {
 		return err
@@ -119,7 +244,27 @@ func (conf *Config) Print() {
 	log.Info().Str("URL", conf.NetworkManagerAddress).Msg("Network Manager")
 	log.Info().Str("URL", conf.EdgeInventoryProxyAddress).Msg("Edge Inventory Proxy")
 	log.Info().Str("Cert Path", conf.CACertPath).Msg("CA files")
-	log.Info().Str("EdgeController", conf.ControllerThreshold.String()).Str("Asset", conf.AssetThreshold.String()).Msg("Online/Offline Threshold")
+	log.Info().Str("EdgeController", conf.ControllerThreshold.String()).Str("Asset", conf.AssetThreshold.String()).Str("Device", conf.DeviceThreshold.String()).Msg("Online/Offline Threshold")
+	log.Info().Str("period", conf.ReconciliationPeriod.String()).Str("policy", conf.ReconciliationPolicy).Msg("Reconciliation")
+	log.Info().Str("timeout", conf.UninstallTimeout.String()).Str("check_period", conf.UninstallCheckPeriod.String()).
+		Str("policy", conf.UninstallTimeoutPolicy).Msg("Agent uninstall")
+	log.Info().Strs("plugins", conf.ForensicPlugins).Msg("Forensic plugins allowed in quarantine")
+	log.Info().Int("parallelism", conf.InventoryParallelism).Str("timeout", conf.InventoryTimeout.String()).Msg("Inventory listing")
+	if conf.StoragePath == "" {
+		log.Warn().Msg("Local storage in memory, the audit log, dead letters, operation history, saved queries, snapshots, trends, archive and attribute schemas are lost on restart")
+	} else {
+		log.Info().Str("path", conf.StoragePath).Msg("Local storage")
+	}
+	log.Info().Str("period", conf.SnapshotPeriod.String()).Msg("Inventory snapshots")
+	log.Info().Str("ttl", conf.CacheTTL.String()).Msg("Inventory cache")
+	log.Info().Int("buffer", conf.WatchBufferSize).Msg("Inventory watch")
+	log.Info().Int("size", conf.OperationHistorySize).Msg("Operation history")
+	log.Info().Str("period", conf.TrendPeriod.String()).Str("retention", conf.TrendRetention.String()).Msg("Capacity trends")
+	log.Info().Str("period", conf.RetentionPeriod.String()).Msg("Retention policies")
+	log.Info().Str("grace_period", conf.ShutdownGracePeriod.String()).Msg("Shutdown")
+	log.Info().Int("max_attempts", conf.BusMaxAttempts).Str("backoff", conf.BusRetryBackoff.String()).Msg("Bus retries")
+	log.Info().Int("workers", conf.BusWorkers).Interface("type_workers", conf.BusTypeWorkers).
+		Int("buffer_size", conf.BusBufferSize).Msg("Bus consumers")
 }
 
 // LoadCert loads the CA certificate in memory.
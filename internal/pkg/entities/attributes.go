@@ -0,0 +1,126 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package entities
+
+import (
+	"fmt"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AttributeDateLayout with the format of the values of the date attributes.
+const AttributeDateLayout = "2006-01-02"
+
+// MaxAttributeValueLength with the maximum length of the value of an attribute.
+const MaxAttributeValueLength = 1024
+
+// attributeNameRegex restricts the names so they can be used as query fields and label keys.
+var attributeNameRegex = regexp.MustCompile("^[a-z][a-z0-9_]{0,62}$")
+
+// AttributeLabel returns the label that stores the value of an attribute.
+func AttributeLabel(name string) string {
+	return AttributeLabelPrefix + name
+}
+
+// AttributesFromLabels returns the custom attributes stored in the labels of an entity.
+func AttributesFromLabels(labels map[string]string) map[string]string {
+	var result map[string]string
+	for key, value := range labels {
+		if !strings.HasPrefix(key, AttributeLabelPrefix) {
+			continue
+		}
+		if result == nil {
+			result = make(map[string]string)
+		}
+		result[strings.TrimPrefix(key, AttributeLabelPrefix)] = value
+	}
+	return result
+}
+
+// FindAttribute returns the definition of an attribute in a schema, or nil if it is not defined.
+func FindAttribute(schema *grpc_inventory_manager_go.AttributeSchema, name string) *grpc_inventory_manager_go.AttributeDefinition {
+	for _, definition := range schema.Attributes {
+		if definition.Name == name {
+			return definition
+		}
+	}
+	return nil
+}
+
+// AttributeAppliesTo checks if an attribute can be set on a type of entity. An attribute without entity types
+// applies to all of them.
+func AttributeAppliesTo(definition *grpc_inventory_manager_go.AttributeDefinition, entityType grpc_inventory_manager_go.InventoryEntityType) bool {
+	if len(definition.EntityTypes) == 0 {
+		return true
+	}
+	for _, allowed := range definition.EntityTypes {
+		if allowed == entityType {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeAttributeValue checks a value against the type of its attribute and returns it in canonical form so the
+// stored values can be compared: numbers without trailing zeros and dates as YYYY-MM-DD. The existence of the
+// referenced assets is not checked.
+func NormalizeAttributeValue(definition *grpc_inventory_manager_go.AttributeDefinition, value string) (string, derrors.Error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", derrors.NewInvalidArgumentError("attribute value cannot be empty").WithParams(definition.Name)
+	}
+	if len(value) > MaxAttributeValueLength {
+		return "", derrors.NewInvalidArgumentError(fmt.Sprintf("attribute value cannot exceed %d characters", MaxAttributeValueLength)).WithParams(definition.Name)
+	}
+	switch definition.Type {
+	case grpc_inventory_manager_go.AttributeType_STRING:
+		return value, nil
+	case grpc_inventory_manager_go.AttributeType_NUMBER:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", derrors.NewInvalidArgumentError("attribute value must be a number").WithParams(definition.Name, value)
+		}
+		return strconv.FormatFloat(number, 'f', -1, 64), nil
+	case grpc_inventory_manager_go.AttributeType_DATE:
+		date, err := time.Parse(AttributeDateLayout, value)
+		if err != nil {
+			date, err = time.Parse(time.RFC3339, value)
+		}
+		if err != nil {
+			return "", derrors.NewInvalidArgumentError("attribute value must be a date as YYYY-MM-DD").WithParams(definition.Name, value)
+		}
+		return date.UTC().Format(AttributeDateLayout), nil
+	case grpc_inventory_manager_go.AttributeType_ENUM:
+		for _, allowed := range definition.EnumValues {
+			if allowed == value {
+				return value, nil
+			}
+		}
+		return "", derrors.NewInvalidArgumentError("attribute value is not one of the allowed values").WithParams(definition.Name, value)
+	case grpc_inventory_manager_go.AttributeType_ASSET_REFERENCE:
+		identifier, err := ParseAssetIdentifier(value)
+		if err != nil {
+			return "", err
+		}
+		return identifier.String(), nil
+	}
+	return "", derrors.NewInvalidArgumentError("unknown attribute type").WithParams(definition.Name, definition.Type)
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package entities
+
+import (
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Custom attributes", func() {
+
+	definition := func(attributeType grpc_inventory_manager_go.AttributeType, values ...string) *grpc_inventory_manager_go.AttributeDefinition {
+		return &grpc_inventory_manager_go.AttributeDefinition{Name: "attr", Type: attributeType, EnumValues: values}
+	}
+
+	ginkgo.It("should normalize the values of each type", func() {
+		value, err := NormalizeAttributeValue(definition(grpc_inventory_manager_go.AttributeType_NUMBER), " 12.50 ")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(value).To(gomega.Equal("12.5"))
+
+		value, err = NormalizeAttributeValue(definition(grpc_inventory_manager_go.AttributeType_DATE), "2021-06-30T23:00:00-02:00")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(value).To(gomega.Equal("2021-07-01"))
+
+		value, err = NormalizeAttributeValue(definition(grpc_inventory_manager_go.AttributeType_ENUM, "gold", "silver"), "gold")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(value).To(gomega.Equal("gold"))
+	})
+
+	ginkgo.It("should reject the values that do not match the type", func() {
+		invalid := map[*grpc_inventory_manager_go.AttributeDefinition]string{
+			definition(grpc_inventory_manager_go.AttributeType_NUMBER):                 "ten",
+			definition(grpc_inventory_manager_go.AttributeType_DATE):                   "30/06/2021",
+			definition(grpc_inventory_manager_go.AttributeType_ENUM, "gold", "silver"): "bronze",
+			definition(grpc_inventory_manager_go.AttributeType_ASSET_REFERENCE):        "asset\tid",
+			definition(grpc_inventory_manager_go.AttributeType_STRING):                 " ",
+		}
+		for attributeDefinition, value := range invalid {
+			_, err := NormalizeAttributeValue(attributeDefinition, value)
+			gomega.Expect(err).NotTo(gomega.Succeed(), value)
+		}
+	})
+
+	ginkgo.It("should read the attributes from the labels", func() {
+		labels := map[string]string{"env": "prod", AttributeLabel("warranty_end"): "2021-06-30"}
+		gomega.Expect(AttributesFromLabels(labels)).To(gomega.Equal(map[string]string{"warranty_end": "2021-06-30"}))
+		gomega.Expect(AttributesFromLabels(map[string]string{"env": "prod"})).To(gomega.BeNil())
+	})
+
+	ginkgo.It("should validate the schemas", func() {
+		valid := &grpc_inventory_manager_go.AttributeSchema{
+			OrganizationId: "org",
+			Attributes: []*grpc_inventory_manager_go.AttributeDefinition{
+				{Name: "purchase_date", Type: grpc_inventory_manager_go.AttributeType_DATE},
+				{Name: "tier", Type: grpc_inventory_manager_go.AttributeType_ENUM, EnumValues: []string{"gold"}},
+			},
+		}
+		gomega.Expect(ValidAttributeSchema(valid)).To(gomega.Succeed())
+
+		invalid := []*grpc_inventory_manager_go.AttributeDefinition{
+			{Name: "Purchase Date", Type: grpc_inventory_manager_go.AttributeType_DATE},
+			{Name: "tier", Type: grpc_inventory_manager_go.AttributeType_ENUM},
+			{Name: "cost", Type: grpc_inventory_manager_go.AttributeType_NUMBER, EnumValues: []string{"1"}},
+			{Name: "kind", Type: grpc_inventory_manager_go.AttributeType(42)},
+		}
+		for _, attributeDefinition := range invalid {
+			schema := &grpc_inventory_manager_go.AttributeSchema{
+				OrganizationId: "org",
+				Attributes:     []*grpc_inventory_manager_go.AttributeDefinition{attributeDefinition},
+			}
+			gomega.Expect(ValidAttributeSchema(schema)).NotTo(gomega.Succeed(), attributeDefinition.Name)
+		}
+	})
+})
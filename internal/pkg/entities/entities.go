@@ -23,8 +23,27 @@ import (
 	"github.com/nalej/grpc-inventory-go"
 	"github.com/nalej/grpc-inventory-manager-go"
 	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/inventory-manager/internal/pkg/units"
+	"strings"
+	"time"
 )
 
+// EdgeControllerNameSuffix with the suffix of the VPN user names of the edge controllers.
+const EdgeControllerNameSuffix = ".eic"
+
+// EdgeControllerFQDNSuffix with the suffix of the DNS entries of the edge controllers.
+const EdgeControllerFQDNSuffix = "-vpn"
+
+// EdgeControllerDNSTag with the tag used to register the edge controllers in the DNS.
+const EdgeControllerDNSTag = "EIC"
+
+// EdgeInventoryProxyName with the name of the proxy the edge controllers are attached to.
+// TODO: we have only one proxy, change this when more proxies are added
+const EdgeInventoryProxyName = "proxy0-vpn.service.nalej"
+
+// EdgeInventoryProxyPort with the port the edge controllers use to connect to the proxy.
+const EdgeInventoryProxyPort = 5544
+
 
 func ValidEICJoinToken(request *grpc_inventory_manager_go.EICJoinToken) derrors.Error {
 	if request.OrganizationId == "" {
@@ -54,15 +73,55 @@ func ValidEICJoinRequest(request *grpc_inventory_manager_go.EICJoinRequest) derr
 }
 
 func GetEdgeControllerName(organizationID string, edgeControllerID string) string {
-	return fmt.Sprintf("%s-%s.eic", organizationID, edgeControllerID)
+	return fmt.Sprintf("%s-%s%s", organizationID, edgeControllerID, EdgeControllerNameSuffix)
+}
+
+// GetEdgeControllerIDFromName returns the edge_controller_id of a name generated with GetEdgeControllerName.
+func GetEdgeControllerIDFromName(organizationID string, name string) (string, bool) {
+	prefix := fmt.Sprintf("%s-", organizationID)
+	if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, EdgeControllerNameSuffix) {
+		return "", false
+	}
+	edgeControllerID := strings.TrimSuffix(strings.TrimPrefix(name, prefix), EdgeControllerNameSuffix)
+	if edgeControllerID == "" {
+		return "", false
+	}
+	return edgeControllerID, true
+}
+
+// GetEdgeControllerFQDN returns the FQDN registered in the DNS for a given edge controller.
+func GetEdgeControllerFQDN(edgeControllerID string) string {
+	return fmt.Sprintf("%s%s", edgeControllerID, EdgeControllerFQDNSuffix)
+}
+
+// GetEdgeControllerIDFromFQDN returns the edge_controller_id of a FQDN generated with GetEdgeControllerFQDN.
+func GetEdgeControllerIDFromFQDN(fqdn string) (string, bool) {
+	if !strings.HasSuffix(fqdn, EdgeControllerFQDNSuffix) {
+		return "", false
+	}
+	edgeControllerID := strings.TrimSuffix(fqdn, EdgeControllerFQDNSuffix)
+	if edgeControllerID == "" {
+		return "", false
+	}
+	return edgeControllerID, true
+}
+
+// IsAssetAliveUpdate checks if an update of an asset only reports an alive message.
+func IsAssetAliveUpdate(request *grpc_inventory_go.UpdateAssetRequest) bool {
+	return request.UpdateLastAlive && !request.AddLabels && !request.RemoveLabels && !request.UpdateLastOpSummary && !request.UpdateLocation
+}
+
+// IsControllerAliveUpdate checks if an update of a controller only reports an alive message.
+func IsControllerAliveUpdate(request *grpc_inventory_go.UpdateEdgeControllerRequest) bool {
+	return request.UpdateLastAlive && !request.AddLabels && !request.RemoveLabels && !request.UpdateGeolocation && !request.UpdateLastOpSummary
 }
 
 func ValidEdgeControllerOpResponse(response * grpc_inventory_manager_go.EdgeControllerOpResponse) derrors.Error{
 	if response.OrganizationId == ""{
 		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
 	}
-	if response.EdgeControllerId == ""{
-		return derrors.NewInvalidArgumentError("edge_controller_id cannot be empty")
+	if _, vErr := ParseControllerIdentifier(response.EdgeControllerId); vErr != nil {
+		return vErr
 	}
 	if response.OperationId == ""{
 		return derrors.NewInvalidArgumentError("operation_id cannot be empty")
@@ -74,8 +133,8 @@ func ValidEdgeControllerId(edgeControllerID *grpc_inventory_go.EdgeControllerId)
 	if edgeControllerID.OrganizationId == "" {
 		return derrors.NewInvalidArgumentError("organization_id must not be empty")
 	}
-	if edgeControllerID.EdgeControllerId == "" {
-		return derrors.NewInvalidArgumentError("edge_controller_id must not be empty")
+	if _, vErr := ParseControllerIdentifier(edgeControllerID.EdgeControllerId); vErr != nil {
+		return vErr
 	}
 	return nil
 }
@@ -84,8 +143,8 @@ func ValidUnlinkECRequest(request *grpc_inventory_manager_go.UnlinkECRequest) de
 	if request.OrganizationId == "" {
 		return derrors.NewInvalidArgumentError("organization_id must not be empty")
 	}
-	if request.EdgeControllerId == "" {
-		return derrors.NewInvalidArgumentError("edge_controller_id must not be empty")
+	if _, vErr := ParseControllerIdentifier(request.EdgeControllerId); vErr != nil {
+		return vErr
 	}
 	return nil
 }
@@ -94,8 +153,8 @@ func ValidEICStartInfo(info *grpc_inventory_manager_go.EICStartInfo) derrors.Err
 	if info.OrganizationId == "" {
 		return derrors.NewInvalidArgumentError("organization_id must not be empty")
 	}
-	if info.EdgeControllerId == "" {
-		return derrors.NewInvalidArgumentError("edge_controller_id must not be empty")
+	if _, vErr := ParseControllerIdentifier(info.EdgeControllerId); vErr != nil {
+		return vErr
 	}
 	// TODO Validate IP regex
 	if info.Ip == "" {
@@ -108,8 +167,8 @@ func ValidInstallAgentRequest(request *grpc_inventory_manager_go.InstallAgentReq
 	if request.OrganizationId == ""{
 		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
 	}
-	if request.EdgeControllerId == ""{
-		return derrors.NewInvalidArgumentError("edge_controller_id cannot be empty")
+	if _, vErr := ParseControllerIdentifier(request.EdgeControllerId); vErr != nil {
+		return vErr
 	}
 	if request.TargetHost == ""{
 		return derrors.NewInvalidArgumentError("target_host cannot be empty")
@@ -121,8 +180,8 @@ func ValidAgentJoinRequest(request *grpc_inventory_manager_go.AgentJoinRequest)
 	if request.OrganizationId == "" {
 		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
 	}
-	if request.EdgeControllerId == "" {
-		return derrors.NewInvalidArgumentError("edge_controller_id cannot be empty")
+	if _, vErr := ParseControllerIdentifier(request.EdgeControllerId); vErr != nil {
+		return vErr
 	}
 	if request.AgentId == "" {
 		return derrors.NewInvalidArgumentError("agent_id cannot be empty")
@@ -134,8 +193,8 @@ func ValidAgentsAlive(request *grpc_inventory_manager_go.AgentsAlive) derrors.Er
 	if request.OrganizationId == "" {
 		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
 	}
-	if request.EdgeControllerId == "" {
-		return derrors.NewInvalidArgumentError("edge_controller_id cannot be empty")
+	if _, vErr := ParseControllerIdentifier(request.EdgeControllerId); vErr != nil {
+		return vErr
 	}
 	if request.Agents == nil || len(request.Agents) <= 0 {
 		return derrors.NewInvalidArgumentError("agents cannot be empty")
@@ -147,8 +206,8 @@ func ValidAssetID(id *grpc_inventory_go.AssetId) derrors.Error {
 	if id.OrganizationId == "" {
 		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
 	}
-	if id.AssetId == "" {
-		return derrors.NewInvalidArgumentError("asset_id cannot be empty")
+	if _, vErr := ParseAssetIdentifier(id.AssetId); vErr != nil {
+		return vErr
 	}
 	return nil
 }
@@ -158,8 +217,8 @@ func ValidUninstallAgentRequest(request *grpc_inventory_manager_go.UninstallAgen
 	if request.OrganizationId == "" {
 		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
 	}
-	if request.AssetId == "" {
-		return derrors.NewInvalidArgumentError("asset_id cannot be empty")
+	if _, vErr := ParseAssetIdentifier(request.AssetId); vErr != nil {
+		return vErr
 	}
 	return nil
 }
@@ -168,8 +227,8 @@ func ValidUpdateGeolocationRequest(request *grpc_inventory_manager_go.UpdateGeol
 	if request.OrganizationId == "" {
 		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
 	}
-	if request.EdgeControllerId == "" {
-		return derrors.NewInvalidArgumentError("edge_controller_id cannot be empty")
+	if _, vErr := ParseControllerIdentifier(request.EdgeControllerId); vErr != nil {
+		return vErr
 	}
 	return nil
 }
@@ -178,21 +237,21 @@ func ValidUpdateECRequest (request *grpc_inventory_go.UpdateEdgeControllerReques
 	if request.OrganizationId == "" {
 		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
 	}
-	if request.EdgeControllerId == "" {
-		return derrors.NewInvalidArgumentError("edge_controller_id cannot be empty")
+	if _, vErr := ParseControllerIdentifier(request.EdgeControllerId); vErr != nil {
+		return vErr
 	}
-	return nil
+	return validUserLabels(request.Labels)
 }
 
 func ValidAgentOpRequest(request *grpc_inventory_manager_go.AgentOpRequest) derrors.Error {
 	if request.OrganizationId == "" {
 		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
 	}
-	if request.EdgeControllerId == "" {
-		return derrors.NewInvalidArgumentError("edge_controller_id cannot be empty")
+	if _, vErr := ParseControllerIdentifier(request.EdgeControllerId); vErr != nil {
+		return vErr
 	}
-	if request.AssetId == "" {
-		return derrors.NewInvalidArgumentError("asset_id cannot be empty")
+	if _, vErr := ParseAssetIdentifier(request.AssetId); vErr != nil {
+		return vErr
 	}
 	if request.OperationId == "" {
 		return derrors.NewInvalidArgumentError("operation_id cannot be empty")
@@ -208,11 +267,11 @@ func ValidAgentOpResponse (request *grpc_inventory_manager_go.AgentOpResponse) d
 	if request.OrganizationId == "" {
 		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
 	}
-	if request.EdgeControllerId == "" {
-		return derrors.NewInvalidArgumentError("edge_controller_id cannot be empty")
+	if _, vErr := ParseControllerIdentifier(request.EdgeControllerId); vErr != nil {
+		return vErr
 	}
-	if request.AssetId == "" {
-		return derrors.NewInvalidArgumentError("asset_id cannot be empty")
+	if _, vErr := ParseAssetIdentifier(request.AssetId); vErr != nil {
+		return vErr
 	}
 	if request.OperationId == "" {
 		return derrors.NewInvalidArgumentError("operation_id cannot be empty")
@@ -220,12 +279,37 @@ func ValidAgentOpResponse (request *grpc_inventory_manager_go.AgentOpResponse) d
 	return nil
 }
 
-func NewDeviceFromGRPC (device *grpc_device_manager_go.Device) *grpc_inventory_manager_go.Device {
+// GetAssetDeviceID returns the identifier of a device in the inventory.
+func GetAssetDeviceID(deviceGroupID string, deviceID string) string {
+	return NewDeviceIdentifier(deviceGroupID, deviceID).String()
+}
+
+// GetConnectedStatus returns ONLINE if the last alive timestamp is within the given threshold.
+func GetConnectedStatus(lastAlive int64, threshold time.Duration) grpc_inventory_manager_go.ConnectedStatus {
+	if lastAlive != 0 && time.Unix(lastAlive, 0).Add(threshold).Unix() > time.Now().Unix() {
+		return grpc_inventory_manager_go.ConnectedStatus_ONLINE
+	}
+	return grpc_inventory_manager_go.ConnectedStatus_OFFLINE
+}
+
+// GetDeviceConnectedStatus returns the connection status of a device using its last alive timestamp. Device managers
+// that do not report that timestamp fall back to the status of the device.
+func GetDeviceConnectedStatus(device *grpc_device_manager_go.Device, threshold time.Duration) grpc_inventory_manager_go.ConnectedStatus {
+	if device.LastAliveTimestamp == 0 {
+		if device.DeviceStatus == grpc_device_manager_go.DeviceStatus_ONLINE {
+			return grpc_inventory_manager_go.ConnectedStatus_ONLINE
+		}
+		return grpc_inventory_manager_go.ConnectedStatus_OFFLINE
+	}
+	return GetConnectedStatus(device.LastAliveTimestamp, threshold)
+}
+
+func NewDeviceFromGRPC (device *grpc_device_manager_go.Device, threshold time.Duration) *grpc_inventory_manager_go.Device {
 	return &grpc_inventory_manager_go.Device{
 		OrganizationId:       device.OrganizationId,
 		DeviceGroupId:        device.DeviceGroupId,
 		DeviceId:             device.DeviceId,
-		AssetDeviceId:        fmt.Sprintf("%s#%s", device.DeviceGroupId, device.DeviceId),
+		AssetDeviceId:        GetAssetDeviceID(device.DeviceGroupId, device.DeviceId),
 		RegisterSince:        device.RegisterSince,
 		Labels:               device.Labels,
 		Enabled:              device.Enabled,
@@ -233,6 +317,10 @@ func NewDeviceFromGRPC (device *grpc_device_manager_go.Device) *grpc_inventory_m
 		DeviceStatus:         device.DeviceStatus,
 		Location:             device.Location,
 		AssetInfo:            device.AssetInfo,
+		LastAliveTimestamp:   device.LastAliveTimestamp,
+		Status:               GetDeviceConnectedStatus(device, threshold),
+		Resources:            NormalizeAssetInfo(device.AssetInfo, device.Labels, units.DeviceRanges),
+		Attributes:           AttributesFromLabels(device.Labels),
 	}
 }
 
@@ -244,18 +332,18 @@ func ValidUpdateAssetRequest (request *grpc_inventory_go.UpdateAssetRequest) der
 	if request.OrganizationId == "" {
 		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
 	}
-	if request.AssetId == "" {
-		return derrors.NewInvalidArgumentError("asset_id cannot be empty")
+	if _, vErr := ParseAssetIdentifier(request.AssetId); vErr != nil {
+		return vErr
 	}
-	return nil
+	return validUserLabels(request.Labels)
 }
 
 func ValidDeviceId (request *grpc_inventory_manager_go.DeviceId) derrors.Error {
 	if request.OrganizationId == "" {
 		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
 	}
-	if request.AssetDeviceId == "" {
-		return derrors.NewInvalidArgumentError("asset_device_id cannot be empty")
+	if _, vErr := ParseDeviceIdentifier(request.AssetDeviceId); vErr != nil {
+		return vErr
 	}
 	return nil
 }
@@ -264,11 +352,324 @@ func ValidUpdateDeviceLocationRequest (request *grpc_inventory_manager_go.Update
 	if request.OrganizationId == "" {
 		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
 	}
-	if request.AssetDeviceId == "" {
-		return derrors.NewInvalidArgumentError("asset_device_id cannot be empty")
+	if _, vErr := ParseDeviceIdentifier(request.AssetDeviceId); vErr != nil {
+		return vErr
 	}
 	if request.Location != nil && request.Location.Geolocation == "" {
 		return derrors.NewInvalidArgumentError("location cannot be empty")
 	}
 	return nil
 }
+
+func ValidUpdateDeviceRequest(request *grpc_inventory_manager_go.UpdateDeviceRequest) derrors.Error {
+	if request.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
+	}
+	if _, vErr := ParseDeviceIdentifier(request.AssetDeviceId); vErr != nil {
+		return vErr
+	}
+	if request.AddLabels && request.RemoveLabels {
+		return derrors.NewInvalidArgumentError("add_labels and remove_labels cannot be set at the same time")
+	}
+	if (request.AddLabels || request.RemoveLabels) && len(request.Labels) == 0 {
+		return derrors.NewInvalidArgumentError("labels cannot be empty")
+	}
+	if !request.AddLabels && !request.RemoveLabels && !request.UpdateEnabled {
+		return derrors.NewInvalidArgumentError("update request must modify the labels or the enabled flag")
+	}
+	return validUserLabels(request.Labels)
+}
+
+// validLabelKey checks that a label can be modified by the users. The reserved labels are only modified by the
+// inventory manager operations that own them.
+func validLabelKey(key string) derrors.Error {
+	if key == "" {
+		return derrors.NewInvalidArgumentError("label keys cannot be empty")
+	}
+	if strings.HasPrefix(key, ReservedLabelPrefix) && !UserDeclaredLabels[key] {
+		return derrors.NewInvalidArgumentError("reserved labels cannot be modified").WithParams(key)
+	}
+	return nil
+}
+
+// validUserLabels checks the keys of the labels sent by the users.
+func validUserLabels(labels map[string]string) derrors.Error {
+	for key := range labels {
+		if vErr := validLabelKey(key); vErr != nil {
+			return vErr
+		}
+	}
+	return nil
+}
+
+func ValidBulkLabelRequest(request *grpc_inventory_manager_go.BulkLabelRequest) derrors.Error {
+	if request.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
+	}
+	selector := request.Selector
+	if selector == nil || (len(selector.Ids) == 0 && len(selector.Labels) == 0 && selector.EdgeControllerId == "" &&
+		selector.Query == "" && selector.QueryId == "") {
+		return derrors.NewInvalidArgumentError("selector must have at least one criterion")
+	}
+	if len(request.AddLabels) == 0 && len(request.RemoveLabels) == 0 {
+		return derrors.NewInvalidArgumentError("add_labels and remove_labels cannot be both empty")
+	}
+	if vErr := validUserLabels(request.AddLabels); vErr != nil {
+		return vErr
+	}
+	for _, key := range request.RemoveLabels {
+		if strings.HasPrefix(key, ReservedLabelPrefix) && !UserDeclaredLabels[key] {
+			return derrors.NewInvalidArgumentError("reserved labels cannot be modified").WithParams(key)
+		}
+		if _, exists := request.AddLabels[key]; exists {
+			return derrors.NewInvalidArgumentError("a label cannot be added and removed at the same time").WithParams(key)
+		}
+	}
+	return nil
+}
+
+// ValidRetentionPolicy checks that a policy has at least one threshold and that the thresholds in use are ordered
+// as flag, archive and delete.
+func ValidRetentionPolicy(policy *grpc_inventory_manager_go.RetentionPolicy) derrors.Error {
+	if policy.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
+	}
+	thresholds := []int64{policy.FlagAfterSeconds, policy.ArchiveAfterSeconds, policy.DeleteAfterSeconds}
+	previous := int64(0)
+	for _, threshold := range thresholds {
+		if threshold < 0 {
+			return derrors.NewInvalidArgumentError("thresholds cannot be negative")
+		}
+		if threshold == 0 {
+			continue
+		}
+		if threshold < previous {
+			return derrors.NewInvalidArgumentError("thresholds must be ordered as flag, archive and delete")
+		}
+		previous = threshold
+	}
+	if previous == 0 {
+		return derrors.NewInvalidArgumentError("at least one threshold must be set")
+	}
+	return nil
+}
+
+func ValidAuditQuery(query *grpc_inventory_manager_go.AuditQuery) derrors.Error {
+	if query.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
+	}
+	if query.From < 0 || query.To < 0 || query.Limit < 0 {
+		return derrors.NewInvalidArgumentError("from, to and limit cannot be negative")
+	}
+	if query.To != 0 && query.To < query.From {
+		return derrors.NewInvalidArgumentError("to cannot be before from")
+	}
+	return nil
+}
+
+func ValidAttributeSchema(schema *grpc_inventory_manager_go.AttributeSchema) derrors.Error {
+	if schema.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
+	}
+	names := make(map[string]bool, len(schema.Attributes))
+	for _, definition := range schema.Attributes {
+		if definition == nil || !attributeNameRegex.MatchString(definition.Name) {
+			return derrors.NewInvalidArgumentError("attribute names must start with a lowercase letter followed by lowercase letters, digits or underscores")
+		}
+		if names[definition.Name] {
+			return derrors.NewInvalidArgumentError("duplicated attribute").WithParams(definition.Name)
+		}
+		names[definition.Name] = true
+		if _, exists := grpc_inventory_manager_go.AttributeType_name[int32(definition.Type)]; !exists {
+			return derrors.NewInvalidArgumentError("unknown attribute type").WithParams(definition.Name, definition.Type)
+		}
+		isEnum := definition.Type == grpc_inventory_manager_go.AttributeType_ENUM
+		if isEnum && len(definition.EnumValues) == 0 {
+			return derrors.NewInvalidArgumentError("enum attributes must have at least one value").WithParams(definition.Name)
+		}
+		if !isEnum && len(definition.EnumValues) > 0 {
+			return derrors.NewInvalidArgumentError("only enum attributes can have values").WithParams(definition.Name)
+		}
+		for _, value := range definition.EnumValues {
+			if strings.TrimSpace(value) != value || value == "" {
+				return derrors.NewInvalidArgumentError("enum values cannot be empty or have surrounding spaces").WithParams(definition.Name)
+			}
+		}
+	}
+	return nil
+}
+
+func ValidUpdateAttributesRequest(request *grpc_inventory_manager_go.UpdateAttributesRequest) derrors.Error {
+	if request.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
+	}
+	var err derrors.Error
+	switch request.EntityType {
+	case grpc_inventory_manager_go.InventoryEntityType_ASSET:
+		_, err = ParseAssetIdentifier(request.EntityId)
+	case grpc_inventory_manager_go.InventoryEntityType_CONTROLLER:
+		_, err = ParseControllerIdentifier(request.EntityId)
+	case grpc_inventory_manager_go.InventoryEntityType_DEVICE:
+		_, err = ParseDeviceIdentifier(request.EntityId)
+	default:
+		return derrors.NewInvalidArgumentError("unknown entity type").WithParams(request.EntityType)
+	}
+	if err != nil {
+		return err
+	}
+	if len(request.Attributes) == 0 && len(request.RemoveAttributes) == 0 {
+		return derrors.NewInvalidArgumentError("attributes and remove_attributes cannot be both empty")
+	}
+	for _, name := range request.RemoveAttributes {
+		if _, exists := request.Attributes[name]; exists {
+			return derrors.NewInvalidArgumentError("an attribute cannot be set and removed at the same time").WithParams(name)
+		}
+	}
+	return nil
+}
+
+func ValidReconcileRequest(request *grpc_inventory_manager_go.ReconcileRequest) derrors.Error {
+	if request.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
+	}
+	return nil
+}
+
+func ValidQuarantineRequest(request *grpc_inventory_manager_go.QuarantineRequest) derrors.Error {
+	if request.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
+	}
+	if _, vErr := ParseAssetIdentifier(request.AssetId); vErr != nil {
+		return vErr
+	}
+	return nil
+}
+
+// MaxPageSize with the maximum number of elements returned in a page of the inventory.
+const MaxPageSize = 1000
+
+func ValidListInventoryRequest(request *grpc_inventory_manager_go.ListInventoryRequest) derrors.Error {
+	if request.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
+	}
+	if request.PageSize < 0 || request.PageSize > MaxPageSize {
+		return derrors.NewInvalidArgumentError("page_size must be between 0 and the maximum page size").WithParams(request.PageSize, MaxPageSize)
+	}
+	return nil
+}
+
+func ValidQueryRequest(request *grpc_inventory_manager_go.QueryRequest) derrors.Error {
+	if request.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
+	}
+	if request.Query == "" && request.QueryId == "" {
+		return derrors.NewInvalidArgumentError("query or query_id must be set")
+	}
+	if request.Query != "" && request.QueryId != "" {
+		return derrors.NewInvalidArgumentError("query and query_id cannot be set at the same time")
+	}
+	return nil
+}
+
+func ValidAddSavedQueryRequest(request *grpc_inventory_manager_go.AddSavedQueryRequest) derrors.Error {
+	if request.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
+	}
+	if request.Name == "" {
+		return derrors.NewInvalidArgumentError("name cannot be empty")
+	}
+	if request.Query == "" {
+		return derrors.NewInvalidArgumentError("query cannot be empty")
+	}
+	return nil
+}
+
+func ValidSavedQueryId(queryID *grpc_inventory_manager_go.SavedQueryId) derrors.Error {
+	if queryID.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
+	}
+	if queryID.QueryId == "" {
+		return derrors.NewInvalidArgumentError("query_id cannot be empty")
+	}
+	return nil
+}
+
+func ValidSnapshotId(snapshotID *grpc_inventory_manager_go.SnapshotId) derrors.Error {
+	if snapshotID.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
+	}
+	if snapshotID.SnapshotId == "" {
+		return derrors.NewInvalidArgumentError("snapshot_id cannot be empty")
+	}
+	return nil
+}
+
+func ValidDiffSnapshotsRequest(request *grpc_inventory_manager_go.DiffSnapshotsRequest) derrors.Error {
+	if request.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
+	}
+	if request.FromSnapshotId == "" || request.ToSnapshotId == "" {
+		return derrors.NewInvalidArgumentError("from_snapshot_id and to_snapshot_id cannot be empty")
+	}
+	return nil
+}
+
+func ValidExportRequest(request *grpc_inventory_manager_go.ExportRequest) derrors.Error {
+	if request.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
+	}
+	return nil
+}
+
+func ValidImportAssetsRequest(request *grpc_inventory_manager_go.ImportAssetsRequest) derrors.Error {
+	if request.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
+	}
+	if len(request.Data) == 0 {
+		return derrors.NewInvalidArgumentError("data cannot be empty")
+	}
+	return nil
+}
+
+func ValidWatchRequest(request *grpc_inventory_manager_go.WatchRequest) derrors.Error {
+	if request.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
+	}
+	if request.FromRevision < 0 {
+		return derrors.NewInvalidArgumentError("from_revision cannot be negative")
+	}
+	return nil
+}
+
+func ValidTrendRequest(request *grpc_inventory_manager_go.TrendRequest) derrors.Error {
+	if request.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
+	}
+	if request.From < 0 || request.To < 0 || request.Step < 0 {
+		return derrors.NewInvalidArgumentError("from, to and step cannot be negative")
+	}
+	if request.To != 0 && request.To < request.From {
+		return derrors.NewInvalidArgumentError("to cannot be before from")
+	}
+	return nil
+}
+
+func ValidDeadLetterId(deadLetterID *grpc_inventory_manager_go.DeadLetterId) derrors.Error {
+	if deadLetterID.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
+	}
+	if deadLetterID.DeadLetterId == "" {
+		return derrors.NewInvalidArgumentError("dead_letter_id cannot be empty")
+	}
+	return nil
+}
+
+func ValidDeadLetterQuery(query *grpc_inventory_manager_go.DeadLetterQuery) derrors.Error {
+	if query.OrganizationId == "" {
+		return derrors.NewInvalidArgumentError("organization_id cannot be empty")
+	}
+	if query.Limit < 0 {
+		return derrors.NewInvalidArgumentError("limit cannot be negative")
+	}
+	return nil
+}
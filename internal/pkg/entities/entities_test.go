@@ -0,0 +1,99 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package entities
+
+import (
+	"github.com/nalej/grpc-device-manager-go"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"time"
+)
+
+var _ = ginkgo.Describe("Label validation", func() {
+
+	const organizationID = "org"
+	reserved := map[string]string{QuarantineLabel: "true"}
+	user := map[string]string{"env": "prod"}
+
+	ginkgo.It("should reject the reserved labels on asset updates", func() {
+		request := &grpc_inventory_go.UpdateAssetRequest{
+			OrganizationId: organizationID, AssetId: "asset", RemoveLabels: true, Labels: reserved,
+		}
+		gomega.Expect(ValidUpdateAssetRequest(request)).NotTo(gomega.Succeed())
+		request.Labels = user
+		gomega.Expect(ValidUpdateAssetRequest(request)).To(gomega.Succeed())
+		request.Labels = map[string]string{RAMUnitLabel: "KB"}
+		gomega.Expect(ValidUpdateAssetRequest(request)).To(gomega.Succeed())
+	})
+
+	ginkgo.It("should reject the reserved labels on controller updates", func() {
+		request := &grpc_inventory_go.UpdateEdgeControllerRequest{
+			OrganizationId: organizationID, EdgeControllerId: "controller", AddLabels: true,
+			Labels: map[string]string{AttributeLabel("warranty_end"): "never"},
+		}
+		gomega.Expect(ValidUpdateECRequest(request)).NotTo(gomega.Succeed())
+		request.Labels = user
+		gomega.Expect(ValidUpdateECRequest(request)).To(gomega.Succeed())
+	})
+
+	ginkgo.It("should reject the reserved labels on device updates", func() {
+		request := &grpc_inventory_manager_go.UpdateDeviceRequest{
+			OrganizationId: organizationID, AssetDeviceId: GetAssetDeviceID("group", "device"), AddLabels: true,
+			Labels: map[string]string{ReservedLabelPrefix + "anything": "true"},
+		}
+		gomega.Expect(ValidUpdateDeviceRequest(request)).NotTo(gomega.Succeed())
+		request.Labels = user
+		gomega.Expect(ValidUpdateDeviceRequest(request)).To(gomega.Succeed())
+	})
+})
+
+var _ = ginkgo.Describe("Connected status", func() {
+
+	const threshold = time.Minute
+
+	ginkgo.It("should be online within the threshold of the last alive timestamp", func() {
+		gomega.Expect(GetConnectedStatus(time.Now().Add(-threshold/2).Unix(), threshold)).To(gomega.Equal(grpc_inventory_manager_go.ConnectedStatus_ONLINE))
+	})
+
+	ginkgo.It("should be offline once the threshold expires", func() {
+		gomega.Expect(GetConnectedStatus(time.Now().Add(-2*threshold).Unix(), threshold)).To(gomega.Equal(grpc_inventory_manager_go.ConnectedStatus_OFFLINE))
+	})
+
+	ginkgo.It("should be offline without a last alive timestamp", func() {
+		gomega.Expect(GetConnectedStatus(0, threshold)).To(gomega.Equal(grpc_inventory_manager_go.ConnectedStatus_OFFLINE))
+	})
+
+	ginkgo.It("should use the status of the devices without a last alive timestamp", func() {
+		device := &grpc_device_manager_go.Device{DeviceStatus: grpc_device_manager_go.DeviceStatus_ONLINE}
+		gomega.Expect(GetDeviceConnectedStatus(device, threshold)).To(gomega.Equal(grpc_inventory_manager_go.ConnectedStatus_ONLINE))
+		device.DeviceStatus = grpc_device_manager_go.DeviceStatus_OFFLINE
+		gomega.Expect(GetDeviceConnectedStatus(device, threshold)).To(gomega.Equal(grpc_inventory_manager_go.ConnectedStatus_OFFLINE))
+	})
+
+	ginkgo.It("should use the last alive timestamp of the devices that report it", func() {
+		device := &grpc_device_manager_go.Device{
+			DeviceStatus:       grpc_device_manager_go.DeviceStatus_OFFLINE,
+			LastAliveTimestamp: time.Now().Add(-threshold / 2).Unix(),
+		}
+		gomega.Expect(GetDeviceConnectedStatus(device, threshold)).To(gomega.Equal(grpc_inventory_manager_go.ConnectedStatus_ONLINE))
+		device.DeviceStatus = grpc_device_manager_go.DeviceStatus_ONLINE
+		device.LastAliveTimestamp = time.Now().Add(-2 * threshold).Unix()
+		gomega.Expect(GetDeviceConnectedStatus(device, threshold)).To(gomega.Equal(grpc_inventory_manager_go.ConnectedStatus_OFFLINE))
+	})
+})
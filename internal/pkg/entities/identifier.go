@@ -0,0 +1,222 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package entities
+
+import (
+	"fmt"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// IdentifierVersion with the version of the composite identifiers generated by the inventory.
+const IdentifierVersion = "v1"
+
+// LegacyIdentifierVersion with the version of the device identifiers composed by device_group_id#device_id
+// without escaping. They are accepted but no longer generated.
+const LegacyIdentifierVersion = "v0"
+
+// identifierSeparator separates the version and the parts of a composite identifier.
+const identifierSeparator = ":"
+
+// legacyIdentifierSeparator separates the parts of a legacy device identifier.
+const legacyIdentifierSeparator = "#"
+
+// versionRegex matches the version prefix of a composite identifier.
+var versionRegex = regexp.MustCompile(`^v[0-9]+:`)
+
+// Identifier of an entity of the inventory. Assets and controllers are identified by the id assigned by the
+// system model while devices use a composite identifier built from their device group and device ids. Only the
+// composite identifiers are versioned: the ids of assets and controllers are not built by the inventory, so their
+// format cannot change here, and prefixing them would change the ids already stored by the API clients.
+type Identifier struct {
+	// EntityType with the type of the identified entity.
+	EntityType grpc_inventory_manager_go.InventoryEntityType
+	// Version of the format of the identifier.
+	Version string
+	// AssetID of an asset.
+	AssetID string
+	// EdgeControllerID of a controller.
+	EdgeControllerID string
+	// DeviceGroupID of a device.
+	DeviceGroupID string
+	// DeviceID of a device.
+	DeviceID string
+}
+
+// NewAssetIdentifier creates the identifier of an asset.
+func NewAssetIdentifier(assetID string) *Identifier {
+	return &Identifier{
+		EntityType: grpc_inventory_manager_go.InventoryEntityType_ASSET,
+		Version:    IdentifierVersion,
+		AssetID:    assetID,
+	}
+}
+
+// NewControllerIdentifier creates the identifier of an edge controller.
+func NewControllerIdentifier(edgeControllerID string) *Identifier {
+	return &Identifier{
+		EntityType:       grpc_inventory_manager_go.InventoryEntityType_CONTROLLER,
+		Version:          IdentifierVersion,
+		EdgeControllerID: edgeControllerID,
+	}
+}
+
+// NewDeviceIdentifier creates the identifier of a device.
+func NewDeviceIdentifier(deviceGroupID string, deviceID string) *Identifier {
+	return &Identifier{
+		EntityType:    grpc_inventory_manager_go.InventoryEntityType_DEVICE,
+		Version:       IdentifierVersion,
+		DeviceGroupID: deviceGroupID,
+		DeviceID:      deviceID,
+	}
+}
+
+// String formats the identifier using the current version. Device identifiers are formatted as
+// v1:<device_group_id>:<device_id> with both parts escaped.
+func (id *Identifier) String() string {
+	switch id.EntityType {
+	case grpc_inventory_manager_go.InventoryEntityType_ASSET:
+		return id.AssetID
+	case grpc_inventory_manager_go.InventoryEntityType_CONTROLLER:
+		return id.EdgeControllerID
+	}
+	return strings.Join([]string{IdentifierVersion, escapeIdentifierPart(id.DeviceGroupID), escapeIdentifierPart(id.DeviceID)}, identifierSeparator)
+}
+
+// ParseAssetIdentifier validates the identifier of an asset.
+func ParseAssetIdentifier(raw string) (*Identifier, derrors.Error) {
+	vErr := validOpaqueIdentifier(raw, "asset_id")
+	if vErr != nil {
+		return nil, vErr
+	}
+	return NewAssetIdentifier(raw), nil
+}
+
+// ParseControllerIdentifier validates the identifier of an edge controller.
+func ParseControllerIdentifier(raw string) (*Identifier, derrors.Error) {
+	vErr := validOpaqueIdentifier(raw, "edge_controller_id")
+	if vErr != nil {
+		return nil, vErr
+	}
+	return NewControllerIdentifier(raw), nil
+}
+
+// ParseDeviceIdentifier parses the identifier of a device. Both the current format and the legacy
+// device_group_id#device_id one are accepted. An identifier with an unknown version prefix is parsed as a legacy
+// one, as the device group of a legacy identifier may look like a version.
+func ParseDeviceIdentifier(raw string) (*Identifier, derrors.Error) {
+	if raw == "" {
+		return nil, derrors.NewInvalidArgumentError("asset_device_id cannot be empty")
+	}
+	if !versionRegex.MatchString(raw) {
+		return parseLegacyDeviceIdentifier(raw)
+	}
+	fields := strings.Split(raw, identifierSeparator)
+	if fields[0] != IdentifierVersion {
+		id, vErr := parseLegacyDeviceIdentifier(raw)
+		if vErr != nil {
+			return nil, derrors.NewInvalidArgumentError("unsupported asset_device_id version").WithParams(fields[0])
+		}
+		return id, nil
+	}
+	if len(fields) != 3 {
+		return nil, derrors.NewInvalidArgumentError("asset_device_id is wrong").WithParams(raw)
+	}
+	deviceGroupID, vErr := unescapeIdentifierPart(fields[1])
+	if vErr != nil {
+		return nil, vErr
+	}
+	deviceID, vErr := unescapeIdentifierPart(fields[2])
+	if vErr != nil {
+		return nil, vErr
+	}
+	if deviceGroupID == "" || deviceID == "" {
+		return nil, derrors.NewInvalidArgumentError("asset_device_id is wrong").WithParams(raw)
+	}
+	return NewDeviceIdentifier(deviceGroupID, deviceID), nil
+}
+
+// parseLegacyDeviceIdentifier parses a device identifier with the device_group_id#device_id format.
+func parseLegacyDeviceIdentifier(raw string) (*Identifier, derrors.Error) {
+	fields := strings.Split(raw, legacyIdentifierSeparator)
+	if len(fields) != 2 || fields[0] == "" || fields[1] == "" {
+		return nil, derrors.NewInvalidArgumentError("asset_device_id is wrong").WithParams(raw)
+	}
+	id := NewDeviceIdentifier(fields[0], fields[1])
+	id.Version = LegacyIdentifierVersion
+	return id, nil
+}
+
+// validOpaqueIdentifier checks that an id assigned by another component is not empty and does not
+// contain control characters.
+func validOpaqueIdentifier(raw string, field string) derrors.Error {
+	if raw == "" {
+		return derrors.NewInvalidArgumentError(fmt.Sprintf("%s cannot be empty", field))
+	}
+	for _, r := range raw {
+		if r < 0x20 || r == 0x7f {
+			return derrors.NewInvalidArgumentError(fmt.Sprintf("%s contains invalid characters", field)).WithParams(raw)
+		}
+	}
+	return nil
+}
+
+// mustEscape returns true for the bytes that are percent-encoded in the parts of a composite identifier.
+func mustEscape(c byte) bool {
+	return c <= ' ' || c == 0x7f || c == '%' || c == ':' || c == '#'
+}
+
+// escapeIdentifierPart percent-encodes the separators, the escape character and the control characters.
+func escapeIdentifierPart(part string) string {
+	var builder strings.Builder
+	for i := 0; i < len(part); i++ {
+		if mustEscape(part[i]) {
+			builder.WriteString(fmt.Sprintf("%%%02X", part[i]))
+		} else {
+			builder.WriteByte(part[i])
+		}
+	}
+	return builder.String()
+}
+
+// unescapeIdentifierPart decodes a part of a composite identifier rejecting malformed or unnecessary escapes.
+func unescapeIdentifierPart(part string) (string, derrors.Error) {
+	var builder strings.Builder
+	for i := 0; i < len(part); i++ {
+		c := part[i]
+		if c != '%' {
+			if mustEscape(c) {
+				return "", derrors.NewInvalidArgumentError("asset_device_id contains unescaped characters").WithParams(part)
+			}
+			builder.WriteByte(c)
+			continue
+		}
+		if i+2 >= len(part) {
+			return "", derrors.NewInvalidArgumentError("asset_device_id contains an invalid escape").WithParams(part)
+		}
+		decoded, err := strconv.ParseUint(part[i+1:i+3], 16, 8)
+		if err != nil || !mustEscape(byte(decoded)) {
+			return "", derrors.NewInvalidArgumentError("asset_device_id contains an invalid escape").WithParams(part)
+		}
+		builder.WriteByte(byte(decoded))
+		i += 2
+	}
+	return builder.String(), nil
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package entities
+
+import (
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Identifier", func() {
+
+	ginkgo.It("should format and parse device identifiers", func() {
+		id := NewDeviceIdentifier("group", "device")
+		gomega.Expect(id.String()).To(gomega.Equal("v1:group:device"))
+		parsed, err := ParseDeviceIdentifier(id.String())
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(parsed).To(gomega.Equal(id))
+	})
+
+	ginkgo.It("should escape the separators of the device identifiers", func() {
+		id := NewDeviceIdentifier("group#1", "SN:100% 2")
+		gomega.Expect(id.String()).To(gomega.Equal("v1:group%231:SN%3A100%25%202"))
+		parsed, err := ParseDeviceIdentifier(id.String())
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(parsed.DeviceGroupID).To(gomega.Equal("group#1"))
+		gomega.Expect(parsed.DeviceID).To(gomega.Equal("SN:100% 2"))
+	})
+
+	ginkgo.It("should accept legacy device identifiers", func() {
+		parsed, err := ParseDeviceIdentifier("group#device")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(parsed.Version).To(gomega.Equal(LegacyIdentifierVersion))
+		gomega.Expect(parsed.EntityType).To(gomega.Equal(grpc_inventory_manager_go.InventoryEntityType_DEVICE))
+		gomega.Expect(parsed.String()).To(gomega.Equal("v1:group:device"))
+	})
+
+	ginkgo.It("should parse as legacy the device identifiers with an unknown version", func() {
+		parsed, err := ParseDeviceIdentifier("v2:group#device")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(parsed.Version).To(gomega.Equal(LegacyIdentifierVersion))
+		gomega.Expect(parsed.DeviceGroupID).To(gomega.Equal("v2:group"))
+		gomega.Expect(parsed.DeviceID).To(gomega.Equal("device"))
+	})
+
+	ginkgo.It("should reject malformed device identifiers", func() {
+		for _, raw := range []string{"", "device", "a#b#c", "#device", "v2:group:device", "v1:group", "v1:group:", "v1:gr#oup:device", "v1:group:dev%2", "v1:group:dev%zz", "v1:group:dev%41"} {
+			_, err := ParseDeviceIdentifier(raw)
+			gomega.Expect(err).NotTo(gomega.Succeed(), raw)
+		}
+	})
+
+	ginkgo.It("should validate asset and controller identifiers", func() {
+		asset, err := ParseAssetIdentifier("asset")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(asset.String()).To(gomega.Equal("asset"))
+		controller, err := ParseControllerIdentifier("controller")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(controller.String()).To(gomega.Equal("controller"))
+		_, err = ParseAssetIdentifier("")
+		gomega.Expect(err).NotTo(gomega.Succeed())
+		_, err = ParseControllerIdentifier("contr\noller")
+		gomega.Expect(err).NotTo(gomega.Succeed())
+	})
+})
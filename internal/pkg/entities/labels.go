@@ -0,0 +1,119 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package entities
+
+import (
+	"github.com/nalej/grpc-inventory-manager-go"
+	"strconv"
+	"time"
+)
+
+// The inventory manager keeps the state of the assets in system-model using reserved labels.
+
+// UninstallRequestedLabel is added to the assets with a pending uninstall. Its value is the timestamp of the request.
+const UninstallRequestedLabel = "nalej-uninstall-requested"
+
+// UninstallOperationLabel contains the operation_id of the pending uninstall.
+const UninstallOperationLabel = "nalej-uninstall-operation"
+
+// UninstallExpiredLabel is added to the assets whose uninstall was not confirmed on time.
+const UninstallExpiredLabel = "nalej-uninstall-expired"
+
+// QuarantineLabel is added to the assets in quarantine. Its value is the timestamp of the quarantine.
+const QuarantineLabel = "nalej-quarantine"
+
+// QuarantineReasonLabel contains the reason of the quarantine.
+const QuarantineReasonLabel = "nalej-quarantine-reason"
+
+// UnmanagedLabel is added to the imported assets that do not run an agent. Its value is the timestamp of the import.
+const UnmanagedLabel = "nalej-unmanaged"
+
+// RAMUnitLabel declares the unit in which an asset or device reports its RAM. Megabytes are assumed by default.
+const RAMUnitLabel = "nalej-ram-unit"
+
+// StorageUnitLabel declares the unit in which an asset or device reports its storage capacity.
+const StorageUnitLabel = "nalej-storage-unit"
+
+// RetentionFlaggedLabel is added to the entities flagged by a retention policy. Its value is the timestamp of the flag.
+const RetentionFlaggedLabel = "nalej-retention-flagged"
+
+// RetentionArchivedLabel is added to the entities archived by a retention policy. Its value is the timestamp of the archive.
+const RetentionArchivedLabel = "nalej-retention-archived"
+
+// AttributeLabelPrefix precedes the name of a custom attribute in the label that stores its value.
+const AttributeLabelPrefix = "nalej-attribute-"
+
+// ReservedLabelPrefix with the prefix of the labels used by the inventory manager.
+const ReservedLabelPrefix = "nalej-"
+
+// GetAssetState returns the state of an asset from its labels.
+func GetAssetState(labels map[string]string) grpc_inventory_manager_go.AssetState {
+	if _, expired := labels[UninstallExpiredLabel]; expired {
+		return grpc_inventory_manager_go.AssetState_UNINSTALL_EXPIRED
+	}
+	if _, uninstalling := labels[UninstallRequestedLabel]; uninstalling {
+		return grpc_inventory_manager_go.AssetState_UNINSTALLING
+	}
+	return grpc_inventory_manager_go.AssetState_ACTIVE
+}
+
+// NewUninstallLabels returns the labels that mark an asset with a pending uninstall.
+func NewUninstallLabels(operationID string, requested time.Time) map[string]string {
+	return map[string]string{
+		UninstallRequestedLabel: strconv.FormatInt(requested.Unix(), 10),
+		UninstallOperationLabel: operationID,
+	}
+}
+
+// GetUninstallRequested returns when the uninstall of an asset was requested.
+func GetUninstallRequested(labels map[string]string) (time.Time, bool) {
+	value, exists := labels[UninstallRequestedLabel]
+	if !exists {
+		return time.Time{}, false
+	}
+	timestamp, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(timestamp, 0), true
+}
+
+// IsQuarantined checks if an asset is in quarantine from its labels.
+func IsQuarantined(labels map[string]string) bool {
+	_, quarantined := labels[QuarantineLabel]
+	return quarantined
+}
+
+// NewQuarantineLabels returns the labels that mark an asset in quarantine.
+func NewQuarantineLabels(reason string, quarantined time.Time) map[string]string {
+	return map[string]string{
+		QuarantineLabel:       strconv.FormatInt(quarantined.Unix(), 10),
+		QuarantineReasonLabel: reason,
+	}
+}
+
+// IsUnmanaged checks if an asset was imported without an agent from its labels.
+func IsUnmanaged(labels map[string]string) bool {
+	_, unmanaged := labels[UnmanagedLabel]
+	return unmanaged
+}
+
+// UserDeclaredLabels contains the reserved labels that the users can modify to declare how an entity reports its data.
+var UserDeclaredLabels = map[string]bool{
+	RAMUnitLabel:     true,
+	StorageUnitLabel: true,
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package entities
+
+import (
+	"fmt"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/inventory-manager/internal/pkg/units"
+)
+
+// declaredUnit returns the unit declared in a label, or the default unit if the label is missing or invalid.
+func declaredUnit(labels map[string]string, label string, warnings []string) (units.Unit, []string) {
+	value, exists := labels[label]
+	if !exists {
+		return units.DefaultUnit, warnings
+	}
+	unit, err := units.ParseUnit(value)
+	if err != nil {
+		return units.DefaultUnit, append(warnings, fmt.Sprintf("invalid unit %q in label %s, assuming %s", value, label, units.DefaultUnit))
+	}
+	return unit, warnings
+}
+
+// ResourceRangesOf returns the plausible resources of a type of entity.
+func ResourceRangesOf(entityType grpc_inventory_manager_go.InventoryEntityType) units.ResourceRanges {
+	if entityType == grpc_inventory_manager_go.InventoryEntityType_DEVICE {
+		return units.DeviceRanges
+	}
+	return units.HostRanges
+}
+
+// NormalizeResources converts the hardware and storage reported by an entity to bytes. The values out of the
+// plausible ranges of the entity are flagged with a warning.
+func NormalizeResources(hardware *grpc_inventory_go.HardwareInfo, storage []*grpc_inventory_go.StorageHardwareInfo, labels map[string]string, ranges units.ResourceRanges) *grpc_inventory_manager_go.NormalizedResources {
+	warnings := make([]string, 0)
+	ramUnit, warnings := declaredUnit(labels, RAMUnitLabel, warnings)
+	storageUnit, warnings := declaredUnit(labels, StorageUnitLabel, warnings)
+	result := &grpc_inventory_manager_go.NormalizedResources{
+		RamReportedUnit:     string(ramUnit),
+		StorageReportedUnit: string(storageUnit),
+	}
+
+	if hardware != nil {
+		for _, cpu := range hardware.Cpus {
+			if cpu != nil {
+				result.NumCpu += int64(cpu.NumCores)
+			}
+		}
+		ram := units.Normalize(hardware.InstalledRam, ramUnit, ranges.RAM)
+		result.RamBytes = ram.Bytes
+		result.RamReportedUnit = string(ram.Unit)
+		if !ram.Plausible {
+			warnings = append(warnings, ram.Warning("installed_ram", hardware.InstalledRam, ramUnit))
+		}
+	}
+	for index, disk := range storage {
+		if disk == nil {
+			continue
+		}
+		capacity := units.Normalize(disk.TotalCapacity, storageUnit, ranges.Storage)
+		result.StorageBytes += capacity.Bytes
+		if !capacity.Plausible {
+			warnings = append(warnings, capacity.Warning(fmt.Sprintf("storage[%d].total_capacity", index), disk.TotalCapacity, storageUnit))
+			if capacity.Unit != storageUnit {
+				result.StorageReportedUnit = string(capacity.Unit)
+			}
+		}
+	}
+
+	result.Implausible = len(warnings) > 0
+	result.Warnings = warnings
+	return result
+}
+
+// NormalizeAssetInfo converts the hardware and storage of an asset information to bytes.
+func NormalizeAssetInfo(assetInfo *grpc_inventory_go.AssetInfo, labels map[string]string, ranges units.ResourceRanges) *grpc_inventory_manager_go.NormalizedResources {
+	if assetInfo == nil {
+		return NormalizeResources(nil, nil, labels, ranges)
+	}
+	return NormalizeResources(assetInfo.Hardware, assetInfo.Storage, labels, ranges)
+}
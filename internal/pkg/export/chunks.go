@@ -0,0 +1,63 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package export
+
+import (
+	"github.com/nalej/grpc-inventory-manager-go"
+)
+
+// ChunkSize with the maximum size of each chunk sent by the export streams.
+const ChunkSize = 64 * 1024
+
+// ChunkSender is implemented by the server side of the export streams.
+type ChunkSender interface {
+	Send(*grpc_inventory_manager_go.ExportChunk) error
+}
+
+// ChunkWriter sends the exported data through a stream in chunks.
+type ChunkWriter struct {
+	stream ChunkSender
+	buffer []byte
+}
+
+func NewChunkWriter(stream ChunkSender) *ChunkWriter {
+	return &ChunkWriter{
+		stream: stream,
+	}
+}
+
+func (c *ChunkWriter) Write(data []byte) (int, error) {
+	c.buffer = append(c.buffer, data...)
+	for len(c.buffer) >= ChunkSize {
+		err := c.stream.Send(&grpc_inventory_manager_go.ExportChunk{Data: c.buffer[:ChunkSize]})
+		if err != nil {
+			return 0, err
+		}
+		c.buffer = append([]byte{}, c.buffer[ChunkSize:]...)
+	}
+	return len(data), nil
+}
+
+// Flush sends the remaining data.
+func (c *ChunkWriter) Flush() error {
+	if len(c.buffer) == 0 {
+		return nil
+	}
+	err := c.stream.Send(&grpc_inventory_manager_go.ExportChunk{Data: c.buffer})
+	c.buffer = nil
+	return err
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/nalej/derrors"
+	"sort"
+	"strings"
+)
+
+// DefaultSeparator between the names of the nested fields.
+const DefaultSeparator = "."
+
+// ListSeparator between the values of a list when they are joined.
+const ListSeparator = ";"
+
+// LabelsField with the name of the field that contains the labels of the entities.
+const LabelsField = "labels"
+
+// Options determines how the nested fields are flattened.
+type Options struct {
+	// Separator between the names of the nested fields, e.g. hardware.installed_ram.
+	Separator string
+	// JoinLists writes the values of a list in a single field instead of one field per element, e.g.
+	// hardware.cpus.num_cores=4;4 instead of hardware.cpus.0.num_cores=4 and hardware.cpus.1.num_cores=4.
+	JoinLists bool
+	// SingleLabels writes the labels in a single field as key=value pairs instead of one field per label.
+	SingleLabels bool
+}
+
+// DefaultOptions returns the options with one field per list element and per label.
+func DefaultOptions() Options {
+	return Options{
+		Separator: DefaultSeparator,
+	}
+}
+
+// marshaler writes the messages as the API reports them: with the original field names, the enums by name and
+// the fields with default values.
+var marshaler = jsonpb.Marshaler{OrigName: true, EmitDefaults: true}
+
+// toJSON serializes an entity. The protocol buffer messages are serialized with the marshaler, and the rest of
+// the values, e.g. the objects read from an import, as regular JSON.
+func toJSON(entity interface{}) ([]byte, error) {
+	if message, isMessage := entity.(proto.Message); isMessage {
+		var buffer bytes.Buffer
+		err := marshaler.Marshal(&buffer, message)
+		return buffer.Bytes(), err
+	}
+	return json.Marshal(entity)
+}
+
+// Flatten converts an entity into a map of field paths and values. The names of the fields are the ones
+// used in the JSON representation of the entity.
+func Flatten(entity interface{}, options Options) (map[string]string, derrors.Error) {
+	if options.Separator == "" {
+		options.Separator = DefaultSeparator
+	}
+	raw, err := toJSON(entity)
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot serialize entity")
+	}
+	// numbers are decoded as json.Number to keep the timestamps and sizes as they are
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var decoded interface{}
+	err = decoder.Decode(&decoded)
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot deserialize entity")
+	}
+	result := make(map[string]string)
+	flattenValue("", decoded, result, options)
+	return result, nil
+}
+
+func flattenValue(path string, value interface{}, result map[string]string, options Options) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if path == LabelsField && options.SingleLabels {
+			result[path] = joinLabels(v)
+			return
+		}
+		for key, inner := range v {
+			flattenValue(joinPath(path, key, options), inner, result, options)
+		}
+	case []interface{}:
+		if !options.JoinLists {
+			for index, inner := range v {
+				flattenValue(joinPath(path, fmt.Sprintf("%d", index), options), inner, result, options)
+			}
+			return
+		}
+		for _, inner := range v {
+			element := make(map[string]string)
+			flattenValue(path, inner, element, options)
+			for key, value := range element {
+				if previous, exists := result[key]; exists {
+					result[key] = previous + ListSeparator + value
+				} else {
+					result[key] = value
+				}
+			}
+		}
+	case nil:
+	default:
+		result[path] = fmt.Sprintf("%v", v)
+	}
+}
+
+func joinPath(path string, key string, options Options) string {
+	if path == "" {
+		return key
+	}
+	return path + options.Separator + key
+}
+
+// joinLabels returns the labels as key=value pairs sorted by key.
+func joinLabels(labels map[string]interface{}) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", key, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ListSeparator)
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package export
+
+import (
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Flatten", func() {
+
+	asset := &grpc_inventory_go.Asset{
+		OrganizationId: "org",
+		AssetId:        "asset",
+		Created:        1234,
+		Labels:         map[string]string{"env": "prod", "area": "north"},
+		Os:             &grpc_inventory_go.OperatingSystemInfo{Name: "windows", Class: grpc_inventory_go.OperatingSystemClass_WINDOWS},
+		Hardware: &grpc_inventory_go.HardwareInfo{
+			Cpus: []*grpc_inventory_go.CPUInfo{{NumCores: 4}, {NumCores: 2}},
+		},
+	}
+
+	ginkgo.It("should use the field names and values of the API", func() {
+		fields, err := Flatten(asset, DefaultOptions())
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(fields).To(gomega.HaveKeyWithValue("asset_id", "asset"))
+		gomega.Expect(fields).To(gomega.HaveKeyWithValue("created", "1234"))
+		gomega.Expect(fields).To(gomega.HaveKeyWithValue("os.class", "WINDOWS"))
+		gomega.Expect(fields).To(gomega.HaveKeyWithValue("labels.env", "prod"))
+		gomega.Expect(fields).To(gomega.HaveKeyWithValue("hardware.cpus.0.num_cores", "4"))
+		gomega.Expect(fields).To(gomega.HaveKeyWithValue("hardware.cpus.1.num_cores", "2"))
+	})
+
+	ginkgo.It("should include the fields with default values", func() {
+		linux := &grpc_inventory_go.Asset{AssetId: "asset", Os: &grpc_inventory_go.OperatingSystemInfo{}}
+		fields, err := Flatten(linux, DefaultOptions())
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(fields).To(gomega.HaveKeyWithValue("os.class", "LINUX"))
+		gomega.Expect(fields).To(gomega.HaveKeyWithValue("show", "false"))
+		gomega.Expect(fields).To(gomega.HaveKeyWithValue("last_alive_timestamp", "0"))
+		gomega.Expect(fields).NotTo(gomega.HaveKey("hardware"))
+	})
+
+	ginkgo.It("should join the lists and the labels", func() {
+		options := Options{Separator: "_", JoinLists: true, SingleLabels: true}
+		fields, err := Flatten(asset, options)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(fields).To(gomega.HaveKeyWithValue("hardware_cpus_num_cores", "4;2"))
+		gomega.Expect(fields).To(gomega.HaveKeyWithValue("labels", "area=north;env=prod"))
+	})
+
+	ginkgo.It("should flatten the values that are not messages", func() {
+		object := map[string]interface{}{
+			"name":   "printer",
+			"labels": map[string]interface{}{"floor": "2"},
+			"size":   12345678901,
+		}
+		fields, err := Flatten(object, DefaultOptions())
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(fields).To(gomega.Equal(map[string]string{"name": "printer", "labels.floor": "2", "size": "12345678901"}))
+	})
+})
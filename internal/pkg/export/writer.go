@@ -0,0 +1,158 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"gopkg.in/yaml.v2"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Record is a flattened entity.
+type Record map[string]string
+
+// leadingColumns are written before the rest of the columns.
+var leadingColumns = []string{"type", "id"}
+
+// Columns returns the columns to export. If fields are requested, only the columns matching a field or
+// nested in a field are returned, e.g. hardware selects hardware.installed_ram.
+func Columns(records []Record, fields []string, separator string) []string {
+	if separator == "" {
+		separator = DefaultSeparator
+	}
+	present := make(map[string]bool)
+	for _, record := range records {
+		for column := range record {
+			present[column] = true
+		}
+	}
+	selected := make([]string, 0, len(present))
+	for column := range present {
+		if isLeadingColumn(column) || !matchesFields(column, fields, separator) {
+			continue
+		}
+		selected = append(selected, column)
+	}
+	sort.Strings(selected)
+
+	result := make([]string, 0, len(selected)+len(leadingColumns))
+	for _, column := range leadingColumns {
+		if present[column] {
+			result = append(result, column)
+		}
+	}
+	return append(result, selected...)
+}
+
+func isLeadingColumn(column string) bool {
+	for _, leading := range leadingColumns {
+		if column == leading {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesFields(column string, fields []string, separator string) bool {
+	if len(fields) == 0 {
+		return true
+	}
+	for _, field := range fields {
+		if column == field || strings.HasPrefix(column, field+separator) {
+			return true
+		}
+	}
+	return false
+}
+
+// Write writes the records in the requested format including only the given columns.
+func Write(w io.Writer, format grpc_inventory_manager_go.ExportFormat, records []Record, columns []string) derrors.Error {
+	switch format {
+	case grpc_inventory_manager_go.ExportFormat_CSV:
+		return writeCSV(w, records, columns)
+	case grpc_inventory_manager_go.ExportFormat_JSONL:
+		return writeJSONL(w, records, columns)
+	case grpc_inventory_manager_go.ExportFormat_YAML:
+		return writeYAML(w, records, columns)
+	}
+	return derrors.NewInvalidArgumentError("unsupported export format").WithParams(format)
+}
+
+func writeCSV(w io.Writer, records []Record, columns []string) derrors.Error {
+	writer := csv.NewWriter(w)
+	err := writer.Write(columns)
+	if err != nil {
+		return derrors.AsError(err, "cannot write CSV header")
+	}
+	row := make([]string, len(columns))
+	for _, record := range records {
+		for index, column := range columns {
+			row[index] = record[column]
+		}
+		err = writer.Write(row)
+		if err != nil {
+			return derrors.AsError(err, "cannot write CSV record")
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return derrors.AsError(err, "cannot write CSV")
+	}
+	return nil
+}
+
+// selectColumns returns the values of the record in the given columns, skipping the empty ones.
+func selectColumns(record Record, columns []string) map[string]string {
+	result := make(map[string]string, len(columns))
+	for _, column := range columns {
+		if value, exists := record[column]; exists {
+			result[column] = value
+		}
+	}
+	return result
+}
+
+func writeJSONL(w io.Writer, records []Record, columns []string) derrors.Error {
+	encoder := json.NewEncoder(w)
+	for _, record := range records {
+		err := encoder.Encode(selectColumns(record, columns))
+		if err != nil {
+			return derrors.AsError(err, "cannot write JSON record")
+		}
+	}
+	return nil
+}
+
+// writeYAML writes the records as a YAML sequence, one element per record.
+func writeYAML(w io.Writer, records []Record, columns []string) derrors.Error {
+	for _, record := range records {
+		raw, err := yaml.Marshal([]map[string]string{selectColumns(record, columns)})
+		if err != nil {
+			return derrors.AsError(err, "cannot serialize YAML record")
+		}
+		_, err = w.Write(raw)
+		if err != nil {
+			return derrors.AsError(err, "cannot write YAML record")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package export
+
+import (
+	"bytes"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Writer", func() {
+
+	records := []Record{
+		{"type": "asset", "id": "a1", "labels.env": "prod", "hardware.installed_ram": "1024"},
+		{"type": "device", "id": "d1", "os.name": "linux"},
+	}
+
+	ginkgo.It("should sort the columns after the type and the identifier", func() {
+		columns := Columns(records, nil, "")
+		gomega.Expect(columns).To(gomega.Equal([]string{"type", "id", "hardware.installed_ram", "labels.env", "os.name"}))
+	})
+
+	ginkgo.It("should select the requested fields and their nested fields", func() {
+		columns := Columns(records, []string{"labels", "os.name", "hard"}, DefaultSeparator)
+		gomega.Expect(columns).To(gomega.Equal([]string{"type", "id", "labels.env", "os.name"}))
+	})
+
+	ginkgo.It("should write CSV with a header", func() {
+		var buffer bytes.Buffer
+		err := Write(&buffer, grpc_inventory_manager_go.ExportFormat_CSV, records, []string{"type", "id", "os.name"})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(buffer.String()).To(gomega.Equal("type,id,os.name\nasset,a1,\ndevice,d1,linux\n"))
+	})
+
+	ginkgo.It("should write JSON Lines skipping the missing values", func() {
+		var buffer bytes.Buffer
+		err := Write(&buffer, grpc_inventory_manager_go.ExportFormat_JSONL, records, []string{"id", "os.name"})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(buffer.String()).To(gomega.Equal("{\"id\":\"a1\"}\n{\"id\":\"d1\",\"os.name\":\"linux\"}\n"))
+	})
+
+	ginkgo.It("should write YAML as a sequence of records", func() {
+		var buffer bytes.Buffer
+		err := Write(&buffer, grpc_inventory_manager_go.ExportFormat_YAML, records, []string{"id", "os.name"})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(buffer.String()).To(gomega.Equal("- id: a1\n- id: d1\n  os.name: linux\n"))
+	})
+
+	ginkgo.It("should reject the unknown formats", func() {
+		var buffer bytes.Buffer
+		err := Write(&buffer, grpc_inventory_manager_go.ExportFormat(-1), records, []string{"id"})
+		gomega.Expect(err).NotTo(gomega.Succeed())
+	})
+})
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jobs
+
+import (
+	"context"
+	"github.com/nalej/derrors"
+	"sync"
+	"time"
+)
+
+// Group runs the periodic jobs of the inventory manager so they can be stopped on shutdown.
+type Group struct {
+	// ctx is cancelled to stop launching the jobs.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// running with the goroutines of the jobs.
+	running sync.WaitGroup
+}
+
+func NewGroup() *Group {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Group{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Every launches a job that is executed each period until the group is stopped. The job receives a context that is
+// cancelled when the group is stopped so it can return before acting on the next organization, but an execution in
+// progress is not interrupted.
+func (g *Group) Every(period time.Duration, job func(ctx context.Context)) {
+	g.running.Add(1)
+	go func() {
+		defer g.running.Done()
+		ticker := time.NewTicker(period)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if g.ctx.Err() == nil {
+					job(g.ctx)
+				}
+			case <-g.ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops launching the jobs.
+func (g *Group) Stop() {
+	g.cancel()
+}
+
+// Wait waits for the executions in progress to finish. An error is returned if they do not finish before the context
+// expires.
+func (g *Group) Wait(ctx context.Context) derrors.Error {
+	finished := make(chan struct{})
+	go func() {
+		g.running.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return derrors.NewDeadlineExceededError("the periodic jobs are still running")
+	}
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package jobs
+
+import (
+	"context"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"sync/atomic"
+	"time"
+)
+
+var _ = ginkgo.Describe("Group", func() {
+
+	ginkgo.It("should run the jobs periodically until stopped", func() {
+		group := NewGroup()
+		var executions int32
+		group.Every(time.Millisecond, func(ctx context.Context) {
+			atomic.AddInt32(&executions, 1)
+		})
+		gomega.Eventually(func() int32 { return atomic.LoadInt32(&executions) }).Should(gomega.BeNumerically(">=", 2))
+		group.Stop()
+		gomega.Expect(group.Wait(context.Background())).To(gomega.Succeed())
+		stopped := atomic.LoadInt32(&executions)
+		time.Sleep(10 * time.Millisecond)
+		gomega.Expect(atomic.LoadInt32(&executions)).To(gomega.Equal(stopped))
+	})
+
+	ginkgo.It("should wait for the execution in progress", func() {
+		group := NewGroup()
+		started := make(chan struct{})
+		release := make(chan struct{})
+		var finished int32
+		group.Every(time.Millisecond, func(ctx context.Context) {
+			if atomic.LoadInt32(&finished) > 0 {
+				return
+			}
+			close(started)
+			<-ctx.Done()
+			<-release
+			atomic.StoreInt32(&finished, 1)
+		})
+		<-started
+		group.Stop()
+
+		expired, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		gomega.Expect(group.Wait(expired)).NotTo(gomega.Succeed())
+
+		close(release)
+		gomega.Expect(group.Wait(context.Background())).To(gomega.Succeed())
+		gomega.Expect(atomic.LoadInt32(&finished)).To(gomega.Equal(int32(1)))
+	})
+})
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package attribute
+
+import (
+	"encoding/json"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// AttributeSchemasFile with the name of the file that contains the attribute schemas.
+const AttributeSchemasFile = "attribute_schemas.json"
+
+// FileProvider keeps the schemas in memory and writes them to a file on every change.
+type FileProvider struct {
+	*MemoryProvider
+	path string
+}
+
+func NewFileProvider(storagePath string) (*FileProvider, derrors.Error) {
+	err := os.MkdirAll(storagePath, 0700)
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot create storage directory")
+	}
+	provider := &FileProvider{
+		MemoryProvider: NewMemoryProvider(),
+		path:           filepath.Join(storagePath, AttributeSchemasFile),
+	}
+	content, err := ioutil.ReadFile(provider.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return provider, nil
+		}
+		return nil, derrors.AsError(err, "cannot read attribute schemas")
+	}
+	schemas := make([]*grpc_inventory_manager_go.AttributeSchema, 0)
+	err = json.Unmarshal(content, &schemas)
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot parse attribute schemas")
+	}
+	for _, schema := range schemas {
+		provider.schemas[schema.OrganizationId] = schema
+	}
+	return provider, nil
+}
+
+func (f *FileProvider) Set(schema *grpc_inventory_manager_go.AttributeSchema) derrors.Error {
+	f.Lock()
+	defer f.Unlock()
+	f.schemas[schema.OrganizationId] = schema
+	return f.unsafeWrite()
+}
+
+// unsafeWrite writes the schemas to a temporal file that replaces the previous one.
+func (f *FileProvider) unsafeWrite() derrors.Error {
+	content, err := json.Marshal(f.unsafeAll())
+	if err != nil {
+		return derrors.AsError(err, "cannot serialize attribute schemas")
+	}
+	tmp := f.path + ".tmp"
+	err = ioutil.WriteFile(tmp, content, 0600)
+	if err != nil {
+		return derrors.AsError(err, "cannot write attribute schemas")
+	}
+	err = os.Rename(tmp, f.path)
+	if err != nil {
+		return derrors.AsError(err, "cannot write attribute schemas")
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package attribute
+
+import (
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"sort"
+	"sync"
+)
+
+type MemoryProvider struct {
+	sync.Mutex
+	// schemas indexed by organization.
+	schemas map[string]*grpc_inventory_manager_go.AttributeSchema
+}
+
+func NewMemoryProvider() *MemoryProvider {
+	return &MemoryProvider{
+		schemas: make(map[string]*grpc_inventory_manager_go.AttributeSchema),
+	}
+}
+
+func (m *MemoryProvider) Set(schema *grpc_inventory_manager_go.AttributeSchema) derrors.Error {
+	m.Lock()
+	defer m.Unlock()
+	m.schemas[schema.OrganizationId] = schema
+	return nil
+}
+
+func (m *MemoryProvider) Get(organizationID string) (*grpc_inventory_manager_go.AttributeSchema, derrors.Error) {
+	m.Lock()
+	defer m.Unlock()
+	schema, exists := m.schemas[organizationID]
+	if !exists {
+		return nil, derrors.NewNotFoundError("attribute schema").WithParams(organizationID)
+	}
+	return schema, nil
+}
+
+// unsafeAll returns all the stored schemas sorted by organization.
+func (m *MemoryProvider) unsafeAll() []*grpc_inventory_manager_go.AttributeSchema {
+	result := make([]*grpc_inventory_manager_go.AttributeSchema, 0, len(m.schemas))
+	for _, schema := range m.schemas {
+		result = append(result, schema)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].OrganizationId < result[j].OrganizationId
+	})
+	return result
+}
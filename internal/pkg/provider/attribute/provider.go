@@ -0,0 +1,38 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package attribute
+
+import (
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+)
+
+// Provider stores the schema of the custom attributes of each organization.
+type Provider interface {
+	// Set adds or replaces the schema of an organization.
+	Set(schema *grpc_inventory_manager_go.AttributeSchema) derrors.Error
+	// Get retrieves the schema of an organization.
+	Get(organizationID string) (*grpc_inventory_manager_go.AttributeSchema, derrors.Error)
+}
+
+// NewProvider creates a provider storing the schemas in the given directory, or in memory if the path is empty.
+func NewProvider(storagePath string) (Provider, derrors.Error) {
+	if storagePath == "" {
+		return NewMemoryProvider(), nil
+	}
+	return NewFileProvider(storagePath)
+}
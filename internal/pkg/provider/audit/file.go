@@ -0,0 +1,130 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/storage"
+	"github.com/rs/zerolog/log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// AuditDir with the name of the directory that contains the audit log.
+const AuditDir = "audit"
+
+// auditExtension with the extension of the files, one JSON entry per line.
+const auditExtension = ".jsonl"
+
+// marshaler writes the entries with the field names of the API.
+var marshaler = jsonpb.Marshaler{OrigName: true}
+
+// unmarshaler reads the entries ignoring the fields removed from the API.
+var unmarshaler = jsonpb.Unmarshaler{AllowUnknownFields: true}
+
+// FileProvider appends the entries of each organization to a file. The files are read on every query so the log
+// is not kept in memory.
+type FileProvider struct {
+	sync.Mutex
+	path string
+}
+
+func NewFileProvider(storagePath string) (*FileProvider, derrors.Error) {
+	provider := &FileProvider{
+		path: filepath.Join(storagePath, AuditDir),
+	}
+	err := os.MkdirAll(provider.path, 0700)
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot create audit directory")
+	}
+	return provider, nil
+}
+
+func (f *FileProvider) fileName(organizationID string) string {
+	return filepath.Join(f.path, organizationID+auditExtension)
+}
+
+func (f *FileProvider) Add(entry *grpc_inventory_manager_go.AuditEntry) derrors.Error {
+	if !storage.ValidFileName(entry.OrganizationId) {
+		return derrors.NewInvalidArgumentError("invalid organization identifier").WithParams(entry.OrganizationId)
+	}
+	var content bytes.Buffer
+	err := marshaler.Marshal(&content, entry)
+	if err != nil {
+		return derrors.AsError(err, "cannot serialize audit entry")
+	}
+	content.WriteByte('\n')
+	f.Lock()
+	defer f.Unlock()
+	file, err := os.OpenFile(f.fileName(entry.OrganizationId), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return derrors.AsError(err, "cannot open audit log")
+	}
+	defer file.Close()
+	_, err = file.Write(content.Bytes())
+	if err != nil {
+		return derrors.AsError(err, "cannot write audit entry")
+	}
+	return nil
+}
+
+func (f *FileProvider) List(organizationID string, from int64, to int64) ([]*grpc_inventory_manager_go.AuditEntry, derrors.Error) {
+	result := make([]*grpc_inventory_manager_go.AuditEntry, 0)
+	if !storage.ValidFileName(organizationID) {
+		return result, nil
+	}
+	f.Lock()
+	defer f.Unlock()
+	file, err := os.Open(f.fileName(organizationID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return result, nil
+		}
+		return nil, derrors.AsError(err, "cannot read audit log")
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	// The entries include the redacted requests so they may exceed the default line limit.
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		entry := &grpc_inventory_manager_go.AuditEntry{}
+		err = unmarshaler.Unmarshal(bytes.NewReader(scanner.Bytes()), entry)
+		if err != nil {
+			// a line truncated by a crash must not hide the rest of the log
+			log.Warn().Str("organization_id", organizationID).Int("line", line).Str("error", err.Error()).
+				Msg("skipping corrupt audit entry")
+			continue
+		}
+		if inRange(entry, from, to) {
+			result = append(result, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, derrors.AsError(err, "cannot read audit log")
+	}
+	return result, nil
+}
@@ -0,0 +1,63 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audit
+
+import (
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"sync"
+)
+
+// MaxMemoryEntries with the number of entries kept in memory per organization. The oldest entries are dropped once
+// the limit is reached, use a storage path to keep the complete audit log.
+const MaxMemoryEntries = 10000
+
+type MemoryProvider struct {
+	sync.Mutex
+	// entries of each organization in the order they were added.
+	entries map[string][]*grpc_inventory_manager_go.AuditEntry
+}
+
+func NewMemoryProvider() *MemoryProvider {
+	return &MemoryProvider{
+		entries: make(map[string][]*grpc_inventory_manager_go.AuditEntry),
+	}
+}
+
+func (m *MemoryProvider) Add(entry *grpc_inventory_manager_go.AuditEntry) derrors.Error {
+	m.Lock()
+	defer m.Unlock()
+	entries := append(m.entries[entry.OrganizationId], entry)
+	if len(entries) > MaxMemoryEntries {
+		// append moves the entries to a new array when the capacity runs out, releasing the dropped ones
+		entries = entries[len(entries)-MaxMemoryEntries:]
+	}
+	m.entries[entry.OrganizationId] = entries
+	return nil
+}
+
+func (m *MemoryProvider) List(organizationID string, from int64, to int64) ([]*grpc_inventory_manager_go.AuditEntry, derrors.Error) {
+	m.Lock()
+	defer m.Unlock()
+	result := make([]*grpc_inventory_manager_go.AuditEntry, 0)
+	for _, entry := range m.entries[organizationID] {
+		if inRange(entry, from, to) {
+			result = append(result, entry)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,43 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audit
+
+import (
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+)
+
+// Provider stores the audit log of the organizations. The entries cannot be modified or removed.
+type Provider interface {
+	// Add appends an entry to the log of its organization.
+	Add(entry *grpc_inventory_manager_go.AuditEntry) derrors.Error
+	// List the entries of an organization between two timestamps, both included, in the order they were added.
+	List(organizationID string, from int64, to int64) ([]*grpc_inventory_manager_go.AuditEntry, derrors.Error)
+}
+
+// NewProvider creates a provider storing the log in the given directory, or in memory if the path is empty.
+func NewProvider(storagePath string) (Provider, derrors.Error) {
+	if storagePath == "" {
+		return NewMemoryProvider(), nil
+	}
+	return NewFileProvider(storagePath)
+}
+
+// inRange checks if an entry was recorded between two timestamps.
+func inRange(entry *grpc_inventory_manager_go.AuditEntry, from int64, to int64) bool {
+	return entry.Timestamp >= from && entry.Timestamp <= to
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deadletter
+
+import (
+	"encoding/json"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/storage"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DeadLettersDir with the name of the directory that contains the dead letters.
+const DeadLettersDir = "deadletters"
+
+// deadLettersExtension with the extension of the files with the dead letters of an organization.
+const deadLettersExtension = ".json"
+
+// FileProvider keeps the dead letters in memory. The dead letters of an organization are written to a file on every
+// change.
+type FileProvider struct {
+	*MemoryProvider
+	path string
+}
+
+func NewFileProvider(storagePath string) (*FileProvider, derrors.Error) {
+	provider := &FileProvider{
+		MemoryProvider: NewMemoryProvider(),
+		path:           filepath.Join(storagePath, DeadLettersDir),
+	}
+	err := os.MkdirAll(provider.path, 0700)
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot create dead letters directory")
+	}
+	files, err := ioutil.ReadDir(provider.path)
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot read dead letters directory")
+	}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), deadLettersExtension) {
+			continue
+		}
+		rErr := provider.read(strings.TrimSuffix(file.Name(), deadLettersExtension))
+		if rErr != nil {
+			return nil, rErr
+		}
+	}
+	return provider, nil
+}
+
+func (f *FileProvider) fileName(organizationID string) string {
+	return filepath.Join(f.path, organizationID+deadLettersExtension)
+}
+
+func (f *FileProvider) read(organizationID string) derrors.Error {
+	content, err := ioutil.ReadFile(f.fileName(organizationID))
+	if err != nil {
+		return derrors.AsError(err, "cannot read dead letters").WithParams(organizationID)
+	}
+	deadLetters := make([]*grpc_inventory_manager_go.DeadLetter, 0)
+	err = json.Unmarshal(content, &deadLetters)
+	if err != nil {
+		return derrors.AsError(err, "cannot parse dead letters").WithParams(organizationID)
+	}
+	for _, deadLetter := range deadLetters {
+		f.unsafeStore(deadLetter)
+	}
+	return nil
+}
+
+// Store keeps the dead letter and writes the file of its organization.
+func (f *FileProvider) Store(deadLetter *grpc_inventory_manager_go.DeadLetter) derrors.Error {
+	if !storage.ValidFileName(deadLetter.OrganizationId) {
+		return derrors.NewInvalidArgumentError("invalid organization identifier").WithParams(deadLetter.OrganizationId)
+	}
+	f.Lock()
+	defer f.Unlock()
+	f.unsafeStore(deadLetter)
+	return f.unsafeWrite(deadLetter.OrganizationId)
+}
+
+func (f *FileProvider) Remove(organizationID string, deadLetterID string) derrors.Error {
+	f.Lock()
+	defer f.Unlock()
+	err := f.unsafeRemove(organizationID, deadLetterID)
+	if err != nil {
+		return err
+	}
+	return f.unsafeWrite(organizationID)
+}
+
+// unsafeWrite writes the dead letters of an organization to a temporal file that replaces the previous one. The
+// file is removed when the organization has no dead letters.
+func (f *FileProvider) unsafeWrite(organizationID string) derrors.Error {
+	deadLetters := f.unsafeList(organizationID)
+	if len(deadLetters) == 0 {
+		err := os.Remove(f.fileName(organizationID))
+		if err != nil && !os.IsNotExist(err) {
+			return derrors.AsError(err, "cannot remove dead letters").WithParams(organizationID)
+		}
+		return nil
+	}
+	content, err := json.Marshal(deadLetters)
+	if err != nil {
+		return derrors.AsError(err, "cannot serialize dead letters")
+	}
+	tmp := f.fileName(organizationID) + ".tmp"
+	err = ioutil.WriteFile(tmp, content, 0600)
+	if err != nil {
+		return derrors.AsError(err, "cannot write dead letters")
+	}
+	err = os.Rename(tmp, f.fileName(organizationID))
+	if err != nil {
+		return derrors.AsError(err, "cannot write dead letters")
+	}
+	return nil
+}
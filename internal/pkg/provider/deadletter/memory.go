@@ -0,0 +1,100 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deadletter
+
+import (
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"sort"
+	"sync"
+)
+
+type MemoryProvider struct {
+	sync.Mutex
+	// deadLetters of each organization indexed by dead_letter_id.
+	deadLetters map[string]map[string]*grpc_inventory_manager_go.DeadLetter
+}
+
+func NewMemoryProvider() *MemoryProvider {
+	return &MemoryProvider{
+		deadLetters: make(map[string]map[string]*grpc_inventory_manager_go.DeadLetter),
+	}
+}
+
+func (m *MemoryProvider) Store(deadLetter *grpc_inventory_manager_go.DeadLetter) derrors.Error {
+	m.Lock()
+	defer m.Unlock()
+	m.unsafeStore(deadLetter)
+	return nil
+}
+
+func (m *MemoryProvider) unsafeStore(deadLetter *grpc_inventory_manager_go.DeadLetter) {
+	org, exists := m.deadLetters[deadLetter.OrganizationId]
+	if !exists {
+		org = make(map[string]*grpc_inventory_manager_go.DeadLetter)
+		m.deadLetters[deadLetter.OrganizationId] = org
+	}
+	org[deadLetter.DeadLetterId] = deadLetter
+}
+
+func (m *MemoryProvider) Get(organizationID string, deadLetterID string) (*grpc_inventory_manager_go.DeadLetter, derrors.Error) {
+	m.Lock()
+	defer m.Unlock()
+	deadLetter, exists := m.deadLetters[organizationID][deadLetterID]
+	if !exists {
+		return nil, derrors.NewNotFoundError("dead letter").WithParams(organizationID, deadLetterID)
+	}
+	return deadLetter, nil
+}
+
+func (m *MemoryProvider) Remove(organizationID string, deadLetterID string) derrors.Error {
+	m.Lock()
+	defer m.Unlock()
+	return m.unsafeRemove(organizationID, deadLetterID)
+}
+
+func (m *MemoryProvider) unsafeRemove(organizationID string, deadLetterID string) derrors.Error {
+	if _, exists := m.deadLetters[organizationID][deadLetterID]; !exists {
+		return derrors.NewNotFoundError("dead letter").WithParams(organizationID, deadLetterID)
+	}
+	delete(m.deadLetters[organizationID], deadLetterID)
+	if len(m.deadLetters[organizationID]) == 0 {
+		delete(m.deadLetters, organizationID)
+	}
+	return nil
+}
+
+func (m *MemoryProvider) List(organizationID string) ([]*grpc_inventory_manager_go.DeadLetter, derrors.Error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.unsafeList(organizationID), nil
+}
+
+// unsafeList returns the dead letters of an organization sorted by reception time.
+func (m *MemoryProvider) unsafeList(organizationID string) []*grpc_inventory_manager_go.DeadLetter {
+	result := make([]*grpc_inventory_manager_go.DeadLetter, 0, len(m.deadLetters[organizationID]))
+	for _, deadLetter := range m.deadLetters[organizationID] {
+		result = append(result, deadLetter)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Received != result[j].Received {
+			return result[i].Received < result[j].Received
+		}
+		return result[i].DeadLetterId < result[j].DeadLetterId
+	})
+	return result
+}
@@ -0,0 +1,42 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deadletter
+
+import (
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+)
+
+// Provider stores the bus messages that could not be processed so they can be inspected and replayed.
+type Provider interface {
+	// Store adds or replaces a dead letter.
+	Store(deadLetter *grpc_inventory_manager_go.DeadLetter) derrors.Error
+	// Get retrieves a dead letter of an organization.
+	Get(organizationID string, deadLetterID string) (*grpc_inventory_manager_go.DeadLetter, derrors.Error)
+	// Remove removes a dead letter of an organization.
+	Remove(organizationID string, deadLetterID string) derrors.Error
+	// List retrieves the dead letters of an organization sorted by reception time.
+	List(organizationID string) ([]*grpc_inventory_manager_go.DeadLetter, derrors.Error)
+}
+
+// NewProvider creates a provider storing the dead letters in the given directory, or in memory if the path is empty.
+func NewProvider(storagePath string) (Provider, derrors.Error) {
+	if storagePath == "" {
+		return NewMemoryProvider(), nil
+	}
+	return NewFileProvider(storagePath)
+}
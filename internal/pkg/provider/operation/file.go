@@ -0,0 +1,128 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operation
+
+import (
+	"encoding/json"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/storage"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// OperationsDir with the name of the directory that contains the operation histories.
+const OperationsDir = "operations"
+
+// operationsExtension with the extension of the history files.
+const operationsExtension = ".json"
+
+// FileProvider stores the history of each controller in a file named after its identifier inside a directory per
+// organization. The files are read on every query so the histories are not kept in memory.
+type FileProvider struct {
+	sync.Mutex
+	path string
+	// size with the maximum number of operations kept for each controller.
+	size int
+}
+
+func NewFileProvider(storagePath string, size int) (*FileProvider, derrors.Error) {
+	provider := &FileProvider{
+		path: filepath.Join(storagePath, OperationsDir),
+		size: size,
+	}
+	err := os.MkdirAll(provider.path, 0700)
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot create operations directory")
+	}
+	return provider, nil
+}
+
+func (f *FileProvider) fileName(organizationID string, edgeControllerID string) string {
+	return filepath.Join(f.path, organizationID, edgeControllerID+operationsExtension)
+}
+
+// read returns the stored operations of a controller, or an empty history if there is none.
+func (f *FileProvider) read(organizationID string, edgeControllerID string) ([]*grpc_inventory_manager_go.OperationRecord, derrors.Error) {
+	operations := make([]*grpc_inventory_manager_go.OperationRecord, 0)
+	content, err := ioutil.ReadFile(f.fileName(organizationID, edgeControllerID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return operations, nil
+		}
+		return nil, derrors.AsError(err, "cannot read operation history")
+	}
+	err = json.Unmarshal(content, &operations)
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot parse operation history").WithParams(organizationID, edgeControllerID)
+	}
+	return operations, nil
+}
+
+func (f *FileProvider) Add(organizationID string, edgeControllerID string, record *grpc_inventory_manager_go.OperationRecord) derrors.Error {
+	if !storage.ValidFileName(organizationID) || !storage.ValidFileName(edgeControllerID) {
+		return derrors.NewInvalidArgumentError("invalid edge controller identifier").WithParams(organizationID, edgeControllerID)
+	}
+	f.Lock()
+	defer f.Unlock()
+	operations, rErr := f.read(organizationID, edgeControllerID)
+	if rErr != nil {
+		return rErr
+	}
+	content, err := json.Marshal(latest(append(operations, record), f.size))
+	if err != nil {
+		return derrors.AsError(err, "cannot serialize operation history")
+	}
+	err = os.MkdirAll(filepath.Join(f.path, organizationID), 0700)
+	if err != nil {
+		return derrors.AsError(err, "cannot create operations directory")
+	}
+	name := f.fileName(organizationID, edgeControllerID)
+	err = ioutil.WriteFile(name+".tmp", content, 0600)
+	if err != nil {
+		return derrors.AsError(err, "cannot write operation history")
+	}
+	err = os.Rename(name+".tmp", name)
+	if err != nil {
+		return derrors.AsError(err, "cannot write operation history")
+	}
+	return nil
+}
+
+func (f *FileProvider) List(organizationID string, edgeControllerID string) ([]*grpc_inventory_manager_go.OperationRecord, derrors.Error) {
+	if !storage.ValidFileName(organizationID) || !storage.ValidFileName(edgeControllerID) {
+		return make([]*grpc_inventory_manager_go.OperationRecord, 0), nil
+	}
+	f.Lock()
+	defer f.Unlock()
+	return f.read(organizationID, edgeControllerID)
+}
+
+func (f *FileProvider) Remove(organizationID string, edgeControllerID string) derrors.Error {
+	if !storage.ValidFileName(organizationID) || !storage.ValidFileName(edgeControllerID) {
+		return nil
+	}
+	f.Lock()
+	defer f.Unlock()
+	err := os.Remove(f.fileName(organizationID, edgeControllerID))
+	if err != nil && !os.IsNotExist(err) {
+		return derrors.AsError(err, "cannot remove operation history")
+	}
+	return nil
+}
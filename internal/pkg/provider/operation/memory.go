@@ -0,0 +1,74 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operation
+
+import (
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"sync"
+)
+
+type MemoryProvider struct {
+	sync.Mutex
+	// size with the maximum number of operations kept for each controller.
+	size int
+	// operations indexed by organization and controller identifier.
+	operations map[string]map[string][]*grpc_inventory_manager_go.OperationRecord
+}
+
+func NewMemoryProvider(size int) *MemoryProvider {
+	return &MemoryProvider{
+		size:       size,
+		operations: make(map[string]map[string][]*grpc_inventory_manager_go.OperationRecord),
+	}
+}
+
+func (m *MemoryProvider) Add(organizationID string, edgeControllerID string, record *grpc_inventory_manager_go.OperationRecord) derrors.Error {
+	m.Lock()
+	defer m.Unlock()
+	orgOperations, exists := m.operations[organizationID]
+	if !exists {
+		orgOperations = make(map[string][]*grpc_inventory_manager_go.OperationRecord)
+		m.operations[organizationID] = orgOperations
+	}
+	orgOperations[edgeControllerID] = latest(append(orgOperations[edgeControllerID], record), m.size)
+	return nil
+}
+
+func (m *MemoryProvider) List(organizationID string, edgeControllerID string) ([]*grpc_inventory_manager_go.OperationRecord, derrors.Error) {
+	m.Lock()
+	defer m.Unlock()
+	operations := m.operations[organizationID][edgeControllerID]
+	result := make([]*grpc_inventory_manager_go.OperationRecord, len(operations))
+	copy(result, operations)
+	return result, nil
+}
+
+func (m *MemoryProvider) Remove(organizationID string, edgeControllerID string) derrors.Error {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.operations[organizationID], edgeControllerID)
+	return nil
+}
+
+// latest returns the last operations that fit in the history.
+func latest(operations []*grpc_inventory_manager_go.OperationRecord, size int) []*grpc_inventory_manager_go.OperationRecord {
+	if len(operations) > size {
+		return operations[len(operations)-size:]
+	}
+	return operations
+}
@@ -0,0 +1,41 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operation
+
+import (
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+)
+
+// Provider stores the latest operation results of the edge controllers and their assets.
+type Provider interface {
+	// Add appends an operation of a controller discarding the oldest ones once the size of the history is reached.
+	Add(organizationID string, edgeControllerID string, record *grpc_inventory_manager_go.OperationRecord) derrors.Error
+	// List retrieves the operations of a controller in the order they were added.
+	List(organizationID string, edgeControllerID string) ([]*grpc_inventory_manager_go.OperationRecord, derrors.Error)
+	// Remove discards the operations of a controller.
+	Remove(organizationID string, edgeControllerID string) derrors.Error
+}
+
+// NewProvider creates a provider keeping the given number of operations per controller in the given directory, or
+// in memory if the path is empty.
+func NewProvider(storagePath string, size int) (Provider, derrors.Error) {
+	if storagePath == "" {
+		return NewMemoryProvider(size), nil
+	}
+	return NewFileProvider(storagePath, size)
+}
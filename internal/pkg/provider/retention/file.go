@@ -0,0 +1,189 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package retention
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/storage"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PoliciesFile with the name of the file that contains the retention policies.
+const PoliciesFile = "retention_policies.json"
+
+// ArchiveDir with the name of the directory that contains the archived entities.
+const ArchiveDir = "archive"
+
+// archiveExtension with the extension of the archive files, one JSON record per line.
+const archiveExtension = ".jsonl"
+
+// FileProvider keeps the policies and the archive in memory. The policies are written to a file on every change and
+// the archived entities are appended to a file per organization.
+type FileProvider struct {
+	*MemoryProvider
+	policiesPath string
+	archivePath  string
+}
+
+func NewFileProvider(storagePath string) (*FileProvider, derrors.Error) {
+	provider := &FileProvider{
+		MemoryProvider: NewMemoryProvider(),
+		policiesPath:   filepath.Join(storagePath, PoliciesFile),
+		archivePath:    filepath.Join(storagePath, ArchiveDir),
+	}
+	err := os.MkdirAll(provider.archivePath, 0700)
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot create archive directory")
+	}
+	rErr := provider.readPolicies()
+	if rErr != nil {
+		return nil, rErr
+	}
+	files, err := ioutil.ReadDir(provider.archivePath)
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot read archive directory")
+	}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), archiveExtension) {
+			continue
+		}
+		rErr := provider.readArchive(strings.TrimSuffix(file.Name(), archiveExtension))
+		if rErr != nil {
+			return nil, rErr
+		}
+	}
+	return provider, nil
+}
+
+func (f *FileProvider) archiveName(organizationID string) string {
+	return filepath.Join(f.archivePath, organizationID+archiveExtension)
+}
+
+func (f *FileProvider) readPolicies() derrors.Error {
+	content, err := ioutil.ReadFile(f.policiesPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return derrors.AsError(err, "cannot read retention policies")
+	}
+	policies := make([]*grpc_inventory_manager_go.RetentionPolicy, 0)
+	err = json.Unmarshal(content, &policies)
+	if err != nil {
+		return derrors.AsError(err, "cannot parse retention policies")
+	}
+	for _, policy := range policies {
+		f.policies[policy.OrganizationId] = policy
+	}
+	return nil
+}
+
+func (f *FileProvider) readArchive(organizationID string) derrors.Error {
+	file, err := os.Open(f.archiveName(organizationID))
+	if err != nil {
+		return derrors.AsError(err, "cannot read archive")
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	// The records include the complete entity so they may exceed the default line limit.
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		entity := &grpc_inventory_manager_go.ArchivedEntity{}
+		err = json.Unmarshal(scanner.Bytes(), entity)
+		if err != nil {
+			return derrors.AsError(err, "cannot parse archive record").WithParams(organizationID)
+		}
+		f.unsafeArchive(entity)
+	}
+	if err := scanner.Err(); err != nil {
+		return derrors.AsError(err, "cannot read archive")
+	}
+	return nil
+}
+
+func (f *FileProvider) SetPolicy(policy *grpc_inventory_manager_go.RetentionPolicy) derrors.Error {
+	f.Lock()
+	defer f.Unlock()
+	f.policies[policy.OrganizationId] = policy
+	return f.unsafeWritePolicies()
+}
+
+func (f *FileProvider) RemovePolicy(organizationID string) derrors.Error {
+	f.Lock()
+	defer f.Unlock()
+	err := f.unsafeRemovePolicy(organizationID)
+	if err != nil {
+		return err
+	}
+	return f.unsafeWritePolicies()
+}
+
+// unsafeWritePolicies writes the policies to a temporal file that replaces the previous one.
+func (f *FileProvider) unsafeWritePolicies() derrors.Error {
+	content, err := json.Marshal(f.unsafeListPolicies())
+	if err != nil {
+		return derrors.AsError(err, "cannot serialize retention policies")
+	}
+	tmp := f.policiesPath + ".tmp"
+	err = ioutil.WriteFile(tmp, content, 0600)
+	if err != nil {
+		return derrors.AsError(err, "cannot write retention policies")
+	}
+	err = os.Rename(tmp, f.policiesPath)
+	if err != nil {
+		return derrors.AsError(err, "cannot write retention policies")
+	}
+	return nil
+}
+
+// Archive appends the record to the file of the organization. The record is only kept in memory once it has been
+// written so an entity is never deleted without its archive record.
+func (f *FileProvider) Archive(entity *grpc_inventory_manager_go.ArchivedEntity) derrors.Error {
+	if !storage.ValidFileName(entity.OrganizationId) {
+		return derrors.NewInvalidArgumentError("invalid organization identifier").WithParams(entity.OrganizationId)
+	}
+	f.Lock()
+	defer f.Unlock()
+	content, err := json.Marshal(entity)
+	if err != nil {
+		return derrors.AsError(err, "cannot serialize archive record")
+	}
+	file, err := os.OpenFile(f.archiveName(entity.OrganizationId), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return derrors.AsError(err, "cannot open archive")
+	}
+	defer file.Close()
+	_, err = file.Write(append(content, '\n'))
+	if err != nil {
+		return derrors.AsError(err, "cannot write archive record")
+	}
+	err = file.Sync()
+	if err != nil {
+		return derrors.AsError(err, "cannot write archive record")
+	}
+	f.unsafeArchive(entity)
+	return nil
+}
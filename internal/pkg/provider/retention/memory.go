@@ -0,0 +1,109 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package retention
+
+import (
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"sort"
+	"sync"
+)
+
+type MemoryProvider struct {
+	sync.Mutex
+	// policies indexed by organization.
+	policies map[string]*grpc_inventory_manager_go.RetentionPolicy
+	// archived entities of each organization in the order they were archived.
+	archived map[string][]*grpc_inventory_manager_go.ArchivedEntity
+}
+
+func NewMemoryProvider() *MemoryProvider {
+	return &MemoryProvider{
+		policies: make(map[string]*grpc_inventory_manager_go.RetentionPolicy),
+		archived: make(map[string][]*grpc_inventory_manager_go.ArchivedEntity),
+	}
+}
+
+func (m *MemoryProvider) SetPolicy(policy *grpc_inventory_manager_go.RetentionPolicy) derrors.Error {
+	m.Lock()
+	defer m.Unlock()
+	m.policies[policy.OrganizationId] = policy
+	return nil
+}
+
+func (m *MemoryProvider) GetPolicy(organizationID string) (*grpc_inventory_manager_go.RetentionPolicy, derrors.Error) {
+	m.Lock()
+	defer m.Unlock()
+	policy, exists := m.policies[organizationID]
+	if !exists {
+		return nil, derrors.NewNotFoundError("retention policy").WithParams(organizationID)
+	}
+	return policy, nil
+}
+
+func (m *MemoryProvider) RemovePolicy(organizationID string) derrors.Error {
+	m.Lock()
+	defer m.Unlock()
+	return m.unsafeRemovePolicy(organizationID)
+}
+
+func (m *MemoryProvider) unsafeRemovePolicy(organizationID string) derrors.Error {
+	if _, exists := m.policies[organizationID]; !exists {
+		return derrors.NewNotFoundError("retention policy").WithParams(organizationID)
+	}
+	delete(m.policies, organizationID)
+	return nil
+}
+
+func (m *MemoryProvider) ListPolicies() ([]*grpc_inventory_manager_go.RetentionPolicy, derrors.Error) {
+	m.Lock()
+	defer m.Unlock()
+	return m.unsafeListPolicies(), nil
+}
+
+// unsafeListPolicies returns all the policies sorted by organization.
+func (m *MemoryProvider) unsafeListPolicies() []*grpc_inventory_manager_go.RetentionPolicy {
+	result := make([]*grpc_inventory_manager_go.RetentionPolicy, 0, len(m.policies))
+	for _, policy := range m.policies {
+		result = append(result, policy)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].OrganizationId < result[j].OrganizationId
+	})
+	return result
+}
+
+func (m *MemoryProvider) Archive(entity *grpc_inventory_manager_go.ArchivedEntity) derrors.Error {
+	m.Lock()
+	defer m.Unlock()
+	m.unsafeArchive(entity)
+	return nil
+}
+
+func (m *MemoryProvider) unsafeArchive(entity *grpc_inventory_manager_go.ArchivedEntity) {
+	m.archived[entity.OrganizationId] = append(m.archived[entity.OrganizationId], entity)
+}
+
+func (m *MemoryProvider) ListArchived(organizationID string) ([]*grpc_inventory_manager_go.ArchivedEntity, derrors.Error) {
+	m.Lock()
+	defer m.Unlock()
+	result := append([]*grpc_inventory_manager_go.ArchivedEntity{}, m.archived[organizationID]...)
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Archived < result[j].Archived
+	})
+	return result, nil
+}
@@ -0,0 +1,47 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package retention
+
+import (
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+)
+
+// Provider stores the retention policies of the organizations and the records of the archived entities.
+type Provider interface {
+	// SetPolicy adds or replaces the policy of an organization.
+	SetPolicy(policy *grpc_inventory_manager_go.RetentionPolicy) derrors.Error
+	// GetPolicy retrieves the policy of an organization.
+	GetPolicy(organizationID string) (*grpc_inventory_manager_go.RetentionPolicy, derrors.Error)
+	// RemovePolicy removes the policy of an organization.
+	RemovePolicy(organizationID string) derrors.Error
+	// ListPolicies retrieves the policies of all the organizations.
+	ListPolicies() ([]*grpc_inventory_manager_go.RetentionPolicy, derrors.Error)
+	// Archive stores the record of an archived entity.
+	Archive(entity *grpc_inventory_manager_go.ArchivedEntity) derrors.Error
+	// ListArchived retrieves the archived entities of an organization sorted by archive time.
+	ListArchived(organizationID string) ([]*grpc_inventory_manager_go.ArchivedEntity, derrors.Error)
+}
+
+// NewProvider creates a provider storing the policies and the archive in the given directory, or in memory if the
+// path is empty.
+func NewProvider(storagePath string) (Provider, derrors.Error) {
+	if storagePath == "" {
+		return NewMemoryProvider(), nil
+	}
+	return NewFileProvider(storagePath)
+}
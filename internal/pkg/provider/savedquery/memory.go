@@ -0,0 +1,100 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package savedquery
+
+import (
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"sort"
+	"sync"
+)
+
+type MemoryProvider struct {
+	sync.Mutex
+	// queries indexed by organization and query identifier.
+	queries map[string]map[string]*grpc_inventory_manager_go.SavedQuery
+}
+
+func NewMemoryProvider() *MemoryProvider {
+	return &MemoryProvider{
+		queries: make(map[string]map[string]*grpc_inventory_manager_go.SavedQuery),
+	}
+}
+
+func (m *MemoryProvider) Add(query *grpc_inventory_manager_go.SavedQuery) derrors.Error {
+	m.Lock()
+	defer m.Unlock()
+	m.unsafeAdd(query)
+	return nil
+}
+
+func (m *MemoryProvider) unsafeAdd(query *grpc_inventory_manager_go.SavedQuery) {
+	orgQueries, exists := m.queries[query.OrganizationId]
+	if !exists {
+		orgQueries = make(map[string]*grpc_inventory_manager_go.SavedQuery)
+		m.queries[query.OrganizationId] = orgQueries
+	}
+	orgQueries[query.QueryId] = query
+}
+
+func (m *MemoryProvider) Get(organizationID string, queryID string) (*grpc_inventory_manager_go.SavedQuery, derrors.Error) {
+	m.Lock()
+	defer m.Unlock()
+	query, exists := m.queries[organizationID][queryID]
+	if !exists {
+		return nil, derrors.NewNotFoundError("saved query").WithParams(organizationID, queryID)
+	}
+	return query, nil
+}
+
+func (m *MemoryProvider) List(organizationID string) ([]*grpc_inventory_manager_go.SavedQuery, derrors.Error) {
+	m.Lock()
+	defer m.Unlock()
+	result := make([]*grpc_inventory_manager_go.SavedQuery, 0, len(m.queries[organizationID]))
+	for _, query := range m.queries[organizationID] {
+		result = append(result, query)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Created < result[j].Created
+	})
+	return result, nil
+}
+
+func (m *MemoryProvider) Remove(organizationID string, queryID string) derrors.Error {
+	m.Lock()
+	defer m.Unlock()
+	return m.unsafeRemove(organizationID, queryID)
+}
+
+func (m *MemoryProvider) unsafeRemove(organizationID string, queryID string) derrors.Error {
+	if _, exists := m.queries[organizationID][queryID]; !exists {
+		return derrors.NewNotFoundError("saved query").WithParams(organizationID, queryID)
+	}
+	delete(m.queries[organizationID], queryID)
+	return nil
+}
+
+// unsafeAll returns all the stored queries.
+func (m *MemoryProvider) unsafeAll() []*grpc_inventory_manager_go.SavedQuery {
+	result := make([]*grpc_inventory_manager_go.SavedQuery, 0)
+	for _, orgQueries := range m.queries {
+		for _, query := range orgQueries {
+			result = append(result, query)
+		}
+	}
+	return result
+}
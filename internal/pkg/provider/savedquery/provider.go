@@ -0,0 +1,42 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package savedquery
+
+import (
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+)
+
+// Provider stores the inventory queries saved by the users.
+type Provider interface {
+	// Add a new saved query.
+	Add(query *grpc_inventory_manager_go.SavedQuery) derrors.Error
+	// Get a saved query.
+	Get(organizationID string, queryID string) (*grpc_inventory_manager_go.SavedQuery, derrors.Error)
+	// List the saved queries of an organization.
+	List(organizationID string) ([]*grpc_inventory_manager_go.SavedQuery, derrors.Error)
+	// Remove a saved query.
+	Remove(organizationID string, queryID string) derrors.Error
+}
+
+// NewProvider creates a provider storing the queries in the given directory, or in memory if the path is empty.
+func NewProvider(storagePath string) (Provider, derrors.Error) {
+	if storagePath == "" {
+		return NewMemoryProvider(), nil
+	}
+	return NewFileProvider(storagePath)
+}
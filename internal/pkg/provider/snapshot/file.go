@@ -0,0 +1,169 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package snapshot
+
+import (
+	"encoding/json"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/storage"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// SnapshotsDir with the name of the directory that contains the snapshots.
+const SnapshotsDir = "snapshots"
+
+// snapshotExtension with the extension of the snapshot files.
+const snapshotExtension = ".json"
+
+// FileProvider stores each snapshot in a file named after its identifier inside a directory per organization.
+// The information of the snapshots is kept in memory so the inventories are only read when requested.
+type FileProvider struct {
+	sync.Mutex
+	path string
+	// info of the stored snapshots indexed by organization and snapshot identifier.
+	info map[string]map[string]*grpc_inventory_manager_go.InventorySnapshotInfo
+}
+
+func NewFileProvider(storagePath string) (*FileProvider, derrors.Error) {
+	provider := &FileProvider{
+		path: filepath.Join(storagePath, SnapshotsDir),
+		info: make(map[string]map[string]*grpc_inventory_manager_go.InventorySnapshotInfo),
+	}
+	err := os.MkdirAll(provider.path, 0700)
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot create snapshots directory")
+	}
+	organizations, err := ioutil.ReadDir(provider.path)
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot read snapshots directory")
+	}
+	for _, organization := range organizations {
+		if !organization.IsDir() {
+			continue
+		}
+		files, err := ioutil.ReadDir(filepath.Join(provider.path, organization.Name()))
+		if err != nil {
+			return nil, derrors.AsError(err, "cannot read snapshots directory")
+		}
+		for _, file := range files {
+			if !strings.HasSuffix(file.Name(), snapshotExtension) {
+				continue
+			}
+			snapshot, rErr := provider.read(organization.Name(), strings.TrimSuffix(file.Name(), snapshotExtension))
+			if rErr != nil {
+				return nil, rErr
+			}
+			provider.addInfo(snapshot.Info)
+		}
+	}
+	return provider, nil
+}
+
+func (f *FileProvider) fileName(organizationID string, snapshotID string) string {
+	return filepath.Join(f.path, organizationID, snapshotID+snapshotExtension)
+}
+
+func (f *FileProvider) addInfo(info *grpc_inventory_manager_go.InventorySnapshotInfo) {
+	orgInfo, exists := f.info[info.OrganizationId]
+	if !exists {
+		orgInfo = make(map[string]*grpc_inventory_manager_go.InventorySnapshotInfo)
+		f.info[info.OrganizationId] = orgInfo
+	}
+	orgInfo[info.SnapshotId] = info
+}
+
+func (f *FileProvider) read(organizationID string, snapshotID string) (*grpc_inventory_manager_go.InventorySnapshot, derrors.Error) {
+	content, err := ioutil.ReadFile(f.fileName(organizationID, snapshotID))
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot read snapshot")
+	}
+	snapshot := &grpc_inventory_manager_go.InventorySnapshot{}
+	err = json.Unmarshal(content, snapshot)
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot parse snapshot")
+	}
+	if snapshot.Info == nil {
+		return nil, derrors.NewInternalError("snapshot without information").WithParams(organizationID, snapshotID)
+	}
+	return snapshot, nil
+}
+
+func (f *FileProvider) Add(snapshot *grpc_inventory_manager_go.InventorySnapshot) derrors.Error {
+	if !storage.ValidFileName(snapshot.Info.OrganizationId) || !storage.ValidFileName(snapshot.Info.SnapshotId) {
+		return derrors.NewInvalidArgumentError("invalid snapshot identifier").WithParams(snapshot.Info.OrganizationId, snapshot.Info.SnapshotId)
+	}
+	f.Lock()
+	defer f.Unlock()
+	content, err := json.Marshal(snapshot)
+	if err != nil {
+		return derrors.AsError(err, "cannot serialize snapshot")
+	}
+	err = os.MkdirAll(filepath.Join(f.path, snapshot.Info.OrganizationId), 0700)
+	if err != nil {
+		return derrors.AsError(err, "cannot create snapshots directory")
+	}
+	name := f.fileName(snapshot.Info.OrganizationId, snapshot.Info.SnapshotId)
+	err = ioutil.WriteFile(name+".tmp", content, 0600)
+	if err != nil {
+		return derrors.AsError(err, "cannot write snapshot")
+	}
+	err = os.Rename(name+".tmp", name)
+	if err != nil {
+		return derrors.AsError(err, "cannot write snapshot")
+	}
+	f.addInfo(snapshot.Info)
+	return nil
+}
+
+func (f *FileProvider) Get(organizationID string, snapshotID string) (*grpc_inventory_manager_go.InventorySnapshot, derrors.Error) {
+	f.Lock()
+	defer f.Unlock()
+	if _, exists := f.info[organizationID][snapshotID]; !exists {
+		return nil, derrors.NewNotFoundError("snapshot").WithParams(organizationID, snapshotID)
+	}
+	return f.read(organizationID, snapshotID)
+}
+
+func (f *FileProvider) List(organizationID string) ([]*grpc_inventory_manager_go.InventorySnapshotInfo, derrors.Error) {
+	f.Lock()
+	defer f.Unlock()
+	result := make([]*grpc_inventory_manager_go.InventorySnapshotInfo, 0, len(f.info[organizationID]))
+	for _, info := range f.info[organizationID] {
+		result = append(result, info)
+	}
+	sortInfo(result)
+	return result, nil
+}
+
+func (f *FileProvider) Remove(organizationID string, snapshotID string) derrors.Error {
+	f.Lock()
+	defer f.Unlock()
+	if _, exists := f.info[organizationID][snapshotID]; !exists {
+		return derrors.NewNotFoundError("snapshot").WithParams(organizationID, snapshotID)
+	}
+	err := os.Remove(f.fileName(organizationID, snapshotID))
+	if err != nil && !os.IsNotExist(err) {
+		return derrors.AsError(err, "cannot remove snapshot")
+	}
+	delete(f.info[organizationID], snapshotID)
+	return nil
+}
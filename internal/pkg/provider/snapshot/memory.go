@@ -0,0 +1,88 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package snapshot
+
+import (
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"sort"
+	"sync"
+)
+
+type MemoryProvider struct {
+	sync.Mutex
+	// snapshots indexed by organization and snapshot identifier.
+	snapshots map[string]map[string]*grpc_inventory_manager_go.InventorySnapshot
+}
+
+func NewMemoryProvider() *MemoryProvider {
+	return &MemoryProvider{
+		snapshots: make(map[string]map[string]*grpc_inventory_manager_go.InventorySnapshot),
+	}
+}
+
+func (m *MemoryProvider) Add(snapshot *grpc_inventory_manager_go.InventorySnapshot) derrors.Error {
+	m.Lock()
+	defer m.Unlock()
+	orgSnapshots, exists := m.snapshots[snapshot.Info.OrganizationId]
+	if !exists {
+		orgSnapshots = make(map[string]*grpc_inventory_manager_go.InventorySnapshot)
+		m.snapshots[snapshot.Info.OrganizationId] = orgSnapshots
+	}
+	orgSnapshots[snapshot.Info.SnapshotId] = snapshot
+	return nil
+}
+
+func (m *MemoryProvider) Get(organizationID string, snapshotID string) (*grpc_inventory_manager_go.InventorySnapshot, derrors.Error) {
+	m.Lock()
+	defer m.Unlock()
+	snapshot, exists := m.snapshots[organizationID][snapshotID]
+	if !exists {
+		return nil, derrors.NewNotFoundError("snapshot").WithParams(organizationID, snapshotID)
+	}
+	return snapshot, nil
+}
+
+func (m *MemoryProvider) List(organizationID string) ([]*grpc_inventory_manager_go.InventorySnapshotInfo, derrors.Error) {
+	m.Lock()
+	defer m.Unlock()
+	result := make([]*grpc_inventory_manager_go.InventorySnapshotInfo, 0, len(m.snapshots[organizationID]))
+	for _, snapshot := range m.snapshots[organizationID] {
+		result = append(result, snapshot.Info)
+	}
+	sortInfo(result)
+	return result, nil
+}
+
+func (m *MemoryProvider) Remove(organizationID string, snapshotID string) derrors.Error {
+	m.Lock()
+	defer m.Unlock()
+	if _, exists := m.snapshots[organizationID][snapshotID]; !exists {
+		return derrors.NewNotFoundError("snapshot").WithParams(organizationID, snapshotID)
+	}
+	delete(m.snapshots[organizationID], snapshotID)
+	return nil
+}
+
+func sortInfo(info []*grpc_inventory_manager_go.InventorySnapshotInfo) {
+	sort.Slice(info, func(i, j int) bool {
+		if info[i].Created != info[j].Created {
+			return info[i].Created < info[j].Created
+		}
+		return info[i].SnapshotId < info[j].SnapshotId
+	})
+}
@@ -0,0 +1,26 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package storage contains the helpers shared by the providers that keep their data in files.
+package storage
+
+import "strings"
+
+// ValidFileName checks that an identifier can be used as a file name inside the directory of a provider, so it
+// cannot refer to a file outside that directory.
+func ValidFileName(identifier string) bool {
+	return identifier != "" && identifier != "." && identifier != ".." && !strings.ContainsAny(identifier, "/\\")
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package trend
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/storage"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TrendsDir with the name of the directory that contains the capacity records.
+const TrendsDir = "trends"
+
+// trendExtension with the extension of the files, one JSON record per line.
+const trendExtension = ".jsonl"
+
+// FileProvider appends the records of each organization to a file and keeps them in memory to answer the queries.
+// The files are rewritten when old records are pruned.
+type FileProvider struct {
+	MemoryProvider
+	path string
+}
+
+func NewFileProvider(storagePath string) (*FileProvider, derrors.Error) {
+	provider := &FileProvider{
+		MemoryProvider: MemoryProvider{
+			points: make(map[string][]*grpc_inventory_manager_go.TrendPoint),
+		},
+		path: filepath.Join(storagePath, TrendsDir),
+	}
+	err := os.MkdirAll(provider.path, 0700)
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot create trends directory")
+	}
+	files, err := ioutil.ReadDir(provider.path)
+	if err != nil {
+		return nil, derrors.AsError(err, "cannot read trends directory")
+	}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), trendExtension) {
+			continue
+		}
+		rErr := provider.read(strings.TrimSuffix(file.Name(), trendExtension))
+		if rErr != nil {
+			return nil, rErr
+		}
+	}
+	return provider, nil
+}
+
+func (f *FileProvider) fileName(organizationID string) string {
+	return filepath.Join(f.path, organizationID+trendExtension)
+}
+
+func (f *FileProvider) read(organizationID string) derrors.Error {
+	file, err := os.Open(f.fileName(organizationID))
+	if err != nil {
+		return derrors.AsError(err, "cannot read trend")
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		point := &grpc_inventory_manager_go.TrendPoint{}
+		err = json.Unmarshal(scanner.Bytes(), point)
+		if err != nil {
+			return derrors.AsError(err, "cannot parse trend record").WithParams(organizationID)
+		}
+		f.add(organizationID, point)
+	}
+	if err := scanner.Err(); err != nil {
+		return derrors.AsError(err, "cannot read trend")
+	}
+	return nil
+}
+
+func (f *FileProvider) Add(organizationID string, point *grpc_inventory_manager_go.TrendPoint) derrors.Error {
+	if !storage.ValidFileName(organizationID) {
+		return derrors.NewInvalidArgumentError("invalid organization identifier").WithParams(organizationID)
+	}
+	f.Lock()
+	defer f.Unlock()
+	content, err := json.Marshal(point)
+	if err != nil {
+		return derrors.AsError(err, "cannot serialize trend record")
+	}
+	file, err := os.OpenFile(f.fileName(organizationID), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return derrors.AsError(err, "cannot open trend")
+	}
+	defer file.Close()
+	_, err = file.Write(append(content, '\n'))
+	if err != nil {
+		return derrors.AsError(err, "cannot write trend record")
+	}
+	f.add(organizationID, point)
+	return nil
+}
+
+func (f *FileProvider) Prune(before int64) derrors.Error {
+	f.Lock()
+	defer f.Unlock()
+	for _, organizationID := range f.prune(before) {
+		wErr := f.write(organizationID)
+		if wErr != nil {
+			return wErr
+		}
+	}
+	return nil
+}
+
+// write replaces the file of an organization with the records kept in memory. The lock must be held.
+func (f *FileProvider) write(organizationID string) derrors.Error {
+	name := f.fileName(organizationID)
+	points, exists := f.points[organizationID]
+	if !exists {
+		err := os.Remove(name)
+		if err != nil && !os.IsNotExist(err) {
+			return derrors.AsError(err, "cannot remove trend")
+		}
+		return nil
+	}
+	content := make([]byte, 0)
+	for _, point := range points {
+		line, err := json.Marshal(point)
+		if err != nil {
+			return derrors.AsError(err, "cannot serialize trend record")
+		}
+		content = append(append(content, line...), '\n')
+	}
+	err := ioutil.WriteFile(name+".tmp", content, 0600)
+	if err != nil {
+		return derrors.AsError(err, "cannot write trend")
+	}
+	err = os.Rename(name+".tmp", name)
+	if err != nil {
+		return derrors.AsError(err, "cannot write trend")
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package trend
+
+import (
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"sort"
+	"sync"
+)
+
+type MemoryProvider struct {
+	sync.Mutex
+	// points of each organization sorted by timestamp.
+	points map[string][]*grpc_inventory_manager_go.TrendPoint
+}
+
+func NewMemoryProvider() *MemoryProvider {
+	return &MemoryProvider{
+		points: make(map[string][]*grpc_inventory_manager_go.TrendPoint),
+	}
+}
+
+func (m *MemoryProvider) Add(organizationID string, point *grpc_inventory_manager_go.TrendPoint) derrors.Error {
+	m.Lock()
+	defer m.Unlock()
+	m.add(organizationID, point)
+	return nil
+}
+
+// add inserts a point keeping the order by timestamp. The lock must be held.
+func (m *MemoryProvider) add(organizationID string, point *grpc_inventory_manager_go.TrendPoint) {
+	points := m.points[organizationID]
+	index := sort.Search(len(points), func(i int) bool {
+		return points[i].Timestamp > point.Timestamp
+	})
+	points = append(points, nil)
+	copy(points[index+1:], points[index:])
+	points[index] = point
+	m.points[organizationID] = points
+}
+
+func (m *MemoryProvider) List(organizationID string, from int64, to int64) ([]*grpc_inventory_manager_go.TrendPoint, derrors.Error) {
+	m.Lock()
+	defer m.Unlock()
+	points := m.points[organizationID]
+	start := sort.Search(len(points), func(i int) bool {
+		return points[i].Timestamp >= from
+	})
+	end := sort.Search(len(points), func(i int) bool {
+		return points[i].Timestamp > to
+	})
+	if start >= end {
+		return []*grpc_inventory_manager_go.TrendPoint{}, nil
+	}
+	return append([]*grpc_inventory_manager_go.TrendPoint{}, points[start:end]...), nil
+}
+
+func (m *MemoryProvider) Prune(before int64) derrors.Error {
+	m.Lock()
+	defer m.Unlock()
+	m.prune(before)
+	return nil
+}
+
+// prune removes the old points and returns the organizations that had any. The lock must be held.
+func (m *MemoryProvider) prune(before int64) []string {
+	pruned := make([]string, 0)
+	for organizationID, points := range m.points {
+		start := sort.Search(len(points), func(i int) bool {
+			return points[i].Timestamp >= before
+		})
+		if start == 0 {
+			continue
+		}
+		pruned = append(pruned, organizationID)
+		if start == len(points) {
+			delete(m.points, organizationID)
+			continue
+		}
+		m.points[organizationID] = append([]*grpc_inventory_manager_go.TrendPoint{}, points[start:]...)
+	}
+	return pruned
+}
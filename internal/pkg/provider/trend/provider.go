@@ -0,0 +1,40 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package trend
+
+import (
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+)
+
+// Provider stores the capacity records of the organizations.
+type Provider interface {
+	// Add a record of an organization.
+	Add(organizationID string, point *grpc_inventory_manager_go.TrendPoint) derrors.Error
+	// List the records of an organization between two timestamps, both included, sorted by timestamp.
+	List(organizationID string, from int64, to int64) ([]*grpc_inventory_manager_go.TrendPoint, derrors.Error)
+	// Prune removes the records older than a timestamp.
+	Prune(before int64) derrors.Error
+}
+
+// NewProvider creates a provider storing the records in the given directory, or in memory if the path is empty.
+func NewProvider(storagePath string) (Provider, derrors.Error) {
+	if storagePath == "" {
+		return NewMemoryProvider(), nil
+	}
+	return NewFileProvider(storagePath)
+}
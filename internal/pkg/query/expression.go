@@ -0,0 +1,161 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Entity is implemented by the elements a query can be evaluated against.
+type Entity interface {
+	// Field returns the value of a field and whether the entity has that field.
+	Field(name string) (string, bool)
+}
+
+// Expression is a parsed query.
+type Expression interface {
+	// Matches checks if an entity satisfies the expression.
+	Matches(entity Entity) bool
+	// Fields returns the fields used in the expression.
+	Fields() []string
+	String() string
+}
+
+// DateLayout with the format of the dates accepted by the relational operators besides RFC 3339.
+const DateLayout = "2006-01-02"
+
+// comparison of a field with a value. Equality is case insensitive, ~ checks if the field contains the
+// value and the relational operators compare numbers and dates. Entities without the field never match.
+type comparison struct {
+	field    string
+	operator string
+	value    string
+}
+
+func (c *comparison) Matches(entity Entity) bool {
+	current, exists := entity.Field(c.field)
+	if !exists {
+		return false
+	}
+	switch c.operator {
+	case "=":
+		return strings.EqualFold(current, c.value)
+	case "!=":
+		return !strings.EqualFold(current, c.value)
+	case "~":
+		return strings.Contains(strings.ToLower(current), strings.ToLower(c.value))
+	case "!~":
+		return !strings.Contains(strings.ToLower(current), strings.ToLower(c.value))
+	}
+	left, ok := orderedValue(current)
+	if !ok {
+		return false
+	}
+	right, ok := orderedValue(c.value)
+	if !ok {
+		return false
+	}
+	switch c.operator {
+	case ">":
+		return left > right
+	case ">=":
+		return left >= right
+	case "<":
+		return left < right
+	case "<=":
+		return left <= right
+	}
+	return false
+}
+
+// orderedValue returns the number represented by a value. The dates are converted to Unix timestamps so they can
+// be compared with other dates and with the timestamps of the entities.
+func orderedValue(value string) (float64, bool) {
+	number, err := strconv.ParseFloat(value, 64)
+	if err == nil {
+		return number, true
+	}
+	date, err := time.Parse(DateLayout, value)
+	if err != nil {
+		date, err = time.Parse(time.RFC3339, value)
+	}
+	if err != nil {
+		return 0, false
+	}
+	return float64(date.Unix()), true
+}
+
+func (c *comparison) Fields() []string {
+	return []string{c.field}
+}
+
+func (c *comparison) String() string {
+	return fmt.Sprintf("%s%s%q", c.field, c.operator, c.value)
+}
+
+type andExpression struct {
+	left  Expression
+	right Expression
+}
+
+func (e *andExpression) Matches(entity Entity) bool {
+	return e.left.Matches(entity) && e.right.Matches(entity)
+}
+
+func (e *andExpression) Fields() []string {
+	return append(e.left.Fields(), e.right.Fields()...)
+}
+
+func (e *andExpression) String() string {
+	return fmt.Sprintf("(%s AND %s)", e.left, e.right)
+}
+
+type orExpression struct {
+	left  Expression
+	right Expression
+}
+
+func (e *orExpression) Matches(entity Entity) bool {
+	return e.left.Matches(entity) || e.right.Matches(entity)
+}
+
+func (e *orExpression) Fields() []string {
+	return append(e.left.Fields(), e.right.Fields()...)
+}
+
+func (e *orExpression) String() string {
+	return fmt.Sprintf("(%s OR %s)", e.left, e.right)
+}
+
+type notExpression struct {
+	inner Expression
+}
+
+func (e *notExpression) Matches(entity Entity) bool {
+	return !e.inner.Matches(entity)
+}
+
+func (e *notExpression) Fields() []string {
+	return e.inner.Fields()
+}
+
+func (e *notExpression) String() string {
+	return fmt.Sprintf("NOT %s", e.inner)
+}
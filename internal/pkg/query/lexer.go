@@ -0,0 +1,134 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"fmt"
+	"github.com/nalej/derrors"
+	"strings"
+)
+
+type tokenType int
+
+const (
+	tokenEOF tokenType = iota
+	tokenIdentifier
+	tokenString
+	tokenOperator
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenLeftParen
+	tokenRightParen
+)
+
+type token struct {
+	kind     tokenType
+	value    string
+	position int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of query"
+	}
+	return fmt.Sprintf("%q at position %d", t.value, t.position)
+}
+
+// operators sorted so the two character operators are matched first.
+var operators = []string{"!=", "!~", ">=", "<=", "=", "~", ">", "<"}
+
+func isIdentifierChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '.' || c == '_' || c == '-' || c == ':' || c == '/'
+}
+
+// tokenize splits a query in tokens.
+func tokenize(query string) ([]token, derrors.Error) {
+	result := make([]token, 0)
+	position := 0
+	for position < len(query) {
+		c := query[position]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			position++
+		case c == '(':
+			result = append(result, token{kind: tokenLeftParen, value: "(", position: position})
+			position++
+		case c == ')':
+			result = append(result, token{kind: tokenRightParen, value: ")", position: position})
+			position++
+		case c == '"':
+			value, next, err := readString(query, position)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, token{kind: tokenString, value: value, position: position})
+			position = next
+		case isIdentifierChar(c):
+			start := position
+			for position < len(query) && isIdentifierChar(query[position]) {
+				position++
+			}
+			value := query[start:position]
+			kind := tokenIdentifier
+			switch strings.ToUpper(value) {
+			case "AND":
+				kind = tokenAnd
+			case "OR":
+				kind = tokenOr
+			case "NOT":
+				kind = tokenNot
+			}
+			result = append(result, token{kind: kind, value: value, position: start})
+		default:
+			found := false
+			for _, op := range operators {
+				if strings.HasPrefix(query[position:], op) {
+					result = append(result, token{kind: tokenOperator, value: op, position: position})
+					position += len(op)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, derrors.NewInvalidArgumentError(fmt.Sprintf("unexpected character %q at position %d", c, position))
+			}
+		}
+	}
+	return append(result, token{kind: tokenEOF, position: len(query)}), nil
+}
+
+// readString reads a quoted string starting at the given position. Quotes and backslashes can be escaped with a backslash.
+func readString(query string, start int) (string, int, derrors.Error) {
+	var value strings.Builder
+	position := start + 1
+	for position < len(query) {
+		c := query[position]
+		switch {
+		case c == '\\' && position+1 < len(query):
+			value.WriteByte(query[position+1])
+			position += 2
+		case c == '"':
+			return value.String(), position + 1, nil
+		default:
+			value.WriteByte(c)
+			position++
+		}
+	}
+	return "", 0, derrors.NewInvalidArgumentError(fmt.Sprintf("unterminated string at position %d", start))
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"fmt"
+	"github.com/nalej/derrors"
+)
+
+// The grammar of the queries is:
+//
+//   expression := and (OR and)*
+//   and        := not (AND not)*
+//   not        := NOT not | primary
+//   primary    := '(' expression ')' | field operator value
+//   operator   := '=' | '!=' | '~' | '!~' | '>' | '>=' | '<' | '<='
+//
+// Fields and unquoted values may contain letters, digits and the characters . _ - : /
+// Keywords are case insensitive.
+
+type parser struct {
+	tokens  []token
+	current int
+}
+
+// Parse parses a query returning the expression to evaluate.
+func Parse(query string) (Expression, derrors.Error) {
+	tokens, err := tokenize(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, derrors.NewInvalidArgumentError("query cannot be empty")
+	}
+	expression, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, derrors.NewInvalidArgumentError(fmt.Sprintf("unexpected %s", p.peek()))
+	}
+	return expression, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.current]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.current]
+	if t.kind != tokenEOF {
+		p.current++
+	}
+	return t
+}
+
+func (p *parser) parseOr() (Expression, derrors.Error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orExpression{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expression, derrors.Error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenAnd {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &andExpression{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (Expression, derrors.Error) {
+	if p.peek().kind == tokenNot {
+		p.next()
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpression{inner: inner}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expression, derrors.Error) {
+	t := p.next()
+	switch t.kind {
+	case tokenLeftParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		closing := p.next()
+		if closing.kind != tokenRightParen {
+			return nil, derrors.NewInvalidArgumentError(fmt.Sprintf("expecting ) but found %s", closing))
+		}
+		return inner, nil
+	case tokenIdentifier:
+		op := p.next()
+		if op.kind != tokenOperator {
+			return nil, derrors.NewInvalidArgumentError(fmt.Sprintf("expecting an operator after %s but found %s", t.value, op))
+		}
+		value := p.next()
+		if value.kind != tokenIdentifier && value.kind != tokenString {
+			return nil, derrors.NewInvalidArgumentError(fmt.Sprintf("expecting a value after %s but found %s", op.value, value))
+		}
+		return &comparison{field: t.value, operator: op.value, value: value.value}, nil
+	default:
+		return nil, derrors.NewInvalidArgumentError(fmt.Sprintf("expecting a field or ( but found %s", t))
+	}
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package query
+
+import (
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+type testEntity map[string]string
+
+func (e testEntity) Field(name string) (string, bool) {
+	value, exists := e[name]
+	return value, exists
+}
+
+var _ = ginkgo.Describe("Query parser", func() {
+
+	asset := testEntity{
+		"type":                   "asset",
+		"status":                 "OFFLINE",
+		"label.env":              "prod",
+		"os.name":                "Ubuntu 18.04",
+		"hardware.ram":           "16384",
+		"created":                "1546300800",
+		"attribute.warranty_end": "2021-06-30",
+	}
+
+	ginkgo.It("should evaluate a conjunction of comparisons", func() {
+		expression, err := Parse(`type=asset AND status=OFFLINE AND label.env=prod AND os.name~"ubuntu" AND hardware.ram>8192`)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(expression.Matches(asset)).To(gomega.BeTrue())
+		gomega.Expect(expression.Fields()).To(gomega.ConsistOf("type", "status", "label.env", "os.name", "hardware.ram"))
+	})
+
+	ginkgo.It("should respect the precedence of the operators", func() {
+		expression, err := Parse(`type=device OR type=asset AND NOT label.env=dev`)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(expression.Matches(asset)).To(gomega.BeTrue())
+
+		expression, err = Parse(`(type=device OR type=asset) AND label.env!=prod`)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(expression.Matches(asset)).To(gomega.BeFalse())
+	})
+
+	ginkgo.It("should not match entities without the field", func() {
+		expression, err := Parse(`label.owner="ops team"`)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(expression.Matches(asset)).To(gomega.BeFalse())
+	})
+
+	ginkgo.It("should compare numbers", func() {
+		expression, err := Parse(`hardware.ram<=16384 and hardware.ram>=16384`)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(expression.Matches(asset)).To(gomega.BeTrue())
+
+		expression, err = Parse(`os.name>1`)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(expression.Matches(asset)).To(gomega.BeFalse())
+	})
+
+	ginkgo.It("should compare dates", func() {
+		expression, err := Parse(`attribute.warranty_end<2022-01-01 AND attribute.warranty_end>="2021-06-30T00:00:00Z"`)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(expression.Matches(asset)).To(gomega.BeTrue())
+
+		expression, err = Parse(`created>=2019-01-01 AND created<2019-01-02`)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(expression.Matches(asset)).To(gomega.BeTrue())
+	})
+
+	ginkgo.It("should reject invalid queries", func() {
+		for _, invalid := range []string{"", "type", "type=", "type=asset AND", "(type=asset", `os.name~"ubuntu`, "type=asset)", "type#asset"} {
+			_, err := Parse(invalid)
+			gomega.Expect(err).NotTo(gomega.Succeed(), invalid)
+		}
+	})
+})
@@ -132,3 +132,23 @@ func (h *Handler) UninstalledAgent(_ context.Context,  assetID *grpc_inventory_g
 	return h.manager.UninstalledAgent(assetID)
 }
 
+
+// QuarantineAsset isolates an asset allowing only forensic operations on it.
+func (h *Handler) QuarantineAsset(_ context.Context, request *grpc_inventory_manager_go.QuarantineRequest) (*grpc_inventory_manager_go.EdgeControllerOpResponse, error) {
+	vErr := entities.ValidQuarantineRequest(request)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	log.Debug().Str("organization_id", request.OrganizationId).Str("asset_id", request.AssetId).Msg("Quarantine asset")
+	return h.manager.QuarantineAsset(request)
+}
+
+// LiftQuarantine restores the normal behaviour of a quarantined asset.
+func (h *Handler) LiftQuarantine(_ context.Context, request *grpc_inventory_manager_go.QuarantineRequest) (*grpc_inventory_manager_go.EdgeControllerOpResponse, error) {
+	vErr := entities.ValidQuarantineRequest(request)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	log.Debug().Str("organization_id", request.OrganizationId).Str("asset_id", request.AssetId).Msg("Lift asset quarantine")
+	return h.manager.LiftQuarantine(request)
+}
@@ -24,7 +24,10 @@ import (
 	"github.com/nalej/grpc-edge-inventory-proxy-go"
 	"github.com/nalej/grpc-inventory-go"
 	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-organization-go"
 	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/inventory-manager/internal/pkg/config"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
 	"github.com/nalej/inventory-manager/internal/pkg/server/contexts"
 	"github.com/rs/zerolog/log"
 	"github.com/satori/go.uuid"
@@ -33,20 +36,32 @@ import (
 
 const ProxyTimeout = time.Second * 60
 
+// AliveListener is notified of the agents alive messages received by the manager.
+type AliveListener interface {
+	OnAgentsAlive(agents *grpc_inventory_manager_go.AgentsAlive)
+}
+
 type Manager struct {
 	proxyClient grpc_edge_inventory_proxy_go.EdgeControllerProxyClient
 	assetClient grpc_inventory_go.AssetsClient
 	controllersClient 	grpc_inventory_go.ControllersClient
+	orgClient   grpc_organization_go.OrganizationsClient
 	CACert      string
+	aliveListener AliveListener
+	cfg         config.Config
 }
 
 func NewManager(proxyClient grpc_edge_inventory_proxy_go.EdgeControllerProxyClient, assetClient grpc_inventory_go.AssetsClient,
-	controllersClient grpc_inventory_go.ControllersClient,	caCert string) Manager {
+	controllersClient grpc_inventory_go.ControllersClient, orgClient grpc_organization_go.OrganizationsClient,
+	aliveListener AliveListener, cfg config.Config) Manager {
 	return Manager{
 		proxyClient: proxyClient,
 		assetClient: assetClient,
 		controllersClient: controllersClient,
-		CACert:      caCert,
+		orgClient:   orgClient,
+		CACert:      cfg.CACertRaw,
+		aliveListener: aliveListener,
+		cfg:         cfg,
 	}
 }
 
@@ -120,6 +135,10 @@ func (m *Manager) AgentJoin(request *grpc_inventory_manager_go.AgentJoinRequest)
 // TODO: add a message in system-model to add many alive messages at once
 func (m *Manager) LogAgentAlive(agents *grpc_inventory_manager_go.AgentsAlive) error {
 
+	if m.aliveListener != nil {
+		m.aliveListener.OnAgentsAlive(agents)
+	}
+
 	for agent, timestamp := range agents.Agents {
 		// send to system model a message to update the timestamp
 		ctx, cancel := contexts.SMContext()
@@ -175,6 +194,18 @@ func (m *Manager) TriggerAgentOperation(request *grpc_inventory_manager_go.Agent
 		return nil, conversions.ToDerror(derrors.NewInvalidArgumentError("this asset is not managed by the EC").WithParams("edge_controller_id", request.EdgeControllerId).WithParams("asset_id", request.AssetId))
 	}
 
+	if entities.IsUnmanaged(asset.Labels) {
+		return nil, conversions.ToGRPCError(derrors.NewFailedPreconditionError("the asset is unmanaged and has no agent").
+			WithParams(request.AssetId))
+	}
+
+	if entities.IsQuarantined(asset.Labels) && !m.isForensicPlugin(request.Plugin) {
+		log.Warn().Str("organization_id", request.OrganizationId).Str("asset_id", request.AssetId).Str("plugin", request.Plugin).
+			Msg("operation rejected, the asset is in quarantine")
+		return nil, conversions.ToGRPCError(derrors.NewPermissionDeniedError("the asset is in quarantine, only forensic plugins are allowed").
+			WithParams(request.AssetId, request.Plugin))
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), ProxyTimeout)
 	defer cancel()
 
@@ -258,6 +289,17 @@ func (m *Manager) UninstallAgent( request *grpc_inventory_manager_go.UninstallAg
 		return nil, err
 	}
 
+	// there is no agent to uninstall in the unmanaged assets
+	if entities.IsUnmanaged(asset.Labels) {
+		return m.removeUnmanagedAsset(asset)
+	}
+
+	// a second request is only sent to the proxy if the user forces it
+	if entities.GetAssetState(asset.Labels) != grpc_inventory_manager_go.AssetState_ACTIVE && !request.Force {
+		return nil, conversions.ToGRPCError(derrors.NewFailedPreconditionError("uninstall already requested, use force to send it again").
+			WithParams(request.OrganizationId, request.AssetId))
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), ProxyTimeout)
 	defer cancel()
 
@@ -278,6 +320,13 @@ func (m *Manager) UninstallAgent( request *grpc_inventory_manager_go.UninstallAg
 			Str("error", conversions.ToDerror(err).DebugReport()).Msg("error updating uninstall agent response")
 	}
 
+	// mark the asset as uninstalling until the confirmation is received
+	err = m.markUninstalling(asset, res.OperationId)
+	if err != nil {
+		log.Warn().Str("asset_id", asset.AssetId).Str("operation_id", res.OperationId).
+			Str("error", conversions.ToDerror(err).DebugReport()).Msg("error marking the asset as uninstalling")
+	}
+
 	return res, nil
 
 }
@@ -293,6 +342,12 @@ func (m *Manager) UninstalledAgent( assetID *grpc_inventory_go.AssetUninstalledI
 		AssetId: assetID.AssetId,
 	} )
 	if err != nil {
+		// the confirmation may be received more than once
+		if conversions.ToDerror(err).Type() == derrors.NotFound {
+			log.Debug().Str("asset", assetID.AssetId).Str("operation_id", assetID.OperationId).
+				Msg("asset already removed, ignoring duplicated confirmation")
+			return &grpc_common_go.Success{}, nil
+		}
 		return nil, err
 	}
 
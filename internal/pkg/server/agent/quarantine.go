@@ -0,0 +1,138 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agent
+
+import (
+	"context"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"github.com/nalej/inventory-manager/internal/pkg/server/contexts"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+// isForensicPlugin checks if a plugin can be triggered on a quarantined asset.
+func (m *Manager) isForensicPlugin(plugin string) bool {
+	for _, allowed := range m.cfg.ForensicPlugins {
+		if allowed == plugin {
+			return true
+		}
+	}
+	return false
+}
+
+// QuarantineAsset marks an asset as quarantined and asks its controller to isolate the agent traffic.
+func (m *Manager) QuarantineAsset(request *grpc_inventory_manager_go.QuarantineRequest) (*grpc_inventory_manager_go.EdgeControllerOpResponse, error) {
+	asset, err := m.getAsset(request.OrganizationId, request.AssetId)
+	if err != nil {
+		return nil, err
+	}
+
+	// the asset is marked first so no more operations are accepted while the controller isolates it.
+	ctx, cancel := contexts.SMContext()
+	defer cancel()
+	_, err = m.assetClient.Update(ctx, &grpc_inventory_go.UpdateAssetRequest{
+		OrganizationId: asset.OrganizationId,
+		AssetId:        asset.AssetId,
+		AddLabels:      true,
+		Labels:         entities.NewQuarantineLabels(request.Reason, time.Now()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := m.isolateAgent(asset, true)
+	if err != nil {
+		log.Warn().Str("organization_id", asset.OrganizationId).Str("asset_id", asset.AssetId).
+			Str("trace", conversions.ToDerror(err).DebugReport()).Msg("asset quarantined but the agent could not be isolated")
+		return nil, err
+	}
+
+	log.Info().Str("audit", "quarantine").Str("organization_id", asset.OrganizationId).Str("edge_controller_id", asset.EdgeControllerId).
+		Str("asset_id", asset.AssetId).Str("reason", request.Reason).Str("operation_id", response.OperationId).Msg("asset quarantined")
+	return response, nil
+}
+
+// LiftQuarantine restores the normal behaviour of a quarantined asset.
+func (m *Manager) LiftQuarantine(request *grpc_inventory_manager_go.QuarantineRequest) (*grpc_inventory_manager_go.EdgeControllerOpResponse, error) {
+	asset, err := m.getAsset(request.OrganizationId, request.AssetId)
+	if err != nil {
+		return nil, err
+	}
+	if !entities.IsQuarantined(asset.Labels) {
+		return nil, conversions.ToGRPCError(derrors.NewFailedPreconditionError("the asset is not in quarantine").WithParams(request.AssetId))
+	}
+
+	// the traffic is restored first so the asset is not released while still isolated.
+	response, err := m.isolateAgent(asset, false)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := contexts.SMContext()
+	defer cancel()
+	_, err = m.assetClient.Update(ctx, &grpc_inventory_go.UpdateAssetRequest{
+		OrganizationId: asset.OrganizationId,
+		AssetId:        asset.AssetId,
+		RemoveLabels:   true,
+		Labels: map[string]string{
+			entities.QuarantineLabel:       asset.Labels[entities.QuarantineLabel],
+			entities.QuarantineReasonLabel: asset.Labels[entities.QuarantineReasonLabel],
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info().Str("audit", "liftQuarantine").Str("organization_id", asset.OrganizationId).Str("edge_controller_id", asset.EdgeControllerId).
+		Str("asset_id", asset.AssetId).Str("reason", request.Reason).Str("operation_id", response.OperationId).Msg("asset quarantine lifted")
+	return response, nil
+}
+
+func (m *Manager) getAsset(organizationID string, assetID string) (*grpc_inventory_go.Asset, error) {
+	ctx, cancel := contexts.SMContext()
+	defer cancel()
+	return m.assetClient.Get(ctx, &grpc_inventory_go.AssetId{
+		OrganizationId: organizationID,
+		AssetId:        assetID,
+	})
+}
+
+// isolateAgent sends the isolation request to the controller of the asset through the proxy.
+func (m *Manager) isolateAgent(asset *grpc_inventory_go.Asset, isolate bool) (*grpc_inventory_manager_go.EdgeControllerOpResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ProxyTimeout)
+	defer cancel()
+	response, err := m.proxyClient.IsolateAgent(ctx, &grpc_inventory_manager_go.AgentIsolationRequest{
+		OrganizationId:   asset.OrganizationId,
+		EdgeControllerId: asset.EdgeControllerId,
+		AssetId:          asset.AssetId,
+		Isolate:          isolate,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.updateLastECOpResponse(response)
+	if err != nil {
+		log.Warn().Str("operation_id", response.OperationId).Str("status", response.Status.String()).Str("info", response.Info).
+			Str("error", conversions.ToDerror(err).DebugReport()).Msg("error updating isolate agent response")
+	}
+	return response, nil
+}
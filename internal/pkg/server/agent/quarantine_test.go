@@ -0,0 +1,159 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package agent
+
+import (
+	"context"
+	"github.com/nalej/grpc-edge-inventory-proxy-go"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/inventory-manager/internal/pkg/config"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeAsset keeps a single asset applying the label updates it receives.
+type fakeAsset struct {
+	grpc_inventory_go.AssetsClient
+	asset *grpc_inventory_go.Asset
+}
+
+func (f *fakeAsset) Get(ctx context.Context, in *grpc_inventory_go.AssetId, opts ...grpc.CallOption) (*grpc_inventory_go.Asset, error) {
+	labels := make(map[string]string, len(f.asset.Labels))
+	for key, value := range f.asset.Labels {
+		labels[key] = value
+	}
+	return &grpc_inventory_go.Asset{
+		OrganizationId:   f.asset.OrganizationId,
+		EdgeControllerId: f.asset.EdgeControllerId,
+		AssetId:          f.asset.AssetId,
+		Labels:           labels,
+	}, nil
+}
+
+func (f *fakeAsset) Update(ctx context.Context, in *grpc_inventory_go.UpdateAssetRequest, opts ...grpc.CallOption) (*grpc_inventory_go.Asset, error) {
+	for key, value := range in.Labels {
+		if in.AddLabels {
+			f.asset.Labels[key] = value
+		}
+		if in.RemoveLabels {
+			delete(f.asset.Labels, key)
+		}
+	}
+	return f.asset, nil
+}
+
+type fakeControllers struct {
+	grpc_inventory_go.ControllersClient
+}
+
+func (f *fakeControllers) Update(ctx context.Context, in *grpc_inventory_go.UpdateEdgeControllerRequest, opts ...grpc.CallOption) (*grpc_inventory_go.EdgeController, error) {
+	return &grpc_inventory_go.EdgeController{OrganizationId: in.OrganizationId, EdgeControllerId: in.EdgeControllerId}, nil
+}
+
+// fakeProxy records the operations and isolation requests sent to the controller.
+type fakeProxy struct {
+	grpc_edge_inventory_proxy_go.EdgeControllerProxyClient
+	triggered []string
+	isolated  []bool
+}
+
+func (f *fakeProxy) TriggerAgentOperation(ctx context.Context, in *grpc_inventory_manager_go.AgentOpRequest, opts ...grpc.CallOption) (*grpc_inventory_manager_go.AgentOpResponse, error) {
+	f.triggered = append(f.triggered, in.Plugin)
+	return &grpc_inventory_manager_go.AgentOpResponse{OrganizationId: in.OrganizationId, AssetId: in.AssetId, OperationId: in.OperationId}, nil
+}
+
+func (f *fakeProxy) IsolateAgent(ctx context.Context, in *grpc_inventory_manager_go.AgentIsolationRequest, opts ...grpc.CallOption) (*grpc_inventory_manager_go.EdgeControllerOpResponse, error) {
+	f.isolated = append(f.isolated, in.Isolate)
+	return &grpc_inventory_manager_go.EdgeControllerOpResponse{OrganizationId: in.OrganizationId, EdgeControllerId: in.EdgeControllerId, OperationId: "isolation"}, nil
+}
+
+var _ = ginkgo.Describe("Quarantine", func() {
+
+	var assets *fakeAsset
+	var proxy *fakeProxy
+	var manager Manager
+
+	trigger := func(plugin string) error {
+		_, err := manager.TriggerAgentOperation(&grpc_inventory_manager_go.AgentOpRequest{
+			OrganizationId:   "org",
+			EdgeControllerId: "ec",
+			AssetId:          "asset",
+			OperationId:      "op",
+			Plugin:           plugin,
+		})
+		return err
+	}
+	quarantine := &grpc_inventory_manager_go.QuarantineRequest{OrganizationId: "org", AssetId: "asset", Reason: "suspicious traffic"}
+
+	ginkgo.BeforeEach(func() {
+		assets = &fakeAsset{asset: &grpc_inventory_go.Asset{
+			OrganizationId:   "org",
+			EdgeControllerId: "ec",
+			AssetId:          "asset",
+			Labels:           map[string]string{"env": "prod"},
+		}}
+		proxy = &fakeProxy{}
+		manager = NewManager(proxy, assets, &fakeControllers{}, nil, nil, config.Config{
+			ForensicPlugins: []string{"forensics"},
+		})
+	})
+
+	ginkgo.It("should reject the non forensic operations on a quarantined asset", func() {
+		_, err := manager.QuarantineAsset(quarantine)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(entities.IsQuarantined(assets.asset.Labels)).To(gomega.BeTrue())
+		gomega.Expect(proxy.isolated).To(gomega.Equal([]bool{true}))
+
+		err = trigger("ping")
+		gomega.Expect(err).NotTo(gomega.Succeed())
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.PermissionDenied))
+		gomega.Expect(proxy.triggered).To(gomega.BeEmpty())
+	})
+
+	ginkgo.It("should allow the forensic operations on a quarantined asset", func() {
+		_, err := manager.QuarantineAsset(quarantine)
+		gomega.Expect(err).To(gomega.Succeed())
+
+		gomega.Expect(trigger("forensics")).To(gomega.Succeed())
+		gomega.Expect(proxy.triggered).To(gomega.Equal([]string{"forensics"}))
+	})
+
+	ginkgo.It("should allow the operations again once the quarantine is lifted", func() {
+		_, err := manager.QuarantineAsset(quarantine)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(trigger("ping")).NotTo(gomega.Succeed())
+
+		_, err = manager.LiftQuarantine(quarantine)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(entities.IsQuarantined(assets.asset.Labels)).To(gomega.BeFalse())
+		gomega.Expect(assets.asset.Labels).To(gomega.Equal(map[string]string{"env": "prod"}))
+		gomega.Expect(proxy.isolated).To(gomega.Equal([]bool{true, false}))
+
+		gomega.Expect(trigger("ping")).To(gomega.Succeed())
+		gomega.Expect(proxy.triggered).To(gomega.Equal([]string{"ping"}))
+	})
+
+	ginkgo.It("should not lift the quarantine of an asset that is not quarantined", func() {
+		_, err := manager.LiftQuarantine(quarantine)
+		gomega.Expect(status.Code(err)).To(gomega.Equal(codes.FailedPrecondition))
+		gomega.Expect(proxy.isolated).To(gomega.BeEmpty())
+	})
+})
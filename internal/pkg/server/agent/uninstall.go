@@ -0,0 +1,184 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"github.com/nalej/grpc-common-go"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/inventory-manager/internal/pkg/config"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"github.com/nalej/inventory-manager/internal/pkg/jobs"
+	"github.com/nalej/inventory-manager/internal/pkg/server/contexts"
+	"github.com/rs/zerolog/log"
+	"github.com/satori/go.uuid"
+	"strconv"
+	"time"
+)
+
+// Run launches the periodic check of the pending uninstalls.
+func (m *Manager) Run(group *jobs.Group) {
+	log.Debug().Str("timeout", m.cfg.UninstallTimeout.String()).Str("period", m.cfg.UninstallCheckPeriod.String()).
+		Msg("checking pending uninstalls")
+	group.Every(m.cfg.UninstallCheckPeriod, m.checkPendingUninstalls)
+}
+
+// checkPendingUninstalls checks the assets with a pending uninstall until the jobs are stopped.
+func (m *Manager) checkPendingUninstalls(stopped context.Context) {
+	ctx, cancel := contexts.SMContext()
+	organizations, err := m.orgClient.ListOrganizations(ctx, &grpc_common_go.Empty{})
+	cancel()
+	if err != nil {
+		log.Error().Str("trace", conversions.ToDerror(err).DebugReport()).Msg("cannot list organizations to check pending uninstalls")
+		return
+	}
+	for _, org := range organizations.Organizations {
+		if stopped.Err() != nil {
+			return
+		}
+		m.checkOrganizationUninstalls(org.OrganizationId)
+	}
+}
+
+func (m *Manager) checkOrganizationUninstalls(organizationID string) {
+	ctx, cancel := contexts.SMContext()
+	defer cancel()
+	assets, err := m.assetClient.List(ctx, &grpc_organization_go.OrganizationId{
+		OrganizationId: organizationID,
+	})
+	if err != nil {
+		log.Warn().Str("organization_id", organizationID).Str("trace", conversions.ToDerror(err).DebugReport()).
+			Msg("cannot list assets to check pending uninstalls")
+		return
+	}
+	for _, asset := range assets.Assets {
+		if entities.GetAssetState(asset.Labels) != grpc_inventory_manager_go.AssetState_UNINSTALLING {
+			continue
+		}
+		requested, valid := entities.GetUninstallRequested(asset.Labels)
+		if !valid {
+			// without the time of the request the uninstall cannot be considered expired
+			log.Warn().Str("organization_id", asset.OrganizationId).Str("asset_id", asset.AssetId).
+				Str("requested", asset.Labels[entities.UninstallRequestedLabel]).
+				Msg("pending uninstall without a valid request time, skipping it")
+			continue
+		}
+		if time.Since(requested) < m.cfg.UninstallTimeout {
+			continue
+		}
+		log.Warn().Str("organization_id", asset.OrganizationId).Str("asset_id", asset.AssetId).
+			Str("requested", requested.String()).Str("policy", m.cfg.UninstallTimeoutPolicy).
+			Msg("agent uninstall not confirmed on time")
+		if m.cfg.UninstallTimeoutPolicy == config.UninstallTimeoutRemove {
+			err = m.removeExpiredUninstall(asset)
+		} else {
+			err = m.flagExpiredUninstall(asset)
+		}
+		if err != nil {
+			log.Warn().Str("organization_id", asset.OrganizationId).Str("asset_id", asset.AssetId).
+				Str("trace", conversions.ToDerror(err).DebugReport()).Msg("cannot process expired uninstall")
+		}
+	}
+}
+
+// markUninstalling adds the labels of a pending uninstall to an asset.
+func (m *Manager) markUninstalling(asset *grpc_inventory_go.Asset, operationID string) error {
+	ctx, cancel := contexts.SMContext()
+	defer cancel()
+	// a new request restarts the timeout of an expired uninstall
+	if _, expired := asset.Labels[entities.UninstallExpiredLabel]; expired {
+		_, err := m.assetClient.Update(ctx, &grpc_inventory_go.UpdateAssetRequest{
+			OrganizationId: asset.OrganizationId,
+			AssetId:        asset.AssetId,
+			RemoveLabels:   true,
+			Labels: map[string]string{
+				entities.UninstallExpiredLabel: asset.Labels[entities.UninstallExpiredLabel],
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	_, err := m.assetClient.Update(ctx, &grpc_inventory_go.UpdateAssetRequest{
+		OrganizationId: asset.OrganizationId,
+		AssetId:        asset.AssetId,
+		AddLabels:      true,
+		Labels:         entities.NewUninstallLabels(operationID, time.Now()),
+	})
+	return err
+}
+
+// removeExpiredUninstall removes an asset whose uninstall was not confirmed on time.
+func (m *Manager) removeExpiredUninstall(asset *grpc_inventory_go.Asset) error {
+	ctx, cancel := contexts.SMContext()
+	defer cancel()
+	_, err := m.assetClient.Remove(ctx, &grpc_inventory_go.AssetId{
+		OrganizationId: asset.OrganizationId,
+		AssetId:        asset.AssetId,
+	})
+	if err != nil {
+		return err
+	}
+	return m.updateLastECOpResponse(&grpc_inventory_manager_go.EdgeControllerOpResponse{
+		OrganizationId:   asset.OrganizationId,
+		EdgeControllerId: asset.EdgeControllerId,
+		OperationId:      asset.Labels[entities.UninstallOperationLabel],
+		Timestamp:        time.Now().Unix(),
+		Status:           grpc_inventory_go.OpStatus_FAIL,
+		Info:             fmt.Sprintf("agent %s uninstall not confirmed, asset removed", asset.AssetId),
+	})
+}
+
+// flagExpiredUninstall marks an asset whose uninstall was not confirmed on time so it can be reviewed.
+func (m *Manager) flagExpiredUninstall(asset *grpc_inventory_go.Asset) error {
+	ctx, cancel := contexts.SMContext()
+	defer cancel()
+	_, err := m.assetClient.Update(ctx, &grpc_inventory_go.UpdateAssetRequest{
+		OrganizationId: asset.OrganizationId,
+		AssetId:        asset.AssetId,
+		AddLabels:      true,
+		Labels: map[string]string{
+			entities.UninstallExpiredLabel: strconv.FormatInt(time.Now().Unix(), 10),
+		},
+	})
+	return err
+}
+
+// removeUnmanagedAsset removes an imported asset that has no agent.
+func (m *Manager) removeUnmanagedAsset(asset *grpc_inventory_go.Asset) (*grpc_inventory_manager_go.EdgeControllerOpResponse, error) {
+	ctx, cancel := contexts.SMContext()
+	defer cancel()
+	_, err := m.assetClient.Remove(ctx, &grpc_inventory_go.AssetId{
+		OrganizationId: asset.OrganizationId,
+		AssetId:        asset.AssetId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &grpc_inventory_manager_go.EdgeControllerOpResponse{
+		OrganizationId:   asset.OrganizationId,
+		EdgeControllerId: asset.EdgeControllerId,
+		OperationId:      uuid.NewV4().String(),
+		Timestamp:        time.Now().Unix(),
+		Status:           grpc_inventory_go.OpStatus_SUCCESS,
+		Info:             fmt.Sprintf("unmanaged asset %s removed", asset.AssetId),
+	}, nil
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package agent
+
+import (
+	"context"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/inventory-manager/internal/pkg/config"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"time"
+)
+
+type fakeAssets struct {
+	grpc_inventory_go.AssetsClient
+	assets []*grpc_inventory_go.Asset
+	// flagged with the assets whose labels were updated.
+	flagged []string
+}
+
+func (f *fakeAssets) List(ctx context.Context, in *grpc_organization_go.OrganizationId, opts ...grpc.CallOption) (*grpc_inventory_go.AssetList, error) {
+	return &grpc_inventory_go.AssetList{Assets: f.assets}, nil
+}
+
+func (f *fakeAssets) Update(ctx context.Context, in *grpc_inventory_go.UpdateAssetRequest, opts ...grpc.CallOption) (*grpc_inventory_go.Asset, error) {
+	f.flagged = append(f.flagged, in.AssetId)
+	return &grpc_inventory_go.Asset{OrganizationId: in.OrganizationId, AssetId: in.AssetId}, nil
+}
+
+var _ = ginkgo.Describe("Pending uninstalls", func() {
+
+	ginkgo.It("should only act on the uninstalls requested before the timeout", func() {
+		malformed := entities.NewUninstallLabels("op3", time.Now())
+		malformed[entities.UninstallRequestedLabel] = "yesterday"
+		assets := &fakeAssets{assets: []*grpc_inventory_go.Asset{
+			{OrganizationId: "org", AssetId: "expired", Labels: entities.NewUninstallLabels("op1", time.Now().Add(-2*time.Hour))},
+			{OrganizationId: "org", AssetId: "recent", Labels: entities.NewUninstallLabels("op2", time.Now())},
+			{OrganizationId: "org", AssetId: "malformed", Labels: malformed},
+			{OrganizationId: "org", AssetId: "active", Labels: map[string]string{"env": "prod"}},
+		}}
+		manager := NewManager(nil, assets, nil, nil, nil, config.Config{
+			UninstallTimeout:       time.Hour,
+			UninstallTimeoutPolicy: config.UninstallTimeoutFlag,
+		})
+		manager.checkOrganizationUninstalls("org")
+		gomega.Expect(assets.flagged).To(gomega.Equal([]string{"expired"}))
+	})
+})
@@ -0,0 +1,52 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audits
+
+import (
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"golang.org/x/net/context"
+)
+
+type Handler struct {
+	manager Manager
+}
+
+func NewHandler(manager Manager) *Handler {
+	return &Handler{
+		manager: manager,
+	}
+}
+
+// ListAuditEntries retrieves the most recent audit entries of an organization.
+func (h *Handler) ListAuditEntries(_ context.Context, query *grpc_inventory_manager_go.AuditQuery) (*grpc_inventory_manager_go.AuditEntryList, error) {
+	vErr := entities.ValidAuditQuery(query)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.ListAuditEntries(query)
+}
+
+// ExportAuditEntries writes the audit log of an organization as JSON Lines.
+func (h *Handler) ExportAuditEntries(query *grpc_inventory_manager_go.AuditQuery, stream grpc_inventory_manager_go.Audit_ExportAuditEntriesServer) error {
+	vErr := entities.ValidAuditQuery(query)
+	if vErr != nil {
+		return conversions.ToGRPCError(vErr)
+	}
+	return h.manager.ExportAuditEntries(query, stream)
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audits
+
+import (
+	"github.com/nalej/grpc-inventory-manager-go"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"strings"
+	"time"
+)
+
+// CallerMetadataKey with the metadata key that contains the identity of the caller.
+const CallerMetadataKey = "user_id"
+
+// AuditedMethods contains the names of the RPCs that modify the inventory.
+var AuditedMethods = map[string]bool{
+	"UpdateAsset":           true,
+	"UpdateDevice":          true,
+	"UpdateDeviceInfo":      true,
+	"BulkUpdateLabels":      true,
+	"UpdateAttributes":      true,
+	"SetAttributeSchema":    true,
+	"ImportAssets":          true,
+	"UpdateEC":              true,
+	"UpdateECGeolocation":   true,
+	"UnlinkEIC":             true,
+	"EICJoin":               true,
+	"InstallAgent":          true,
+	"UninstallAgent":        true,
+	"AgentJoin":             true,
+	"TriggerAgentOperation": true,
+	"QuarantineAsset":       true,
+	"LiftQuarantine":        true,
+	"SetRetentionPolicy":    true,
+	"RemoveRetentionPolicy": true,
+	"ReplayDeadLetter":      true,
+	"RemoveDeadLetter":      true,
+}
+
+// organizationRequest is implemented by the requests that belong to an organization.
+type organizationRequest interface {
+	GetOrganizationId() string
+}
+
+// methodName returns the name of an RPC from its full name, e.g. UnlinkEIC from /inventory_manager.EIC/UnlinkEIC.
+func methodName(fullMethod string) string {
+	return fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+}
+
+// UnaryInterceptor records an audit entry for every call to an audited method once it has been served.
+func (m *Manager) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, request interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !AuditedMethods[methodName(info.FullMethod)] {
+			return handler(ctx, request)
+		}
+		start := time.Now()
+		response, err := handler(ctx, request)
+		m.Record(newEntry(ctx, info.FullMethod, request, err, start, time.Now()))
+		return response, err
+	}
+}
+
+// newEntry builds the audit entry of a call.
+func newEntry(ctx context.Context, method string, request interface{}, err error, start time.Time, end time.Time) *grpc_inventory_manager_go.AuditEntry {
+	entry := &grpc_inventory_manager_go.AuditEntry{
+		Timestamp:  start.Unix(),
+		Method:     method,
+		Request:    redactRequest(request),
+		Success:    err == nil,
+		DurationMs: int64(end.Sub(start) / time.Millisecond),
+	}
+	if orgRequest, ok := request.(organizationRequest); ok {
+		entry.OrganizationId = orgRequest.GetOrganizationId()
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(CallerMetadataKey); len(values) > 0 {
+			entry.CallerId = values[0]
+		}
+	}
+	if callerPeer, ok := peer.FromContext(ctx); ok && callerPeer.Addr != nil {
+		entry.CallerAddress = callerPeer.Addr.String()
+	}
+	setError(entry, err)
+	return entry
+}
+
+// setError records the status code and message of a failed call.
+func setError(entry *grpc_inventory_manager_go.AuditEntry, err error) {
+	if err == nil {
+		return
+	}
+	callStatus := status.Convert(err)
+	entry.ErrorCode = callStatus.Code().String()
+	entry.ErrorMessage = callStatus.Message()
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audits
+
+import (
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/inventory-manager/internal/pkg/export"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/audit"
+	"github.com/rs/zerolog/log"
+	"github.com/satori/go.uuid"
+	"math"
+	"strings"
+	"time"
+)
+
+// DefaultAuditLimit with the maximum number of entries listed if no limit is requested.
+const DefaultAuditLimit = 1000
+
+// SystemCaller identifies the changes made by the inventory manager itself, e.g. by the retention policies.
+const SystemCaller = "inventory-manager"
+
+type Manager struct {
+	provider audit.Provider
+}
+
+func NewManager(provider audit.Provider) Manager {
+	return Manager{
+		provider: provider,
+	}
+}
+
+// Record stores an audit entry. An entry that cannot be stored is written to the log so it is not lost.
+func (m *Manager) Record(entry *grpc_inventory_manager_go.AuditEntry) {
+	entry.EntryId = uuid.NewV4().String()
+	err := m.provider.Add(entry)
+	if err != nil {
+		log.Error().Str("audit", entry.Method).Str("organization_id", entry.OrganizationId).Str("caller_id", entry.CallerId).
+			Str("caller_address", entry.CallerAddress).Str("request", entry.Request).Bool("success", entry.Success).
+			Str("error_code", entry.ErrorCode).Str("trace", err.DebugReport()).Msg("cannot store the audit entry")
+		return
+	}
+	log.Debug().Str("audit", entry.Method).Str("organization_id", entry.OrganizationId).Str("caller_id", entry.CallerId).
+		Bool("success", entry.Success).Msg("audit entry recorded")
+}
+
+// RecordSystem stores the audit entry of a change made by the inventory manager without a call.
+func (m *Manager) RecordSystem(organizationID string, method string, request interface{}, err error) {
+	entry := &grpc_inventory_manager_go.AuditEntry{
+		OrganizationId: organizationID,
+		Timestamp:      time.Now().Unix(),
+		CallerId:       SystemCaller,
+		Method:         method,
+		Request:        redactRequest(request),
+		Success:        err == nil,
+	}
+	setError(entry, err)
+	m.Record(entry)
+}
+
+// selectEntries returns the entries of an organization matching a query in the order they were recorded.
+func (m *Manager) selectEntries(query *grpc_inventory_manager_go.AuditQuery) ([]*grpc_inventory_manager_go.AuditEntry, error) {
+	to := query.To
+	if to == 0 {
+		to = math.MaxInt64
+	}
+	entries, err := m.provider.List(query.OrganizationId, query.From, to)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	result := make([]*grpc_inventory_manager_go.AuditEntry, 0, len(entries))
+	for _, entry := range entries {
+		if matchesQuery(entry, query) {
+			result = append(result, entry)
+		}
+	}
+	return result, nil
+}
+
+// matchesQuery checks the method and caller of an entry. The method may be requested by its full or short name.
+func matchesQuery(entry *grpc_inventory_manager_go.AuditEntry, query *grpc_inventory_manager_go.AuditQuery) bool {
+	if query.Method != "" && entry.Method != query.Method && !strings.HasSuffix(entry.Method, "/"+query.Method) {
+		return false
+	}
+	if query.CallerId != "" && entry.CallerId != query.CallerId {
+		return false
+	}
+	return true
+}
+
+// ListAuditEntries retrieves the most recent entries of an organization matching a query, newest first.
+func (m *Manager) ListAuditEntries(query *grpc_inventory_manager_go.AuditQuery) (*grpc_inventory_manager_go.AuditEntryList, error) {
+	entries, err := m.selectEntries(query)
+	if err != nil {
+		return nil, err
+	}
+	limit := int(query.Limit)
+	if limit == 0 {
+		limit = DefaultAuditLimit
+	}
+	if len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	result := make([]*grpc_inventory_manager_go.AuditEntry, 0, len(entries))
+	for index := len(entries) - 1; index >= 0; index-- {
+		result = append(result, entries[index])
+	}
+	return &grpc_inventory_manager_go.AuditEntryList{
+		OrganizationId: query.OrganizationId,
+		Entries:        result,
+	}, nil
+}
+
+// ExportAuditEntries writes all the entries of an organization matching a query as JSON Lines in the order they
+// were recorded. The limit of the query is ignored.
+func (m *Manager) ExportAuditEntries(query *grpc_inventory_manager_go.AuditQuery, stream grpc_inventory_manager_go.Audit_ExportAuditEntriesServer) error {
+	entries, err := m.selectEntries(query)
+	if err != nil {
+		return err
+	}
+	writer := export.NewChunkWriter(stream)
+	for _, entry := range entries {
+		err = marshaler.Marshal(writer, entry)
+		if err != nil {
+			return err
+		}
+		_, err = writer.Write([]byte{'\n'})
+		if err != nil {
+			return err
+		}
+	}
+	err = writer.Flush()
+	if err != nil {
+		return err
+	}
+	log.Debug().Str("organization_id", query.OrganizationId).Int("entries", len(entries)).Msg("audit log exported")
+	return nil
+}
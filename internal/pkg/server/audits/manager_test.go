@@ -0,0 +1,87 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audits
+
+import (
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/audit"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type fakeExportStream struct {
+	grpc.ServerStream
+	data []byte
+}
+
+func (f *fakeExportStream) Send(chunk *grpc_inventory_manager_go.ExportChunk) error {
+	f.data = append(f.data, chunk.Data...)
+	return nil
+}
+
+var _ = ginkgo.Describe("Audit log stored in files", func() {
+
+	var storagePath string
+	var manager Manager
+
+	ginkgo.BeforeEach(func() {
+		path, err := ioutil.TempDir("", "audit")
+		gomega.Expect(err).To(gomega.Succeed())
+		storagePath = path
+		provider, pErr := audit.NewFileProvider(storagePath)
+		gomega.Expect(pErr).To(gomega.Succeed())
+		manager = NewManager(provider)
+	})
+
+	ginkgo.AfterEach(func() {
+		gomega.Expect(os.RemoveAll(storagePath)).To(gomega.Succeed())
+	})
+
+	ginkgo.It("should skip the corrupt entries", func() {
+		manager.RecordSystem("org", "/first", nil, nil)
+		logFile, err := os.OpenFile(filepath.Join(storagePath, audit.AuditDir, "org.jsonl"), os.O_APPEND|os.O_WRONLY, 0600)
+		gomega.Expect(err).To(gomega.Succeed())
+		_, err = logFile.WriteString("{\"entry_id\":\"trunc\n")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(logFile.Close()).To(gomega.Succeed())
+		manager.RecordSystem("org", "/second", nil, derrors.NewNotFoundError("asset"))
+
+		list, lErr := manager.ListAuditEntries(&grpc_inventory_manager_go.AuditQuery{OrganizationId: "org"})
+		gomega.Expect(lErr).To(gomega.Succeed())
+		gomega.Expect(list.Entries).To(gomega.HaveLen(2))
+		gomega.Expect(list.Entries[0].Method).To(gomega.Equal("/second"))
+		gomega.Expect(list.Entries[0].Success).To(gomega.BeFalse())
+		gomega.Expect(list.Entries[1].Method).To(gomega.Equal("/first"))
+	})
+
+	ginkgo.It("should export the entries with the field names of the API", func() {
+		manager.RecordSystem("org", "/first", nil, nil)
+		manager.RecordSystem("org", "/second", nil, nil)
+		stream := &fakeExportStream{}
+		gomega.Expect(manager.ExportAuditEntries(&grpc_inventory_manager_go.AuditQuery{OrganizationId: "org"}, stream)).To(gomega.Succeed())
+		lines := strings.Split(strings.TrimSpace(string(stream.data)), "\n")
+		gomega.Expect(lines).To(gomega.HaveLen(2))
+		gomega.Expect(lines[0]).To(gomega.ContainSubstring("\"organization_id\":\"org\""))
+		gomega.Expect(lines[1]).To(gomega.ContainSubstring("\"method\":\"/second\""))
+	})
+})
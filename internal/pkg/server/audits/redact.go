@@ -0,0 +1,103 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audits
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"strings"
+)
+
+// RedactedValue replaces the values of the sensitive fields.
+const RedactedValue = "<redacted>"
+
+// MaxAuditValueSize with the maximum size of a string value recorded in a request. Longer values, such as the
+// content of the imported files, are replaced by their size.
+const MaxAuditValueSize = 1024
+
+// RedactedFields contains the words that identify a sensitive field. The names are compared in lower case and
+// without underscores.
+var RedactedFields = []string{"password", "secret", "token", "apikey", "privatekey", "cacert", "certificate", "credential"}
+
+// isRedactedField checks if the value of a field must not be recorded.
+func isRedactedField(name string) bool {
+	normalized := strings.Replace(strings.ToLower(name), "_", "", -1)
+	for _, field := range RedactedFields {
+		if strings.Contains(normalized, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// marshaler writes the requests and the audit entries with the field names of the API.
+var marshaler = jsonpb.Marshaler{OrigName: true}
+
+// toJSON serializes a request. The protocol buffer messages are serialized with the marshaler so the enums
+// are written by name.
+func toJSON(request interface{}) ([]byte, error) {
+	if message, isMessage := request.(proto.Message); isMessage {
+		var buffer bytes.Buffer
+		err := marshaler.Marshal(&buffer, message)
+		return buffer.Bytes(), err
+	}
+	return json.Marshal(request)
+}
+
+// redactRequest returns the JSON representation of a request without its sensitive fields.
+func redactRequest(request interface{}) string {
+	content, err := toJSON(request)
+	if err != nil {
+		return ""
+	}
+	var decoded interface{}
+	err = json.Unmarshal(content, &decoded)
+	if err != nil {
+		return ""
+	}
+	redacted, err := json.Marshal(redactValue(decoded))
+	if err != nil {
+		return ""
+	}
+	return string(redacted)
+}
+
+// redactValue replaces the sensitive fields and the long values of a decoded JSON value.
+func redactValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range typed {
+			if isRedactedField(key) {
+				typed[key] = RedactedValue
+				continue
+			}
+			typed[key] = redactValue(nested)
+		}
+	case []interface{}:
+		for index, nested := range typed {
+			typed[index] = redactValue(nested)
+		}
+	case string:
+		if len(typed) > MaxAuditValueSize {
+			return fmt.Sprintf("<%d bytes>", len(typed))
+		}
+	}
+	return value
+}
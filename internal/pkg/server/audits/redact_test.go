@@ -0,0 +1,86 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audits
+
+import (
+	"encoding/json"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"strings"
+	"time"
+)
+
+var _ = ginkgo.Describe("Audit entries", func() {
+
+	decode := func(request string) map[string]interface{} {
+		decoded := make(map[string]interface{})
+		gomega.Expect(json.Unmarshal([]byte(request), &decoded)).To(gomega.Succeed())
+		return decoded
+	}
+
+	ginkgo.It("should redact the sensitive fields", func() {
+		request := map[string]interface{}{
+			"organization_id": "org",
+			"ca_cert":         "-----BEGIN CERTIFICATE-----",
+			"Credentials":     map[string]interface{}{"Username": "user", "Password": "pass"},
+			"device_api_key":  "key",
+			"labels":          map[string]string{"env": "prod"},
+		}
+		decoded := decode(redactRequest(request))
+		gomega.Expect(decoded["organization_id"]).To(gomega.Equal("org"))
+		gomega.Expect(decoded["ca_cert"]).To(gomega.Equal(RedactedValue))
+		gomega.Expect(decoded["Credentials"]).To(gomega.Equal(RedactedValue))
+		gomega.Expect(decoded["device_api_key"]).To(gomega.Equal(RedactedValue))
+		gomega.Expect(decoded["labels"]).To(gomega.Equal(map[string]interface{}{"env": "prod"}))
+	})
+
+	ginkgo.It("should replace the long values by their size", func() {
+		request := map[string]interface{}{
+			"records": []interface{}{strings.Repeat("a", MaxAuditValueSize+1)},
+		}
+		decoded := decode(redactRequest(request))
+		gomega.Expect(decoded["records"]).To(gomega.Equal([]interface{}{"<1025 bytes>"}))
+	})
+
+	ginkgo.It("should record the caller, organization and outcome of a call", func() {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(CallerMetadataKey, "admin"))
+		request := &grpc_inventory_manager_go.UnlinkECRequest{OrganizationId: "org", EdgeControllerId: "ec", Force: true}
+		start := time.Unix(1000, 0)
+		callErr := status.Error(codes.PermissionDenied, "denied")
+		entry := newEntry(ctx, "/inventory_manager.EIC/UnlinkEIC", request, callErr, start, start.Add(250*time.Millisecond))
+		gomega.Expect(entry.OrganizationId).To(gomega.Equal("org"))
+		gomega.Expect(entry.CallerId).To(gomega.Equal("admin"))
+		gomega.Expect(entry.Timestamp).To(gomega.Equal(int64(1000)))
+		gomega.Expect(entry.DurationMs).To(gomega.Equal(int64(250)))
+		gomega.Expect(entry.Success).To(gomega.BeFalse())
+		gomega.Expect(entry.ErrorCode).To(gomega.Equal(codes.PermissionDenied.String()))
+		gomega.Expect(entry.ErrorMessage).To(gomega.Equal("denied"))
+	})
+
+	ginkgo.It("should match the methods by their full or short name", func() {
+		entry := &grpc_inventory_manager_go.AuditEntry{Method: "/inventory_manager.EIC/UnlinkEIC", CallerId: "admin"}
+		gomega.Expect(matchesQuery(entry, &grpc_inventory_manager_go.AuditQuery{Method: "UnlinkEIC"})).To(gomega.BeTrue())
+		gomega.Expect(matchesQuery(entry, &grpc_inventory_manager_go.AuditQuery{Method: entry.Method})).To(gomega.BeTrue())
+		gomega.Expect(matchesQuery(entry, &grpc_inventory_manager_go.AuditQuery{Method: "EICJoin"})).To(gomega.BeFalse())
+		gomega.Expect(matchesQuery(entry, &grpc_inventory_manager_go.AuditQuery{CallerId: "other"})).To(gomega.BeFalse())
+	})
+})
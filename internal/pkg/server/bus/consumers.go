@@ -0,0 +1,78 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bus
+
+import (
+	"context"
+	"github.com/nalej/derrors"
+	"sync"
+)
+
+// consumerGroup coordinates the shutdown of a queue handler. The loop consuming from the queue finishes when the
+// group is stopped, and the channel consumers drain the messages already received before finishing.
+type consumerGroup struct {
+	// ctx is cancelled to stop consuming from the queue.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// stopped is closed when the queue is no longer consumed.
+	stopped chan struct{}
+	// workers is the context of the workers. It is only cancelled when the grace period expires so the drained
+	// messages are still retried while the group is stopping.
+	workers context.Context
+	abort   context.CancelFunc
+	// consumers with the goroutines reading from the channels and the workers processing their messages.
+	consumers sync.WaitGroup
+}
+
+func newConsumerGroup() *consumerGroup {
+	ctx, cancel := context.WithCancel(context.Background())
+	workers, abort := context.WithCancel(context.Background())
+	return &consumerGroup{
+		ctx:     ctx,
+		cancel:  cancel,
+		stopped: make(chan struct{}),
+		workers: workers,
+		abort:   abort,
+	}
+}
+
+// stop stops consuming from the queue.
+func (cg *consumerGroup) stop() {
+	cg.cancel()
+}
+
+// wait waits for the channel consumers to finish processing the received messages. If they do not finish before the
+// context expires, the retries of the workers are aborted and an error is returned.
+func (cg *consumerGroup) wait(ctx context.Context, queue string) derrors.Error {
+	defer cg.abort()
+	select {
+	case <-cg.stopped:
+	case <-ctx.Done():
+		return derrors.NewDeadlineExceededError("the queue is still being consumed").WithParams(queue)
+	}
+	finished := make(chan struct{})
+	go func() {
+		cg.consumers.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return derrors.NewDeadlineExceededError("the received messages have not been processed").WithParams(queue)
+	}
+}
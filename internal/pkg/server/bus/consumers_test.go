@@ -0,0 +1,55 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bus
+
+import (
+	"context"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"time"
+)
+
+var _ = ginkgo.Describe("Consumer group", func() {
+
+	ginkgo.It("should keep the workers running while the received messages are drained", func() {
+		group := newConsumerGroup()
+		group.consumers.Add(1)
+		group.stop()
+		close(group.stopped)
+		gomega.Expect(group.ctx.Err()).NotTo(gomega.BeNil())
+		gomega.Expect(group.workers.Err()).To(gomega.BeNil())
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			group.consumers.Done()
+		}()
+		gomega.Expect(group.wait(context.Background(), InventoryOpsQueue)).To(gomega.Succeed())
+	})
+
+	ginkgo.It("should abort the workers when the grace period expires", func() {
+		group := newConsumerGroup()
+		group.consumers.Add(1)
+		defer group.consumers.Done()
+		group.stop()
+		close(group.stopped)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		gomega.Expect(group.wait(ctx, InventoryOpsQueue)).NotTo(gomega.Succeed())
+		gomega.Expect(group.workers.Err()).NotTo(gomega.BeNil())
+	})
+})
@@ -18,8 +18,8 @@ package bus
 
 import (
 	"context"
-	"github.com/nalej/inventory-manager/internal/pkg/server/agent"
-	"github.com/nalej/inventory-manager/internal/pkg/server/edgecontroller"
+	"github.com/nalej/derrors"
+	"github.com/nalej/inventory-manager/internal/pkg/config"
 	"github.com/nalej/nalej-bus/pkg/queue/inventory/events"
 	"github.com/rs/zerolog/log"
 	"time"
@@ -30,20 +30,25 @@ const InventoryEventsTimeout = time.Second * 30
 // TODO Refactor this package to be outside of server, and move service.go to other package.
 
 type InventoryEventsHandler struct {
-	ecHandler    *edgecontroller.Handler
-	agentHandler *agent.Handler
-	consumer     *events.InventoryEventsConsumer
+	consumer *events.InventoryEventsConsumer
+	// pools with the workers processing each message type.
+	pools map[string]*workerPool
+	group *consumerGroup
 }
 
-func NewInventoryEventsHandler(ecHandler *edgecontroller.Handler, agentHandler *agent.Handler, consumer *events.InventoryEventsConsumer) *InventoryEventsHandler {
+func NewInventoryEventsHandler(processor *Processor, consumer *events.InventoryEventsConsumer, cfg config.Config) *InventoryEventsHandler {
 	return &InventoryEventsHandler{
-		ecHandler:    ecHandler,
-		agentHandler: agentHandler,
-		consumer:     consumer,
+		consumer: consumer,
+		pools:    newWorkerPools(processor, cfg, EICStartMessage, EdgeControllerAliveMessage, AgentsAliveMessage, AgentUninstalledMessage),
+		group:    newConsumerGroup(),
 	}
 }
 
 func (ieh *InventoryEventsHandler) Run() {
+	ieh.group.consumers.Add(4)
+	for _, pool := range ieh.pools {
+		pool.start(ieh.group.workers, &ieh.group.consumers)
+	}
 	go ieh.consumeEICStart()
 	go ieh.consumeEdgeControllerId()
 	go ieh.consumeAgentAlive()
@@ -51,16 +56,29 @@ func (ieh *InventoryEventsHandler) Run() {
 	go ieh.waitRequests()
 }
 
-// Endless loop waiting for requests
+// Stop stops consuming from the inventory events queue.
+func (ieh *InventoryEventsHandler) Stop() {
+	ieh.group.stop()
+}
+
+// Wait waits for the received events to be processed.
+func (ieh *InventoryEventsHandler) Wait(ctx context.Context) derrors.Error {
+	return ieh.group.wait(ctx, InventoryEventsQueue)
+}
+
+// Loop waiting for requests until the handler is stopped
 func (ieh *InventoryEventsHandler) waitRequests() {
+	defer close(ieh.group.stopped)
 	log.Debug().Msg("wait for requests to be received by the inventory events queue")
-	for {
-		ctx, cancel := context.WithTimeout(context.Background(), InventoryEventsTimeout)
+	for ieh.group.ctx.Err() == nil {
+		ctx, cancel := context.WithTimeout(ieh.group.ctx, InventoryEventsTimeout)
 		// in every iteration this loop consumes data and sends it to the corresponding channels
 		currentTime := time.Now()
 		err := ieh.consumer.Consume(ctx)
 		cancel()
 		select {
+		case <-ieh.group.ctx.Done():
+			log.Debug().Msg("inventory events queue no longer consumed")
 		case <-ctx.Done():
 			// the timeout was reached
 			log.Debug().Msgf("no message received since %s", currentTime.Format(time.RFC3339))
@@ -74,36 +92,76 @@ func (ieh *InventoryEventsHandler) waitRequests() {
 }
 
 func (ieh *InventoryEventsHandler) consumeEICStart() {
+	defer ieh.group.consumers.Done()
+	defer ieh.pools[EICStartMessage].close()
 	log.Debug().Msg("consuming EICStart")
+	ch := ieh.consumer.Config.ChEICStart
 	for {
-		received := <-ieh.consumer.Config.ChEICStart
-		log.Debug().Interface("message", received).Msg("EICSTart received")
-		ieh.ecHandler.EICStart(nil, received)
+		select {
+		case received := <-ch:
+			log.Debug().Interface("message", received).Msg("EICSTart received")
+			ieh.pools[EICStartMessage].dispatch(received)
+		case <-ieh.group.stopped:
+			for len(ch) > 0 {
+				ieh.pools[EICStartMessage].dispatch(<-ch)
+			}
+			return
+		}
 	}
 }
 
 func (ieh *InventoryEventsHandler) consumeEdgeControllerId() {
+	defer ieh.group.consumers.Done()
+	defer ieh.pools[EdgeControllerAliveMessage].close()
 	log.Debug().Msg("consuming EdgeControllerId")
+	ch := ieh.consumer.Config.ChEdgeControllerId
 	for {
-		received := <-ieh.consumer.Config.ChEdgeControllerId
-		log.Debug().Interface("message", received).Msg("EdgeControllerId received")
-		ieh.ecHandler.EICAlive(nil, received)
+		select {
+		case received := <-ch:
+			log.Debug().Interface("message", received).Msg("EdgeControllerId received")
+			ieh.pools[EdgeControllerAliveMessage].dispatch(received)
+		case <-ieh.group.stopped:
+			for len(ch) > 0 {
+				ieh.pools[EdgeControllerAliveMessage].dispatch(<-ch)
+			}
+			return
+		}
 	}
 }
 
 func (ieh *InventoryEventsHandler) consumeAgentAlive() {
+	defer ieh.group.consumers.Done()
+	defer ieh.pools[AgentsAliveMessage].close()
 	log.Debug().Msg("consuming AgentAlive")
+	ch := ieh.consumer.Config.ChAgentsAlive
 	for {
-		received := <-ieh.consumer.Config.ChAgentsAlive
-		log.Debug().Interface("message", received).Msg("AgentAlive received")
-		ieh.agentHandler.LogAgentAlive(nil, received)
+		select {
+		case received := <-ch:
+			log.Debug().Interface("message", received).Msg("AgentAlive received")
+			ieh.pools[AgentsAliveMessage].dispatch(received)
+		case <-ieh.group.stopped:
+			for len(ch) > 0 {
+				ieh.pools[AgentsAliveMessage].dispatch(<-ch)
+			}
+			return
+		}
 	}
 }
 func (ieh *InventoryEventsHandler) consumeAgentUninstalled() {
+	defer ieh.group.consumers.Done()
+	defer ieh.pools[AgentUninstalledMessage].close()
 	log.Debug().Msg("consuming AgentUninstalled")
+	ch := ieh.consumer.Config.ChUninstalledAssetId
 	for {
-		received := <-ieh.consumer.Config.ChUninstalledAssetId
-		log.Debug().Interface("message", received).Msg("AgentUninstalled received")
-		ieh.agentHandler.UninstalledAgent(nil, received)
+		select {
+		case received := <-ch:
+			log.Debug().Interface("message", received).Msg("AgentUninstalled received")
+			ieh.pools[AgentUninstalledMessage].dispatch(received)
+		case <-ieh.group.stopped:
+			for len(ch) > 0 {
+				ieh.pools[AgentUninstalledMessage].dispatch(<-ch)
+			}
+			return
+		}
 	}
-}
\ No newline at end of file
+}
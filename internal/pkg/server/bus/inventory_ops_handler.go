@@ -18,8 +18,8 @@ package bus
 
 import (
 	"context"
-	"github.com/nalej/inventory-manager/internal/pkg/server/agent"
-	"github.com/nalej/inventory-manager/internal/pkg/server/edgecontroller"
+	"github.com/nalej/derrors"
+	"github.com/nalej/inventory-manager/internal/pkg/config"
 	"github.com/nalej/nalej-bus/pkg/queue/inventory/ops"
 	"github.com/rs/zerolog/log"
 	"time"
@@ -29,35 +29,53 @@ const InventoryOpsTimeout = time.Second * 30
 
 
 type InventoryOpsHandler struct {
-	agentHandler *agent.Handler
-	edgeControllerHandler * edgecontroller.Handler
 	consumer     *ops.InventoryOpsConsumer
+	// pools with the workers processing each message type.
+	pools        map[string]*workerPool
+	group        *consumerGroup
 }
 
-func NewInventoryOpsHandler(agentHandler *agent.Handler, edgeControllerHandler * edgecontroller.Handler, consumer *ops.InventoryOpsConsumer) *InventoryOpsHandler {
+func NewInventoryOpsHandler(processor *Processor, consumer *ops.InventoryOpsConsumer, cfg config.Config) *InventoryOpsHandler {
 	return &InventoryOpsHandler{
-		agentHandler: agentHandler,
-		edgeControllerHandler:edgeControllerHandler,
 		consumer:     consumer,
+		pools:        newWorkerPools(processor, cfg, AgentOpResponseMessage, EdgeControllerOpResponseMessage),
+		group:        newConsumerGroup(),
 	}
 }
 
 func (ioh *InventoryOpsHandler) Run() {
+	ioh.group.consumers.Add(2)
+	for _, pool := range ioh.pools {
+		pool.start(ioh.group.workers, &ioh.group.consumers)
+	}
 	go ioh.consumeAgentOpResponse()
 	go ioh.consumeECOpResponse()
 	go ioh.waitRequests()
 }
 
-// Endless loop waiting for requests
+// Stop stops consuming from the inventory ops queue.
+func (ioh *InventoryOpsHandler) Stop() {
+	ioh.group.stop()
+}
+
+// Wait waits for the received responses to be processed.
+func (ioh *InventoryOpsHandler) Wait(ctx context.Context) derrors.Error {
+	return ioh.group.wait(ctx, InventoryOpsQueue)
+}
+
+// Loop waiting for requests until the handler is stopped
 func (ioh *InventoryOpsHandler) waitRequests() {
+	defer close(ioh.group.stopped)
 	log.Debug().Msg("wait for requests to be received by the inventory ops queue")
-	for {
-		ctx, cancel := context.WithTimeout(context.Background(), InventoryOpsTimeout)
+	for ioh.group.ctx.Err() == nil {
+		ctx, cancel := context.WithTimeout(ioh.group.ctx, InventoryOpsTimeout)
 		// in every iteration this loop consumes data and sends it to the corresponding channels
 		currentTime := time.Now()
 		err := ioh.consumer.Consume(ctx)
 		cancel()
 		select {
+		case <-ioh.group.ctx.Done():
+			log.Debug().Msg("inventory ops queue no longer consumed")
 		case <-ctx.Done():
 			// the timeout was reached
 			log.Debug().Msgf("no message received since %s", currentTime.Format(time.RFC3339))
@@ -71,19 +89,39 @@ func (ioh *InventoryOpsHandler) waitRequests() {
 }
 
 func (ioh *InventoryOpsHandler) consumeAgentOpResponse() {
+	defer ioh.group.consumers.Done()
+	defer ioh.pools[AgentOpResponseMessage].close()
 	log.Debug().Msg("AgentOpResponse")
+	ch := ioh.consumer.Config.ChAgentOpResponse
 	for {
-		received := <- ioh.consumer.Config.ChAgentOpResponse
-		log.Debug().Msg("agentOpResponse received")
-		ioh.agentHandler.CallbackAgentOperation(nil, received)
+		select {
+		case received := <- ch:
+			log.Debug().Msg("agentOpResponse received")
+			ioh.pools[AgentOpResponseMessage].dispatch(received)
+		case <- ioh.group.stopped:
+			for len(ch) > 0 {
+				ioh.pools[AgentOpResponseMessage].dispatch(<- ch)
+			}
+			return
+		}
 	}
 }
 
 func (ioh *InventoryOpsHandler) consumeECOpResponse() {
+	defer ioh.group.consumers.Done()
+	defer ioh.pools[EdgeControllerOpResponseMessage].close()
 	log.Debug().Msg("ECOpResponse")
+	ch := ioh.consumer.Config.ChEdgeControllerOpResponse
 	for {
-		received := <- ioh.consumer.Config.ChEdgeControllerOpResponse
-		log.Debug().Msg("edgeControllerOpResponse received")
-		ioh.edgeControllerHandler.CallbackECOperation(nil, received)
+		select {
+		case received := <- ch:
+			log.Debug().Msg("edgeControllerOpResponse received")
+			ioh.pools[EdgeControllerOpResponseMessage].dispatch(received)
+		case <- ioh.group.stopped:
+			for len(ch) > 0 {
+				ioh.pools[EdgeControllerOpResponseMessage].dispatch(<- ch)
+			}
+			return
+		}
 	}
 }
@@ -0,0 +1,95 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bus
+
+import (
+	"context"
+	"github.com/nalej/inventory-manager/internal/pkg/config"
+	"hash/fnv"
+	"sync"
+)
+
+// workerPool processes the messages of a type in parallel. The messages with the same key are always sent to the
+// same worker so they are processed in the order they were received.
+type workerPool struct {
+	processor       *Processor
+	messageTypeName string
+	// queues with the pending messages of each worker.
+	queues []chan organizationMessage
+}
+
+func newWorkerPool(processor *Processor, messageTypeName string, workers int, bufferSize int) *workerPool {
+	queues := make([]chan organizationMessage, workers)
+	for index := range queues {
+		queues[index] = make(chan organizationMessage, bufferSize)
+	}
+	return &workerPool{
+		processor:       processor,
+		messageTypeName: messageTypeName,
+		queues:          queues,
+	}
+}
+
+// newWorkerPools creates a pool per message type with the configured number of workers.
+func newWorkerPools(processor *Processor, cfg config.Config, messageTypeNames ...string) map[string]*workerPool {
+	pools := make(map[string]*workerPool, len(messageTypeNames))
+	for _, messageTypeName := range messageTypeNames {
+		pools[messageTypeName] = newWorkerPool(processor, messageTypeName, numWorkers(cfg, messageTypeName), cfg.BusBufferSize)
+	}
+	return pools
+}
+
+// numWorkers returns the workers configured for a message type, or BusWorkers if the type is not configured.
+func numWorkers(cfg config.Config, messageTypeName string) int {
+	if workers, exists := cfg.BusTypeWorkers[messageTypeName]; exists {
+		return workers
+	}
+	return cfg.BusWorkers
+}
+
+// start launches the workers. They are added to the wait group and finish once the pool is closed and their
+// pending messages have been processed.
+func (wp *workerPool) start(ctx context.Context, wg *sync.WaitGroup) {
+	wg.Add(len(wp.queues))
+	for _, queue := range wp.queues {
+		go func(queue chan organizationMessage) {
+			defer wg.Done()
+			for message := range queue {
+				wp.processor.Process(ctx, wp.messageTypeName, message)
+			}
+		}(queue)
+	}
+}
+
+// keyHash returns the hash used to distribute the messages by key.
+func keyHash(key string) uint32 {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return hash.Sum32()
+}
+
+// dispatch sends a message to the worker of its key. It blocks while the queue of that worker is full.
+func (wp *workerPool) dispatch(message organizationMessage) {
+	wp.queues[keyHash(wp.processor.key(wp.messageTypeName, message))%uint32(len(wp.queues))] <- message
+}
+
+// close stops accepting messages. It must be called once no more messages are dispatched.
+func (wp *workerPool) close() {
+	for _, queue := range wp.queues {
+		close(queue)
+	}
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bus
+
+import (
+	"context"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/inventory-manager/internal/pkg/config"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/deadletter"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"sync"
+	"time"
+)
+
+var _ = ginkgo.Describe("Worker pool", func() {
+
+	const numAssets = 5
+	const numMessages = 50
+
+	var lock sync.Mutex
+	// processed with the operations processed for each asset.
+	var processed map[string][]string
+	var pool *workerPool
+
+	ginkgo.BeforeEach(func() {
+		processed = make(map[string][]string)
+		processor := &Processor{
+			messageTypes: map[string]*messageType{
+				AgentOpResponseMessage: {
+					queue:      InventoryOpsQueue,
+					newMessage: func() organizationMessage { return &grpc_inventory_manager_go.AgentOpResponse{} },
+					key: func(message organizationMessage) string {
+						return message.(*grpc_inventory_manager_go.AgentOpResponse).AssetId
+					},
+					handle: func(message organizationMessage) error {
+						response := message.(*grpc_inventory_manager_go.AgentOpResponse)
+						lock.Lock()
+						defer lock.Unlock()
+						processed[response.AssetId] = append(processed[response.AssetId], response.OperationId)
+						return nil
+					},
+					deadLetter: true,
+				},
+			},
+			provider:    deadletter.NewMemoryProvider(),
+			maxAttempts: 1,
+			backoff:     time.Millisecond,
+		}
+		pool = newWorkerPool(processor, AgentOpResponseMessage, 3, 2)
+	})
+
+	ginkgo.It("should process the messages of each key in order", func() {
+		var wg sync.WaitGroup
+		pool.start(context.Background(), &wg)
+		expected := make(map[string][]string)
+		for index := 0; index < numMessages; index++ {
+			assetID := string(rune('a' + index%numAssets))
+			operationID := string(rune('A' + index))
+			expected[assetID] = append(expected[assetID], operationID)
+			pool.dispatch(&grpc_inventory_manager_go.AgentOpResponse{OrganizationId: "org", AssetId: assetID, OperationId: operationID})
+		}
+		pool.close()
+		wg.Wait()
+		gomega.Expect(processed).To(gomega.Equal(expected))
+	})
+
+	ginkgo.It("should use the workers configured for each message type", func() {
+		cfg := config.Config{
+			BusWorkers:     4,
+			BusBufferSize:  1,
+			BusTypeWorkers: map[string]int{AgentsAliveMessage: 16},
+		}
+		pools := newWorkerPools(&Processor{}, cfg, AgentsAliveMessage, EICStartMessage)
+		gomega.Expect(pools[AgentsAliveMessage].queues).To(gomega.HaveLen(16))
+		gomega.Expect(pools[EICStartMessage].queues).To(gomega.HaveLen(4))
+	})
+})
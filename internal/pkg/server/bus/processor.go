@@ -0,0 +1,331 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bus
+
+import (
+	"context"
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/inventory-manager/internal/pkg/config"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/deadletter"
+	"github.com/nalej/inventory-manager/internal/pkg/server/agent"
+	"github.com/nalej/inventory-manager/internal/pkg/server/edgecontroller"
+	"github.com/rs/zerolog/log"
+	"github.com/satori/go.uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sync"
+	"time"
+)
+
+// Names of the queues the messages are received from.
+const (
+	InventoryEventsQueue = "inventory events"
+	InventoryOpsQueue    = "inventory ops"
+)
+
+// Types of the messages received from the queues.
+const (
+	EICStartMessage                 = "EICStartInfo"
+	EdgeControllerAliveMessage      = "EdgeControllerId"
+	AgentsAliveMessage              = "AgentsAlive"
+	AgentUninstalledMessage         = "AssetUninstalledId"
+	AgentOpResponseMessage          = "AgentOpResponse"
+	EdgeControllerOpResponseMessage = "EdgeControllerOpResponse"
+)
+
+// MaxRetryBackoff with the maximum time to wait between two attempts to process a message.
+const MaxRetryBackoff = time.Minute
+
+// keyLocks with the number of locks serializing the processing of the messages with the same key.
+const keyLocks = 64
+
+// permanentErrors contains the status codes of the errors that cannot be solved by retrying.
+var permanentErrors = map[codes.Code]bool{
+	codes.InvalidArgument: true,
+	codes.Unimplemented:   true,
+}
+
+// payloadMarshaler writes the payload of the dead letters with the field names of the API.
+var payloadMarshaler = jsonpb.Marshaler{OrigName: true}
+
+// organizationMessage is implemented by the messages received from the queues.
+type organizationMessage interface {
+	proto.Message
+	GetOrganizationId() string
+}
+
+type messageType struct {
+	queue string
+	// newMessage creates an empty message to decode a dead letter.
+	newMessage func() organizationMessage
+	// key returns the entity the message refers to. The messages with the same key are processed in order.
+	key    func(message organizationMessage) string
+	handle func(message organizationMessage) error
+	// deadLetter indicates whether the messages that cannot be processed are stored to be replayed. The liveness
+	// messages are superseded by the next ones so they are discarded instead.
+	deadLetter bool
+}
+
+// Processor handles the messages received from the queues retrying them with exponential backoff. The messages
+// that keep failing are stored as dead letters.
+type Processor struct {
+	messageTypes map[string]*messageType
+	provider     deadletter.Provider
+	maxAttempts  int
+	backoff      time.Duration
+	// locks prevent a replayed dead letter from being processed at the same time as a message with the same key.
+	locks [keyLocks]sync.Mutex
+}
+
+func NewProcessor(ecHandler *edgecontroller.Handler, agentHandler *agent.Handler, provider deadletter.Provider, cfg config.Config) *Processor {
+	messageTypes := map[string]*messageType{
+		EICStartMessage: {
+			queue:      InventoryEventsQueue,
+			newMessage: func() organizationMessage { return &grpc_inventory_manager_go.EICStartInfo{} },
+			key: func(message organizationMessage) string {
+				return message.(*grpc_inventory_manager_go.EICStartInfo).EdgeControllerId
+			},
+			handle: func(message organizationMessage) error {
+				_, err := ecHandler.EICStart(nil, message.(*grpc_inventory_manager_go.EICStartInfo))
+				return err
+			},
+			deadLetter: true,
+		},
+		EdgeControllerAliveMessage: {
+			queue:      InventoryEventsQueue,
+			newMessage: func() organizationMessage { return &grpc_inventory_go.EdgeControllerId{} },
+			key: func(message organizationMessage) string {
+				return message.(*grpc_inventory_go.EdgeControllerId).EdgeControllerId
+			},
+			handle: func(message organizationMessage) error {
+				_, err := ecHandler.EICAlive(nil, message.(*grpc_inventory_go.EdgeControllerId))
+				return err
+			},
+		},
+		AgentsAliveMessage: {
+			queue:      InventoryEventsQueue,
+			newMessage: func() organizationMessage { return &grpc_inventory_manager_go.AgentsAlive{} },
+			key: func(message organizationMessage) string {
+				return message.(*grpc_inventory_manager_go.AgentsAlive).EdgeControllerId
+			},
+			handle: func(message organizationMessage) error {
+				_, err := agentHandler.LogAgentAlive(nil, message.(*grpc_inventory_manager_go.AgentsAlive))
+				return err
+			},
+		},
+		AgentUninstalledMessage: {
+			queue:      InventoryEventsQueue,
+			newMessage: func() organizationMessage { return &grpc_inventory_go.AssetUninstalledId{} },
+			key: func(message organizationMessage) string {
+				return message.(*grpc_inventory_go.AssetUninstalledId).AssetId
+			},
+			handle: func(message organizationMessage) error {
+				_, err := agentHandler.UninstalledAgent(nil, message.(*grpc_inventory_go.AssetUninstalledId))
+				return err
+			},
+			deadLetter: true,
+		},
+		AgentOpResponseMessage: {
+			queue:      InventoryOpsQueue,
+			newMessage: func() organizationMessage { return &grpc_inventory_manager_go.AgentOpResponse{} },
+			key: func(message organizationMessage) string {
+				return message.(*grpc_inventory_manager_go.AgentOpResponse).AssetId
+			},
+			handle: func(message organizationMessage) error {
+				_, err := agentHandler.CallbackAgentOperation(nil, message.(*grpc_inventory_manager_go.AgentOpResponse))
+				return err
+			},
+			deadLetter: true,
+		},
+		EdgeControllerOpResponseMessage: {
+			queue:      InventoryOpsQueue,
+			newMessage: func() organizationMessage { return &grpc_inventory_manager_go.EdgeControllerOpResponse{} },
+			key: func(message organizationMessage) string {
+				return message.(*grpc_inventory_manager_go.EdgeControllerOpResponse).EdgeControllerId
+			},
+			handle: func(message organizationMessage) error {
+				_, err := ecHandler.CallbackECOperation(nil, message.(*grpc_inventory_manager_go.EdgeControllerOpResponse))
+				return err
+			},
+			deadLetter: true,
+		},
+	}
+	for messageTypeName := range cfg.BusTypeWorkers {
+		if _, exists := messageTypes[messageTypeName]; !exists {
+			log.Warn().Str("message_type", messageTypeName).Msg("workers configured for an unknown message type")
+		}
+	}
+	return &Processor{
+		messageTypes: messageTypes,
+		provider:     provider,
+		maxAttempts:  cfg.BusMaxAttempts,
+		backoff:      cfg.BusRetryBackoff,
+	}
+}
+
+// key returns the key of a message to keep the order of the messages of the same entity.
+func (p *Processor) key(messageTypeName string, message organizationMessage) string {
+	return p.messageTypes[messageTypeName].key(message)
+}
+
+// keyLock returns the lock of the key of a message.
+func (p *Processor) keyLock(mt *messageType, message organizationMessage) *sync.Mutex {
+	return &p.locks[keyHash(mt.key(message))%keyLocks]
+}
+
+// handle processes a message once holding the lock of its key.
+func (p *Processor) handle(mt *messageType, message organizationMessage) error {
+	lock := p.keyLock(mt, message)
+	lock.Lock()
+	defer lock.Unlock()
+	return mt.handle(message)
+}
+
+// retryBackoff returns the time to wait after a failed attempt, doubling the initial backoff on every attempt.
+func retryBackoff(initial time.Duration, attempt int) time.Duration {
+	backoff := initial
+	for i := 1; i < attempt && backoff < MaxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > MaxRetryBackoff {
+		return MaxRetryBackoff
+	}
+	return backoff
+}
+
+// errorStatus returns the status of the error returned by a handler.
+func errorStatus(err error) *status.Status {
+	if dErr, ok := err.(derrors.Error); ok {
+		err = conversions.ToGRPCError(dErr)
+	}
+	return status.Convert(err)
+}
+
+// Process handles a message retrying the failed attempts. The retries stop when the context is cancelled once the
+// grace period of the shutdown expires, and the message is stored as a dead letter if it could not be processed.
+func (p *Processor) Process(ctx context.Context, messageTypeName string, message organizationMessage) {
+	received := time.Now()
+	mt := p.messageTypes[messageTypeName]
+	var err error
+	attempts := 0
+	for attempts < p.maxAttempts {
+		attempts++
+		err = p.handle(mt, message)
+		if err == nil {
+			return
+		}
+		if permanentErrors[errorStatus(err).Code()] || attempts == p.maxAttempts {
+			break
+		}
+		backoff := retryBackoff(p.backoff, attempts)
+		log.Warn().Str("message_type", messageTypeName).Str("organization_id", message.GetOrganizationId()).
+			Int("attempt", attempts).Str("backoff", backoff.String()).Str("err", err.Error()).Msg("cannot process message, retrying")
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			p.fail(mt, messageTypeName, message, attempts, received, err)
+			return
+		}
+	}
+	p.fail(mt, messageTypeName, message, attempts, received, err)
+}
+
+// fail stores a message that could not be processed as a dead letter, or discards it if it is not worth replaying.
+func (p *Processor) fail(mt *messageType, messageTypeName string, message organizationMessage, attempts int, received time.Time, err error) {
+	if !mt.deadLetter {
+		log.Warn().Str("message_type", messageTypeName).Str("organization_id", message.GetOrganizationId()).
+			Int("attempts", attempts).Str("err", err.Error()).Msg("message discarded")
+		return
+	}
+	payload, mErr := payloadMarshaler.MarshalToString(message)
+	if mErr != nil {
+		log.Error().Err(mErr).Str("message_type", messageTypeName).Interface("message", message).Msg("cannot serialize dead letter")
+		return
+	}
+	errStatus := errorStatus(err)
+	deadLetter := &grpc_inventory_manager_go.DeadLetter{
+		OrganizationId: message.GetOrganizationId(),
+		DeadLetterId:   uuid.NewV4().String(),
+		Queue:          mt.queue,
+		MessageType:    messageTypeName,
+		Payload:        payload,
+		Attempts:       int32(attempts),
+		Received:       received.Unix(),
+		LastAttempt:    time.Now().Unix(),
+		ErrorCode:      errStatus.Code().String(),
+		ErrorMessage:   errStatus.Message(),
+	}
+	sErr := p.provider.Store(deadLetter)
+	if sErr != nil {
+		log.Error().Str("trace", sErr.DebugReport()).Interface("dead_letter", deadLetter).Msg("cannot store dead letter")
+		return
+	}
+	log.Warn().Str("organization_id", deadLetter.OrganizationId).Str("dead_letter_id", deadLetter.DeadLetterId).
+		Str("message_type", messageTypeName).Int("attempts", attempts).Msg("message stored as dead letter")
+}
+
+// Replay processes the message of a dead letter once. It is serialized with the messages received for the same key
+// so it never runs concurrently with them, nor with another replay of the same dead letter. On failure, the dead
+// letter is updated with the attempt.
+func (p *Processor) Replay(deadLetter *grpc_inventory_manager_go.DeadLetter) derrors.Error {
+	mt, exists := p.messageTypes[deadLetter.MessageType]
+	if !exists {
+		return derrors.NewFailedPreconditionError("unknown message type").WithParams(deadLetter.MessageType)
+	}
+	message := mt.newMessage()
+	err := jsonpb.UnmarshalString(deadLetter.Payload, message)
+	if err != nil {
+		return derrors.NewFailedPreconditionError("cannot decode dead letter", err).WithParams(deadLetter.DeadLetterId)
+	}
+	lock := p.keyLock(mt, message)
+	lock.Lock()
+	defer lock.Unlock()
+	// a concurrent replay may have processed and removed the dead letter while this one was waiting for the lock
+	deadLetter, gErr := p.provider.Get(deadLetter.OrganizationId, deadLetter.DeadLetterId)
+	if gErr != nil {
+		return gErr
+	}
+	err = mt.handle(message)
+	if err != nil {
+		errStatus := errorStatus(err)
+		updated := &grpc_inventory_manager_go.DeadLetter{
+			OrganizationId: deadLetter.OrganizationId,
+			DeadLetterId:   deadLetter.DeadLetterId,
+			Queue:          deadLetter.Queue,
+			MessageType:    deadLetter.MessageType,
+			Payload:        deadLetter.Payload,
+			Attempts:       deadLetter.Attempts + 1,
+			Received:       deadLetter.Received,
+			LastAttempt:    time.Now().Unix(),
+			ErrorCode:      errStatus.Code().String(),
+			ErrorMessage:   errStatus.Message(),
+		}
+		sErr := p.provider.Store(updated)
+		if sErr != nil {
+			log.Error().Str("trace", sErr.DebugReport()).Str("dead_letter_id", deadLetter.DeadLetterId).Msg("cannot update dead letter")
+		}
+		return conversions.ToDerror(err)
+	}
+	return p.provider.Remove(deadLetter.OrganizationId, deadLetter.DeadLetterId)
+}
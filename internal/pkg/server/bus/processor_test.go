@@ -0,0 +1,201 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package bus
+
+import (
+	"context"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/deadletter"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+var _ = ginkgo.Describe("Processor", func() {
+
+	const organizationID = "org"
+
+	var provider *deadletter.MemoryProvider
+	var processor *Processor
+	// failures with the number of attempts that fail before the message is processed.
+	var failures int
+	var failure error
+	var handled []*grpc_inventory_manager_go.AgentOpResponse
+
+	newMessageType := func(deadLetter bool) *messageType {
+		return &messageType{
+			queue:      InventoryOpsQueue,
+			newMessage: func() organizationMessage { return &grpc_inventory_manager_go.AgentOpResponse{} },
+			key: func(message organizationMessage) string {
+				return message.(*grpc_inventory_manager_go.AgentOpResponse).AssetId
+			},
+			handle: func(message organizationMessage) error {
+				if failures > 0 {
+					failures--
+					return failure
+				}
+				handled = append(handled, message.(*grpc_inventory_manager_go.AgentOpResponse))
+				return nil
+			},
+			deadLetter: deadLetter,
+		}
+	}
+
+	ginkgo.BeforeEach(func() {
+		provider = deadletter.NewMemoryProvider()
+		processor = &Processor{
+			messageTypes: map[string]*messageType{
+				AgentOpResponseMessage: newMessageType(true),
+				AgentsAliveMessage:     newMessageType(false),
+			},
+			provider:    provider,
+			maxAttempts: 3,
+			backoff:     time.Millisecond,
+		}
+		failures = 0
+		failure = status.Error(codes.Unavailable, "system model not available")
+		handled = make([]*grpc_inventory_manager_go.AgentOpResponse, 0)
+	})
+
+	message := &grpc_inventory_manager_go.AgentOpResponse{OrganizationId: organizationID, OperationId: "op"}
+
+	ginkgo.It("should double the backoff up to the maximum", func() {
+		gomega.Expect(retryBackoff(time.Second, 1)).To(gomega.Equal(time.Second))
+		gomega.Expect(retryBackoff(time.Second, 3)).To(gomega.Equal(4 * time.Second))
+		gomega.Expect(retryBackoff(time.Second, 100)).To(gomega.Equal(MaxRetryBackoff))
+	})
+
+	ginkgo.It("should retry a failed message", func() {
+		failures = 2
+		processor.Process(context.Background(), AgentOpResponseMessage, message)
+		gomega.Expect(handled).To(gomega.HaveLen(1))
+		deadLetters, err := provider.List(organizationID)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(deadLetters).To(gomega.BeEmpty())
+	})
+
+	ginkgo.It("should store the messages that keep failing as dead letters", func() {
+		failures = 3
+		processor.Process(context.Background(), AgentOpResponseMessage, message)
+		gomega.Expect(handled).To(gomega.BeEmpty())
+		deadLetters, err := provider.List(organizationID)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(deadLetters).To(gomega.HaveLen(1))
+		gomega.Expect(deadLetters[0].Attempts).To(gomega.Equal(int32(3)))
+		gomega.Expect(deadLetters[0].Queue).To(gomega.Equal(InventoryOpsQueue))
+		gomega.Expect(deadLetters[0].ErrorCode).To(gomega.Equal(codes.Unavailable.String()))
+		gomega.Expect(deadLetters[0].Payload).To(gomega.ContainSubstring("\"operation_id\":\"op\""))
+	})
+
+	ginkgo.It("should not retry the invalid messages", func() {
+		failures = 3
+		failure = status.Error(codes.InvalidArgument, "operation_id cannot be empty")
+		processor.Process(context.Background(), AgentOpResponseMessage, message)
+		deadLetters, err := provider.List(organizationID)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(deadLetters).To(gomega.HaveLen(1))
+		gomega.Expect(deadLetters[0].Attempts).To(gomega.Equal(int32(1)))
+	})
+
+	ginkgo.It("should stop retrying when the context is cancelled", func() {
+		failures = 3
+		processor.backoff = time.Hour
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		processor.Process(ctx, AgentOpResponseMessage, message)
+		deadLetters, err := provider.List(organizationID)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(deadLetters).To(gomega.HaveLen(1))
+		gomega.Expect(deadLetters[0].Attempts).To(gomega.Equal(int32(1)))
+	})
+
+	ginkgo.It("should discard the liveness messages", func() {
+		failures = 3
+		processor.Process(context.Background(), AgentsAliveMessage, message)
+		deadLetters, err := provider.List(organizationID)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(deadLetters).To(gomega.BeEmpty())
+	})
+
+	ginkgo.It("should replay a dead letter", func() {
+		failures = 4
+		processor.Process(context.Background(), AgentOpResponseMessage, message)
+		deadLetters, err := provider.List(organizationID)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(deadLetters).To(gomega.HaveLen(1))
+
+		err = processor.Replay(deadLetters[0])
+		gomega.Expect(err).NotTo(gomega.Succeed())
+		updated, err := provider.Get(organizationID, deadLetters[0].DeadLetterId)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(updated.Attempts).To(gomega.Equal(int32(4)))
+
+		err = processor.Replay(updated)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(handled).To(gomega.Equal([]*grpc_inventory_manager_go.AgentOpResponse{message}))
+		_, err = provider.Get(organizationID, deadLetters[0].DeadLetterId)
+		gomega.Expect(err).NotTo(gomega.Succeed())
+	})
+
+	ginkgo.It("should not replay a dead letter while a message with the same key is processed", func() {
+		failures = 3
+		processor.Process(context.Background(), AgentOpResponseMessage, message)
+		deadLetters, err := provider.List(organizationID)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(deadLetters).To(gomega.HaveLen(1))
+
+		// the lock of the key is held while a message is processed
+		lock := &processor.locks[keyHash(message.AssetId)%keyLocks]
+		lock.Lock()
+		replayed := make(chan derrors.Error, 1)
+		go func() {
+			replayed <- processor.Replay(deadLetters[0])
+		}()
+		gomega.Consistently(replayed, 20*time.Millisecond).ShouldNot(gomega.Receive())
+		lock.Unlock()
+		gomega.Eventually(replayed).Should(gomega.Receive(gomega.BeNil()))
+		gomega.Expect(handled).To(gomega.HaveLen(1))
+	})
+
+	ginkgo.It("should replay a dead letter only once when it is replayed concurrently", func() {
+		failures = 3
+		processor.Process(context.Background(), AgentOpResponseMessage, message)
+		deadLetters, err := provider.List(organizationID)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(deadLetters).To(gomega.HaveLen(1))
+
+		lock := &processor.locks[keyHash(message.AssetId)%keyLocks]
+		lock.Lock()
+		replayed := make(chan derrors.Error, 2)
+		for index := 0; index < 2; index++ {
+			go func() {
+				replayed <- processor.Replay(deadLetters[0])
+			}()
+		}
+		gomega.Consistently(replayed, 20*time.Millisecond).ShouldNot(gomega.Receive())
+		lock.Unlock()
+		var first, second derrors.Error
+		gomega.Eventually(replayed).Should(gomega.Receive(&first))
+		gomega.Eventually(replayed).Should(gomega.Receive(&second))
+		gomega.Expect([]derrors.Error{first, second}).To(gomega.ContainElement(gomega.BeNil()))
+		gomega.Expect([]derrors.Error{first, second}).To(gomega.ContainElement(gomega.Not(gomega.BeNil())))
+		gomega.Expect(handled).To(gomega.HaveLen(1))
+	})
+})
@@ -26,6 +26,7 @@ const AuthxContextTimeout = 30 * time.Second
 const VPNContextTimeout = 30 * time.Second
 const SMContextTimeout = 30 * time.Second
 const ProxyContextTimeout = 30 * time.Second
+const NetworkContextTimeout = 30 * time.Second
 
 // AuthxContext generates a new gRPC for authx connections
 func AuthxContext() (context.Context, func()) {
@@ -51,3 +52,8 @@ func SMContext() (context.Context, func()) {
 func ProxyContext() (context.Context, func()) {
 	return context.WithTimeout(context.Background(), ProxyContextTimeout)
 }
+
+// NetworkManagerContext generates a new gRPC context for network manager connections
+func NetworkManagerContext() (context.Context, func()) {
+	return context.WithTimeout(context.Background(), NetworkContextTimeout)
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deadletters
+
+import (
+	"github.com/nalej/grpc-common-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"golang.org/x/net/context"
+)
+
+type Handler struct {
+	manager Manager
+}
+
+func NewHandler(manager Manager) *Handler {
+	return &Handler{
+		manager: manager,
+	}
+}
+
+// ListDeadLetters retrieves the bus messages of an organization that could not be processed.
+func (h *Handler) ListDeadLetters(_ context.Context, query *grpc_inventory_manager_go.DeadLetterQuery) (*grpc_inventory_manager_go.DeadLetterList, error) {
+	vErr := entities.ValidDeadLetterQuery(query)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.ListDeadLetters(query)
+}
+
+// GetDeadLetter retrieves a dead letter.
+func (h *Handler) GetDeadLetter(_ context.Context, deadLetterID *grpc_inventory_manager_go.DeadLetterId) (*grpc_inventory_manager_go.DeadLetter, error) {
+	vErr := entities.ValidDeadLetterId(deadLetterID)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.GetDeadLetter(deadLetterID)
+}
+
+// ReplayDeadLetter processes the message of a dead letter again.
+func (h *Handler) ReplayDeadLetter(_ context.Context, deadLetterID *grpc_inventory_manager_go.DeadLetterId) (*grpc_common_go.Success, error) {
+	vErr := entities.ValidDeadLetterId(deadLetterID)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.ReplayDeadLetter(deadLetterID)
+}
+
+// RemoveDeadLetter discards a dead letter.
+func (h *Handler) RemoveDeadLetter(_ context.Context, deadLetterID *grpc_inventory_manager_go.DeadLetterId) (*grpc_common_go.Success, error) {
+	vErr := entities.ValidDeadLetterId(deadLetterID)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.RemoveDeadLetter(deadLetterID)
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package deadletters
+
+import (
+	"github.com/nalej/grpc-common-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/deadletter"
+	"github.com/nalej/inventory-manager/internal/pkg/server/bus"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultDeadLetterLimit with the number of dead letters returned when the query does not set a limit.
+const DefaultDeadLetterLimit = 1000
+
+// Manager structure with the required providers for dead letter operations.
+type Manager struct {
+	provider  deadletter.Provider
+	processor *bus.Processor
+}
+
+// NewManager creates a Manager using a set of providers.
+func NewManager(provider deadletter.Provider, processor *bus.Processor) Manager {
+	return Manager{
+		provider:  provider,
+		processor: processor,
+	}
+}
+
+// ListDeadLetters retrieves the oldest dead letters of an organization matching a query.
+func (m *Manager) ListDeadLetters(query *grpc_inventory_manager_go.DeadLetterQuery) (*grpc_inventory_manager_go.DeadLetterList, error) {
+	deadLetters, err := m.provider.List(query.OrganizationId)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	limit := int(query.Limit)
+	if limit == 0 {
+		limit = DefaultDeadLetterLimit
+	}
+	result := make([]*grpc_inventory_manager_go.DeadLetter, 0)
+	for _, deadLetter := range deadLetters {
+		if len(result) == limit {
+			break
+		}
+		if (query.Queue == "" || deadLetter.Queue == query.Queue) &&
+			(query.MessageType == "" || deadLetter.MessageType == query.MessageType) {
+			result = append(result, deadLetter)
+		}
+	}
+	return &grpc_inventory_manager_go.DeadLetterList{
+		OrganizationId: query.OrganizationId,
+		DeadLetters:    result,
+	}, nil
+}
+
+// GetDeadLetter retrieves a dead letter including the message that could not be processed.
+func (m *Manager) GetDeadLetter(deadLetterID *grpc_inventory_manager_go.DeadLetterId) (*grpc_inventory_manager_go.DeadLetter, error) {
+	deadLetter, err := m.provider.Get(deadLetterID.OrganizationId, deadLetterID.DeadLetterId)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return deadLetter, nil
+}
+
+// ReplayDeadLetter processes the message of a dead letter again. The dead letter is removed if the message is
+// processed, and kept with the new error otherwise.
+func (m *Manager) ReplayDeadLetter(deadLetterID *grpc_inventory_manager_go.DeadLetterId) (*grpc_common_go.Success, error) {
+	deadLetter, err := m.provider.Get(deadLetterID.OrganizationId, deadLetterID.DeadLetterId)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	err = m.processor.Replay(deadLetter)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	log.Info().Str("organization_id", deadLetter.OrganizationId).Str("dead_letter_id", deadLetter.DeadLetterId).
+		Str("message_type", deadLetter.MessageType).Msg("dead letter replayed")
+	return &grpc_common_go.Success{}, nil
+}
+
+// RemoveDeadLetter discards a dead letter without processing its message.
+func (m *Manager) RemoveDeadLetter(deadLetterID *grpc_inventory_manager_go.DeadLetterId) (*grpc_common_go.Success, error) {
+	err := m.provider.Remove(deadLetterID.OrganizationId, deadLetterID.DeadLetterId)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return &grpc_common_go.Success{}, nil
+}
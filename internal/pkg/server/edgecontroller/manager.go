@@ -36,9 +36,6 @@ import (
 	"time"
 )
 
-// TODO: we have only one proxy, change this when more proxies are added
-const proxy = "proxy0-vpn.service.nalej"
-
 type Manager struct {
 	controllersClient 	grpc_inventory_go.ControllersClient
 	assetsClient  		grpc_inventory_go.AssetsClient
@@ -141,7 +138,7 @@ func (m *Manager) EICJoin(request *grpc_inventory_manager_go.EICJoinRequest) (*g
 		Username:  vpnCredentials.Username,
 		Password:  vpnCredentials.Password,
 		Hostname:  fmt.Sprintf("vpn-server.%s:5555", m.config.ManagementClusterURL),
-		Proxyname: fmt.Sprintf("%s:5544", proxy),
+		Proxyname: fmt.Sprintf("%s:%d", entities.EdgeInventoryProxyName, entities.EdgeInventoryProxyPort),
 	}
 	return &grpc_inventory_manager_go.EICJoinResponse{
 		OrganizationId:   added.OrganizationId,
@@ -158,9 +155,9 @@ func (m *Manager) EICStart(info *grpc_inventory_manager_go.EICStartInfo) error {
 	defer netCancel()
 	addRequest := &grpc_network_go.AddServiceDNSEntryRequest{
 		OrganizationId: info.OrganizationId,
-		Fqdn:           fmt.Sprintf("%s-vpn", info.EdgeControllerId),
+		Fqdn:           entities.GetEdgeControllerFQDN(info.EdgeControllerId),
 		Ip:             info.Ip,
-		Tags:           []string{"EIC"},
+		Tags:           []string{entities.EdgeControllerDNSTag},
 	}
 	log.Debug().Interface("request", addRequest).Msg("registering entry")
 	_, err := m.netMngrClient.AddEntry(netCtx, addRequest)
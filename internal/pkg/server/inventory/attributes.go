@@ -0,0 +1,162 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inventory
+
+import (
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"github.com/nalej/inventory-manager/internal/pkg/server/contexts"
+	"time"
+)
+
+// SetAttributeSchema replaces the schema of the custom attributes of an organization. The values already stored
+// are kept even if their attribute is removed or changes its type.
+func (m *Manager) SetAttributeSchema(schema *grpc_inventory_manager_go.AttributeSchema) (*grpc_inventory_manager_go.AttributeSchema, error) {
+	toAdd := &grpc_inventory_manager_go.AttributeSchema{
+		OrganizationId: schema.OrganizationId,
+		Attributes:     schema.Attributes,
+		Updated:        time.Now().Unix(),
+	}
+	err := m.attributeSchemas.Set(toAdd)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return toAdd, nil
+}
+
+// GetAttributeSchema retrieves the schema of the custom attributes of an organization. An organization without
+// schema has no attributes.
+func (m *Manager) GetAttributeSchema(organizationID *grpc_organization_go.OrganizationId) (*grpc_inventory_manager_go.AttributeSchema, error) {
+	schema, err := m.attributeSchemas.Get(organizationID.OrganizationId)
+	if err != nil {
+		if err.Type() == derrors.NotFound {
+			return &grpc_inventory_manager_go.AttributeSchema{OrganizationId: organizationID.OrganizationId}, nil
+		}
+		return nil, conversions.ToGRPCError(err)
+	}
+	return schema, nil
+}
+
+// UpdateAttributes sets and removes the custom attributes of an entity. The values are validated against the schema
+// of the organization and stored as labels in their canonical form.
+func (m *Manager) UpdateAttributes(request *grpc_inventory_manager_go.UpdateAttributesRequest) (*grpc_inventory_manager_go.EntityAttributes, error) {
+	schema, err := m.GetAttributeSchema(&grpc_organization_go.OrganizationId{OrganizationId: request.OrganizationId})
+	if err != nil {
+		return nil, err
+	}
+	toAdd := make(map[string]string, len(request.Attributes))
+	for name, value := range request.Attributes {
+		label, normalized, err := m.validateAttribute(request.OrganizationId, schema, request.EntityType, name, value)
+		if err != nil {
+			return nil, err
+		}
+		toAdd[label] = normalized
+	}
+
+	item, err := m.getEntity(request.OrganizationId, request.EntityType, request.EntityId)
+	if err != nil {
+		return nil, err
+	}
+	toRemove := make(map[string]string)
+	removedLabels := make([]string, 0, len(request.RemoveAttributes))
+	for _, name := range request.RemoveAttributes {
+		label := entities.AttributeLabel(name)
+		if value, exists := item.labels[label]; exists {
+			toRemove[label] = value
+			removedLabels = append(removedLabels, label)
+		}
+	}
+	if len(toRemove) > 0 {
+		err = m.updateEntityLabels(request.OrganizationId, item, false, toRemove)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(toAdd) > 0 {
+		err = m.updateEntityLabels(request.OrganizationId, item, true, toAdd)
+		if err != nil {
+			return nil, err
+		}
+	}
+	labels, _ := applyLabelChanges(item.labels, toAdd, removedLabels)
+	return &grpc_inventory_manager_go.EntityAttributes{
+		OrganizationId: request.OrganizationId,
+		EntityType:     item.entityType,
+		EntityId:       item.id,
+		Attributes:     entities.AttributesFromLabels(labels),
+	}, nil
+}
+
+// validateAttribute checks the value of an attribute for an entity type and returns the label that stores it with
+// the normalized value. The referenced assets must exist in the organization.
+func (m *Manager) validateAttribute(organizationID string, schema *grpc_inventory_manager_go.AttributeSchema,
+	entityType grpc_inventory_manager_go.InventoryEntityType, name string, value string) (string, string, error) {
+	definition := entities.FindAttribute(schema, name)
+	if definition == nil {
+		return "", "", conversions.ToGRPCError(derrors.NewInvalidArgumentError("unknown attribute").WithParams(name))
+	}
+	if !entities.AttributeAppliesTo(definition, entityType) {
+		return "", "", conversions.ToGRPCError(derrors.NewInvalidArgumentError("attribute cannot be set on this type of entity").
+			WithParams(name, entityTypeNames[entityType]))
+	}
+	normalized, vErr := entities.NormalizeAttributeValue(definition, value)
+	if vErr != nil {
+		return "", "", conversions.ToGRPCError(vErr)
+	}
+	if definition.Type == grpc_inventory_manager_go.AttributeType_ASSET_REFERENCE {
+		_, err := m.GetAssetInfo(&grpc_inventory_go.AssetId{OrganizationId: organizationID, AssetId: normalized})
+		if err != nil {
+			return "", "", err
+		}
+	}
+	return entities.AttributeLabel(name), normalized, nil
+}
+
+// getEntity retrieves an asset, controller or device as an inventory item.
+func (m *Manager) getEntity(organizationID string, entityType grpc_inventory_manager_go.InventoryEntityType, entityID string) (*inventoryItem, error) {
+	list := &grpc_inventory_manager_go.InventoryList{}
+	switch entityType {
+	case grpc_inventory_manager_go.InventoryEntityType_ASSET:
+		asset, err := m.GetAssetInfo(&grpc_inventory_go.AssetId{OrganizationId: organizationID, AssetId: entityID})
+		if err != nil {
+			return nil, err
+		}
+		list.Assets = append(list.Assets, asset)
+	case grpc_inventory_manager_go.InventoryEntityType_CONTROLLER:
+		ctx, cancel := contexts.SMContext()
+		defer cancel()
+		controller, err := m.controllersClient.Get(ctx, &grpc_inventory_go.EdgeControllerId{
+			OrganizationId:   organizationID,
+			EdgeControllerId: entityID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		list.Controllers = append(list.Controllers, m.toController(controller))
+	default:
+		device, err := m.GetDeviceInfo(&grpc_inventory_manager_go.DeviceId{OrganizationId: organizationID, AssetDeviceId: entityID})
+		if err != nil {
+			return nil, err
+		}
+		list.Devices = append(list.Devices, device)
+	}
+	return toInventoryItems(list)[0], nil
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inventory
+
+import (
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/inventory-manager/internal/pkg/export"
+	"github.com/rs/zerolog/log"
+)
+
+// ExportExcludedFields contains the fields that are never exported.
+var ExportExcludedFields = []string{"device_api_key"}
+
+// Export writes the inventory of an organization, or the entities matching a query, in the requested format.
+func (m *Manager) Export(request *grpc_inventory_manager_go.ExportRequest, stream grpc_inventory_manager_go.Inventory_ExportServer) error {
+	var items []*grpc_inventory_manager_go.InventoryItem
+	var sourceErrors []*grpc_inventory_manager_go.InventorySourceError
+	if request.Query != "" {
+		expression, _, qErr := m.ResolveQuery(request.OrganizationId, request.Query, "")
+		if qErr != nil {
+			return conversions.ToGRPCError(qErr)
+		}
+		selected, selectErrors, err := m.Select(request.OrganizationId, expression)
+		if err != nil {
+			return err
+		}
+		items, sourceErrors = selected, selectErrors
+	} else {
+		list, err := m.List(&grpc_organization_go.OrganizationId{OrganizationId: request.OrganizationId})
+		if err != nil {
+			return err
+		}
+		for _, item := range toInventoryItems(list) {
+			items = append(items, item.item)
+		}
+		sourceErrors = list.Errors
+	}
+	// an incomplete export would be taken as the whole inventory
+	if len(sourceErrors) > 0 {
+		return conversions.ToGRPCError(derrors.NewUnavailableError("the inventory cannot be completely retrieved").
+			WithParams(request.OrganizationId, len(sourceErrors)))
+	}
+
+	options := export.DefaultOptions()
+	var fields []string
+	if request.Flatten != nil {
+		if request.Flatten.Separator != "" {
+			options.Separator = request.Flatten.Separator
+		}
+		options.JoinLists = request.Flatten.Lists == grpc_inventory_manager_go.ListFlattening_JOIN
+		options.SingleLabels = request.Flatten.Labels == grpc_inventory_manager_go.LabelFlattening_SINGLE
+		fields = request.Flatten.Fields
+	}
+
+	records := make([]export.Record, 0, len(items))
+	for _, item := range items {
+		record, err := toExportRecord(item, options)
+		if err != nil {
+			return conversions.ToGRPCError(err)
+		}
+		records = append(records, record)
+	}
+
+	writer := export.NewChunkWriter(stream)
+	wErr := export.Write(writer, request.Format, records, export.Columns(records, fields, options.Separator))
+	if wErr != nil {
+		return conversions.ToGRPCError(wErr)
+	}
+	err := writer.Flush()
+	if err != nil {
+		return err
+	}
+	log.Debug().Str("organization_id", request.OrganizationId).Int("records", len(records)).Msg("inventory exported")
+	return nil
+}
+
+// toExportRecord flattens an inventory item adding its type and identifier.
+func toExportRecord(item *grpc_inventory_manager_go.InventoryItem, options export.Options) (export.Record, derrors.Error) {
+	var entity interface{}
+	var id string
+	switch item.Type {
+	case grpc_inventory_manager_go.InventoryEntityType_ASSET:
+		entity, id = item.Asset, item.Asset.AssetId
+	case grpc_inventory_manager_go.InventoryEntityType_CONTROLLER:
+		entity, id = item.Controller, item.Controller.EdgeControllerId
+	default:
+		entity, id = item.Device, item.Device.AssetDeviceId
+	}
+	fields, err := export.Flatten(entity, options)
+	if err != nil {
+		return nil, err
+	}
+	for _, excluded := range ExportExcludedFields {
+		delete(fields, excluded)
+	}
+	record := export.Record(fields)
+	record["type"] = entityTypeNames[item.Type]
+	record["id"] = id
+	return record, nil
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inventory
+
+import (
+	"context"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-vpn-server-go"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"github.com/nalej/inventory-manager/internal/pkg/server/contexts"
+	"net"
+	"sync"
+	"time"
+)
+
+// ProxyCheckTimeout is the maximum time to wait for a connection with the edge inventory proxy.
+const ProxyCheckTimeout = time.Second * 3
+
+// GetControllerExtendedInfo retrieves a controller with its assets, the latest operations, its connectivity and the
+// status and resources of its assets. The VPN and DNS information is returned even if it is incomplete, with the
+// errors found retrieving it.
+func (m *Manager) GetControllerExtendedInfo(edgeControllerID *grpc_inventory_go.EdgeControllerId) (*grpc_inventory_manager_go.EdgeControllerExtendedInfo, error) {
+	controller, assets, err := m.getControllerWithAssets(edgeControllerID)
+	if err != nil {
+		return nil, err
+	}
+	connectivity, sourceErrors := m.getConnectivity(edgeControllerID)
+
+	result := &grpc_inventory_manager_go.EdgeControllerExtendedInfo{
+		Controller:       controller,
+		ManagedAssets:    assets,
+		OperationHistory: m.history.List(edgeControllerID.OrganizationId, edgeControllerID.EdgeControllerId),
+		Connectivity:     connectivity,
+		AssetResources:   &grpc_inventory_manager_go.ResourceTotals{},
+		Errors:           sourceErrors,
+	}
+	for _, item := range toInventoryItems(&grpc_inventory_manager_go.InventoryList{Assets: assets}) {
+		if item.status == grpc_inventory_manager_go.ConnectedStatus_ONLINE {
+			result.NumAssetsOnline++
+		} else {
+			result.NumAssetsOffline++
+		}
+		addResources(result.AssetResources, item.resources())
+	}
+	return result, nil
+}
+
+// getControllerWithAssets retrieves a controller and its assets from the cache or from system-model.
+func (m *Manager) getControllerWithAssets(edgeControllerID *grpc_inventory_go.EdgeControllerId) (*grpc_inventory_manager_go.EdgeController, []*grpc_inventory_manager_go.Asset, error) {
+	if controller, assets, exists := m.cache.GetController(edgeControllerID.OrganizationId, edgeControllerID.EdgeControllerId); exists {
+		return controller, assets, nil
+	}
+	generation := m.cache.Generation(edgeControllerID.OrganizationId)
+
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer cancel()
+	controller, err := m.controllersClient.Get(ctx, edgeControllerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	assetCtx, assetCancel := context.WithTimeout(context.Background(), DefaultTimeout)
+	defer assetCancel()
+	assets, err := m.assetsClient.ListControllerAssets(assetCtx, edgeControllerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	result, resultAssets := m.toController(controller), m.toAssetFromList(assets.Assets)
+	m.cache.SetController(edgeControllerID.OrganizationId, generation, result, resultAssets)
+	return result, resultAssets, nil
+}
+
+// getConnectivity checks the VPN user and the DNS entry of a controller and if the proxy is reachable. These checks
+// are not cached as they must reflect the current state.
+func (m *Manager) getConnectivity(edgeControllerID *grpc_inventory_go.EdgeControllerId) (*grpc_inventory_manager_go.ControllerConnectivity, []*grpc_inventory_manager_go.InventorySourceError) {
+	result := &grpc_inventory_manager_go.ControllerConnectivity{
+		VpnUsername: entities.GetEdgeControllerName(edgeControllerID.OrganizationId, edgeControllerID.EdgeControllerId),
+		Fqdn:        entities.GetEdgeControllerFQDN(edgeControllerID.EdgeControllerId),
+		ProxyName:   entities.EdgeInventoryProxyName,
+	}
+	var vpnErr, dnsErr error
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		result.VpnUserRegistered, vpnErr = m.isVPNUserRegistered(edgeControllerID.OrganizationId, result.VpnUsername)
+	}()
+	go func() {
+		defer wg.Done()
+		result.DnsIp, result.DnsRegistered, dnsErr = m.getDNSEntry(edgeControllerID.OrganizationId, result.Fqdn)
+	}()
+	go func() {
+		defer wg.Done()
+		result.ProxyReachable = m.isProxyReachable()
+	}()
+	wg.Wait()
+
+	sourceErrors := make([]*grpc_inventory_manager_go.InventorySourceError, 0)
+	if vpnErr != nil {
+		sourceErrors = append(sourceErrors, newSourceError(grpc_inventory_manager_go.InventorySource_VPN, result.VpnUsername, vpnErr))
+	}
+	if dnsErr != nil {
+		sourceErrors = append(sourceErrors, newSourceError(grpc_inventory_manager_go.InventorySource_DNS, result.Fqdn, dnsErr))
+	}
+	return result, sourceErrors
+}
+
+// isVPNUserRegistered checks if the VPN server has a user with the given name.
+func (m *Manager) isVPNUserRegistered(organizationID string, username string) (bool, error) {
+	ctx, cancel := contexts.VPNManagerContext()
+	defer cancel()
+	users, err := m.vpnClient.ListVPNUsers(ctx, &grpc_vpn_server_go.GetVPNUserListRequest{
+		OrganizationId: organizationID,
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, user := range users.Usernames {
+		if user == username {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// getDNSEntry returns the IP registered in the DNS for the given FQDN.
+func (m *Manager) getDNSEntry(organizationID string, fqdn string) (string, bool, error) {
+	ctx, cancel := contexts.NetworkManagerContext()
+	defer cancel()
+	entries, err := m.netMngrClient.ListEntries(ctx, &grpc_organization_go.OrganizationId{
+		OrganizationId: organizationID,
+	})
+	if err != nil {
+		return "", false, err
+	}
+	for _, entry := range entries.Entries {
+		if entry.Fqdn == fqdn {
+			return entry.Ip, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// isProxyReachable checks if a connection can be opened with the edge inventory proxy.
+func (m *Manager) isProxyReachable() bool {
+	conn, err := net.DialTimeout("tcp", m.cfg.EdgeInventoryProxyAddress, ProxyCheckTimeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
@@ -17,6 +17,7 @@
 package inventory
 
 import (
+	"github.com/nalej/grpc-common-go"
 	"github.com/nalej/grpc-inventory-go"
 	"github.com/nalej/grpc-inventory-manager-go"
 	"github.com/nalej/grpc-organization-go"
@@ -52,14 +53,7 @@ func (h *Handler) GetControllerExtendedInfo(_ context.Context, edgeControllerID
 	if vErr != nil {
 		return nil, conversions.ToGRPCError(vErr)
 	}
-	controller, assets, err := h.manager.GetControllerExtendedInfo(edgeControllerID)
-	if err != nil {
-		return nil, err
-	}
-	return &grpc_inventory_manager_go.EdgeControllerExtendedInfo{
-		Controller:    controller,
-		ManagedAssets: assets,
-	}, nil
+	return h.manager.GetControllerExtendedInfo(edgeControllerID)
 }
 
 func (h *Handler) GetAssetInfo(_ context.Context, assetID *grpc_inventory_go.AssetId) (*grpc_inventory_manager_go.Asset, error) {
@@ -111,3 +105,120 @@ func (h *Handler) UpdateDevice(ctx context.Context, in *grpc_inventory_manager_g
 
 	return h.manager.UpdateDeviceLocation(in)
 }
+
+// UpdateDeviceInfo adds or removes labels of a device and enables or disables it.
+func (h *Handler) UpdateDeviceInfo(_ context.Context, in *grpc_inventory_manager_go.UpdateDeviceRequest) (*grpc_inventory_manager_go.Device, error) {
+	vErr := entities.ValidUpdateDeviceRequest(in)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.UpdateDevice(in)
+}
+
+// BulkUpdateLabels adds and removes labels of the entities matching a selector.
+func (h *Handler) BulkUpdateLabels(_ context.Context, request *grpc_inventory_manager_go.BulkLabelRequest) (*grpc_inventory_manager_go.BulkLabelResponse, error) {
+	vErr := entities.ValidBulkLabelRequest(request)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.BulkUpdateLabels(request)
+}
+
+// ListPaged returns a page of the inventory of an organization.
+func (h *Handler) ListPaged(_ context.Context, request *grpc_inventory_manager_go.ListInventoryRequest) (*grpc_inventory_manager_go.InventoryPage, error) {
+	vErr := entities.ValidListInventoryRequest(request)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.ListPaged(request)
+}
+
+// Query searches the inventory of an organization using a query or a saved query.
+func (h *Handler) Query(_ context.Context, request *grpc_inventory_manager_go.QueryRequest) (*grpc_inventory_manager_go.QueryResult, error) {
+	vErr := entities.ValidQueryRequest(request)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.Query(request)
+}
+
+// AddSavedQuery stores a query so it can be reused.
+func (h *Handler) AddSavedQuery(_ context.Context, request *grpc_inventory_manager_go.AddSavedQueryRequest) (*grpc_inventory_manager_go.SavedQuery, error) {
+	vErr := entities.ValidAddSavedQueryRequest(request)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.AddSavedQuery(request)
+}
+
+// ListSavedQueries retrieves the saved queries of an organization.
+func (h *Handler) ListSavedQueries(_ context.Context, orgID *grpc_organization_go.OrganizationId) (*grpc_inventory_manager_go.SavedQueryList, error) {
+	vErr := entities.ValidOrganizationID(orgID)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.ListSavedQueries(orgID)
+}
+
+// RemoveSavedQuery removes a saved query.
+func (h *Handler) RemoveSavedQuery(_ context.Context, queryID *grpc_inventory_manager_go.SavedQueryId) (*grpc_common_go.Success, error) {
+	vErr := entities.ValidSavedQueryId(queryID)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.RemoveSavedQuery(queryID)
+}
+
+// SetAttributeSchema replaces the schema of the custom attributes of an organization.
+func (h *Handler) SetAttributeSchema(_ context.Context, schema *grpc_inventory_manager_go.AttributeSchema) (*grpc_inventory_manager_go.AttributeSchema, error) {
+	vErr := entities.ValidAttributeSchema(schema)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.SetAttributeSchema(schema)
+}
+
+// GetAttributeSchema retrieves the schema of the custom attributes of an organization.
+func (h *Handler) GetAttributeSchema(_ context.Context, orgID *grpc_organization_go.OrganizationId) (*grpc_inventory_manager_go.AttributeSchema, error) {
+	vErr := entities.ValidOrganizationID(orgID)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.GetAttributeSchema(orgID)
+}
+
+// UpdateAttributes sets and removes the custom attributes of an asset, controller or device.
+func (h *Handler) UpdateAttributes(_ context.Context, request *grpc_inventory_manager_go.UpdateAttributesRequest) (*grpc_inventory_manager_go.EntityAttributes, error) {
+	vErr := entities.ValidUpdateAttributesRequest(request)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.UpdateAttributes(request)
+}
+
+// Export streams the inventory of an organization as CSV, JSON Lines or YAML.
+func (h *Handler) Export(request *grpc_inventory_manager_go.ExportRequest, stream grpc_inventory_manager_go.Inventory_ExportServer) error {
+	vErr := entities.ValidExportRequest(request)
+	if vErr != nil {
+		return conversions.ToGRPCError(vErr)
+	}
+	return h.manager.Export(request, stream)
+}
+
+// ImportAssets registers unmanaged assets from a CSV or JSON file.
+func (h *Handler) ImportAssets(_ context.Context, request *grpc_inventory_manager_go.ImportAssetsRequest) (*grpc_inventory_manager_go.ImportReport, error) {
+	vErr := entities.ValidImportAssetsRequest(request)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.ImportAssets(request)
+}
+
+// Watch streams the changes of the inventory of an organization.
+func (h *Handler) Watch(request *grpc_inventory_manager_go.WatchRequest, stream grpc_inventory_manager_go.Inventory_WatchServer) error {
+	vErr := entities.ValidWatchRequest(request)
+	if vErr != nil {
+		return conversions.ToGRPCError(vErr)
+	}
+	return h.manager.Watch(request, stream)
+}
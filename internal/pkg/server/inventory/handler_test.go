@@ -15,3 +15,83 @@
  */
 
 package inventory
+
+import (
+	"context"
+	"github.com/nalej/grpc-device-manager-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Device updates", func() {
+
+	var devices *fakeDevices
+	var handler *Handler
+	deviceID := entities.GetAssetDeviceID("group", "device")
+
+	ginkgo.BeforeEach(func() {
+		devices = &fakeDevices{}
+		handler = NewHandler(newTestManager(devices, &fakeAssets{}, &fakeControllers{}))
+	})
+
+	ginkgo.It("should pass the labels to add to the device manager", func() {
+		device, err := handler.UpdateDeviceInfo(context.Background(), &grpc_inventory_manager_go.UpdateDeviceRequest{
+			OrganizationId: "org",
+			AssetDeviceId:  deviceID,
+			AddLabels:      true,
+			Labels:         map[string]string{"env": "prod"},
+		})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(device.AssetDeviceId).To(gomega.Equal(deviceID))
+		gomega.Expect(devices.updates).To(gomega.Equal([]*grpc_device_manager_go.UpdateDeviceRequest{{
+			OrganizationId: "org",
+			DeviceGroupId:  "group",
+			DeviceId:       "device",
+			AddLabels:      true,
+			Labels:         map[string]string{"env": "prod"},
+		}}))
+	})
+
+	ginkgo.It("should pass the labels to remove to the device manager", func() {
+		_, err := handler.UpdateDeviceInfo(context.Background(), &grpc_inventory_manager_go.UpdateDeviceRequest{
+			OrganizationId: "org",
+			AssetDeviceId:  deviceID,
+			RemoveLabels:   true,
+			Labels:         map[string]string{"env": "prod"},
+		})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(devices.updates).To(gomega.HaveLen(1))
+		gomega.Expect(devices.updates[0].RemoveLabels).To(gomega.BeTrue())
+		gomega.Expect(devices.updates[0].AddLabels).To(gomega.BeFalse())
+	})
+
+	ginkgo.It("should pass the enabled flag to the device manager", func() {
+		for _, enabled := range []bool{false, true} {
+			device, err := handler.UpdateDeviceInfo(context.Background(), &grpc_inventory_manager_go.UpdateDeviceRequest{
+				OrganizationId: "org",
+				AssetDeviceId:  deviceID,
+				UpdateEnabled:  true,
+				Enabled:        enabled,
+			})
+			gomega.Expect(err).To(gomega.Succeed())
+			gomega.Expect(device.Enabled).To(gomega.Equal(enabled))
+		}
+		gomega.Expect(devices.updates).To(gomega.HaveLen(2))
+		gomega.Expect(devices.updates[0].UpdateEnabled).To(gomega.BeTrue())
+		gomega.Expect(devices.updates[0].Enabled).To(gomega.BeFalse())
+		gomega.Expect(devices.updates[1].Enabled).To(gomega.BeTrue())
+	})
+
+	ginkgo.It("should reject the reserved labels", func() {
+		for _, request := range []*grpc_inventory_manager_go.UpdateDeviceRequest{
+			{OrganizationId: "org", AssetDeviceId: deviceID, AddLabels: true, Labels: map[string]string{entities.QuarantineLabel: "true"}},
+			{OrganizationId: "org", AssetDeviceId: deviceID, RemoveLabels: true, Labels: map[string]string{entities.RetentionFlaggedLabel: "1"}},
+		} {
+			_, err := handler.UpdateDeviceInfo(context.Background(), request)
+			gomega.Expect(err).NotTo(gomega.Succeed())
+		}
+		gomega.Expect(devices.updates).To(gomega.BeEmpty())
+	})
+})
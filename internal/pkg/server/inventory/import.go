@@ -0,0 +1,364 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inventory
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"github.com/nalej/inventory-manager/internal/pkg/export"
+	"github.com/nalej/inventory-manager/internal/pkg/server/contexts"
+	"github.com/rs/zerolog/log"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxImportRows with the maximum number of assets in an import.
+const MaxImportRows = 10000
+
+// The imported columns use the same names as the export so an exported CSV file can be edited and imported.
+var importColumns = map[string]bool{
+	"edge_controller_id": true, "location.geolocation": true, "location.geohash": true,
+	"os.name": true, "os.version": true, "os.class": true, "os.architecture": true,
+	"hardware.installed_ram": true,
+}
+
+var cpuColumn = regexp.MustCompile(`^hardware\.cpus\.(\d+)\.(manufacturer|model|architecture|num_cores)$`)
+var storageColumn = regexp.MustCompile(`^storage\.(\d+)\.(type|total_capacity)$`)
+
+// The columns written by the export that are computed by the inventory or that describe the exported entity. They are
+// ignored on import as the imported rows are registered as new unmanaged assets.
+var exportOnlyColumns = map[string]bool{
+	"type": true, "id": true, "organization_id": true, "asset_id": true, "agent_id": true, "show": true,
+	"created": true, "eic_net_ip": true, "last_alive_timestamp": true, "status": true, "state": true,
+	"quarantined": true, "unmanaged": true,
+}
+
+var exportOnlyPrefixes = []string{"last_op_summary.", "resources.", "attributes.", "hardware.net_interfaces."}
+
+const labelColumnPrefix = export.LabelsField + export.DefaultSeparator
+
+// importRow is a row read from the imported file, or the error found reading it.
+type importRow struct {
+	record export.Record
+	err    derrors.Error
+}
+
+func exportOnlyColumn(column string) bool {
+	if exportOnlyColumns[column] {
+		return true
+	}
+	for _, prefix := range exportOnlyPrefixes {
+		if strings.HasPrefix(column, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func validImportColumn(column string) bool {
+	return importColumns[column] || exportOnlyColumn(column) || cpuColumn.MatchString(column) || storageColumn.MatchString(column) ||
+		(strings.HasPrefix(column, labelColumnPrefix) && len(column) > len(labelColumnPrefix))
+}
+
+// ImportAssets registers assets without an agent, such as printers or network devices. Each row is validated and
+// imported independently and the result of all of them is returned in the report.
+func (m *Manager) ImportAssets(request *grpc_inventory_manager_go.ImportAssetsRequest) (*grpc_inventory_manager_go.ImportReport, error) {
+	var rows []importRow
+	var rErr derrors.Error
+	switch request.Format {
+	case grpc_inventory_manager_go.ImportFormat_CSV:
+		rows, rErr = readCSVRecords(request.Data)
+	case grpc_inventory_manager_go.ImportFormat_JSON:
+		rows, rErr = readJSONRecords(request.Data)
+	default:
+		rErr = derrors.NewInvalidArgumentError("unsupported import format").WithParams(request.Format)
+	}
+	if rErr != nil {
+		return nil, conversions.ToGRPCError(rErr)
+	}
+	if len(rows) > MaxImportRows {
+		return nil, conversions.ToGRPCError(derrors.NewInvalidArgumentError("too many rows to import").WithParams(len(rows), MaxImportRows))
+	}
+
+	ctx, cancel := contexts.SMContext()
+	controllers, err := m.controllersClient.List(ctx, &grpc_organization_go.OrganizationId{OrganizationId: request.OrganizationId})
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	existingControllers := make(map[string]bool, len(controllers.Controllers))
+	for _, ec := range controllers.Controllers {
+		existingControllers[ec.EdgeControllerId] = true
+	}
+
+	report := &grpc_inventory_manager_go.ImportReport{
+		OrganizationId: request.OrganizationId,
+		DryRun:         request.DryRun,
+		Total:          int32(len(rows)),
+		Rows:           make([]*grpc_inventory_manager_go.ImportRowResult, 0, len(rows)),
+	}
+	imported := time.Now()
+	for index, row := range rows {
+		result := &grpc_inventory_manager_go.ImportRowResult{Row: int32(index + 1)}
+		var addRequest *grpc_inventory_go.AddAssetRequest
+		pErr := row.err
+		if pErr == nil {
+			addRequest, pErr = toAddAssetRequest(request.OrganizationId, row.record, imported)
+		}
+		if pErr == nil && !existingControllers[addRequest.EdgeControllerId] {
+			pErr = derrors.NewNotFoundError("edge controller").WithParams(addRequest.EdgeControllerId)
+		}
+		if pErr == nil && !request.DryRun {
+			pErr = m.addImportedAsset(addRequest, result)
+		}
+		if pErr != nil {
+			result.Error = pErr.Error()
+			report.Failed++
+		} else {
+			result.Success = true
+			report.Imported++
+		}
+		report.Rows = append(report.Rows, result)
+	}
+	log.Info().Str("organization_id", request.OrganizationId).Bool("dry_run", request.DryRun).Int32("imported", report.Imported).
+		Int32("failed", report.Failed).Msg("unmanaged assets imported")
+	return report, nil
+}
+
+func (m *Manager) addImportedAsset(addRequest *grpc_inventory_go.AddAssetRequest, result *grpc_inventory_manager_go.ImportRowResult) derrors.Error {
+	ctx, cancel := contexts.SMContext()
+	defer cancel()
+	asset, err := m.assetsClient.Add(ctx, addRequest)
+	if err != nil {
+		return conversions.ToDerror(err)
+	}
+	result.AssetId = asset.AssetId
+	return nil
+}
+
+// readCSVRecords reads a CSV file whose first line contains the names of the columns.
+func readCSVRecords(data []byte) ([]importRow, derrors.Error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	header, err := reader.Read()
+	if err != nil {
+		return nil, derrors.NewInvalidArgumentError("cannot read CSV header", err)
+	}
+	for _, column := range header {
+		if !validImportColumn(column) {
+			return nil, derrors.NewInvalidArgumentError(fmt.Sprintf("unknown column %s", column))
+		}
+	}
+	// the number of fields is checked on each row so it is reported as an error of the row
+	reader.FieldsPerRecord = -1
+	result := make([]importRow, 0)
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, derrors.NewInvalidArgumentError("cannot read CSV", err)
+		}
+		if len(row) != len(header) {
+			result = append(result, importRow{err: derrors.NewInvalidArgumentError("wrong number of fields").WithParams(len(row), len(header))})
+			continue
+		}
+		record := make(export.Record, len(header))
+		for index, column := range header {
+			if row[index] != "" {
+				record[column] = row[index]
+			}
+		}
+		result = append(result, importRow{record: record})
+	}
+	return result, nil
+}
+
+// readJSONRecords reads a JSON array of objects. Nested objects are flattened to the same columns as the CSV.
+func readJSONRecords(data []byte) ([]importRow, derrors.Error) {
+	objects := make([]interface{}, 0)
+	err := json.Unmarshal(data, &objects)
+	if err != nil {
+		return nil, derrors.NewInvalidArgumentError("cannot read JSON, expecting an array of objects", err)
+	}
+	result := make([]importRow, 0, len(objects))
+	for _, object := range objects {
+		if _, isObject := object.(map[string]interface{}); !isObject {
+			result = append(result, importRow{err: derrors.NewInvalidArgumentError("expecting an object")})
+			continue
+		}
+		fields, fErr := export.Flatten(object, export.DefaultOptions())
+		if fErr != nil {
+			result = append(result, importRow{err: fErr})
+			continue
+		}
+		row := importRow{record: export.Record(fields)}
+		for column := range fields {
+			if !validImportColumn(column) {
+				row.err = derrors.NewInvalidArgumentError(fmt.Sprintf("unknown field %s", column))
+				break
+			}
+		}
+		result = append(result, row)
+	}
+	return result, nil
+}
+
+// toAddAssetRequest validates a record and converts it into the request to add the asset.
+func toAddAssetRequest(organizationID string, record export.Record, imported time.Time) (*grpc_inventory_go.AddAssetRequest, derrors.Error) {
+	request := &grpc_inventory_go.AddAssetRequest{
+		OrganizationId:   organizationID,
+		EdgeControllerId: record["edge_controller_id"],
+		Labels: map[string]string{
+			entities.UnmanagedLabel: strconv.FormatInt(imported.Unix(), 10),
+		},
+	}
+	if entityType, exists := record["type"]; exists && entityType != entityTypeNames[grpc_inventory_manager_go.InventoryEntityType_ASSET] {
+		return nil, derrors.NewInvalidArgumentError("only assets can be imported").WithParams(entityType)
+	}
+	if _, vErr := entities.ParseControllerIdentifier(request.EdgeControllerId); vErr != nil {
+		return nil, vErr
+	}
+
+	cpus := make(map[int]*grpc_inventory_go.CPUInfo)
+	storage := make(map[int]*grpc_inventory_go.StorageHardwareInfo)
+	for column, value := range record {
+		switch {
+		case strings.HasPrefix(column, labelColumnPrefix):
+			key := strings.TrimPrefix(column, labelColumnPrefix)
+			// the reserved labels of an exported asset describe its state and do not apply to the imported one
+			if strings.HasPrefix(key, entities.ReservedLabelPrefix) && !entities.UserDeclaredLabels[key] {
+				continue
+			}
+			request.Labels[key] = value
+		case strings.HasPrefix(column, "location."):
+			if request.Location == nil {
+				request.Location = &grpc_inventory_go.InventoryLocation{}
+			}
+			if column == "location.geolocation" {
+				request.Location.Geolocation = value
+			} else {
+				request.Location.Geohash = value
+			}
+		case strings.HasPrefix(column, "os."):
+			if request.Os == nil {
+				request.Os = &grpc_inventory_go.OperatingSystemInfo{}
+			}
+			switch column {
+			case "os.name":
+				request.Os.Name = value
+			case "os.version":
+				request.Os.Version = value
+			case "os.architecture":
+				request.Os.Architecture = value
+			case "os.class":
+				class, exists := grpc_inventory_go.OperatingSystemClass_value[strings.ToUpper(value)]
+				if !exists {
+					return nil, derrors.NewInvalidArgumentError("invalid os.class").WithParams(value)
+				}
+				request.Os.Class = grpc_inventory_go.OperatingSystemClass(class)
+			}
+		case column == "hardware.installed_ram":
+			ram, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || ram < 0 {
+				return nil, derrors.NewInvalidArgumentError("invalid hardware.installed_ram").WithParams(value)
+			}
+			if request.Hardware == nil {
+				request.Hardware = &grpc_inventory_go.HardwareInfo{}
+			}
+			request.Hardware.InstalledRam = ram
+		case cpuColumn.MatchString(column):
+			match := cpuColumn.FindStringSubmatch(column)
+			index, _ := strconv.Atoi(match[1])
+			cpu, exists := cpus[index]
+			if !exists {
+				cpu = &grpc_inventory_go.CPUInfo{}
+				cpus[index] = cpu
+			}
+			switch match[2] {
+			case "manufacturer":
+				cpu.Manufacturer = value
+			case "model":
+				cpu.Model = value
+			case "architecture":
+				cpu.Architecture = value
+			case "num_cores":
+				cores, err := strconv.ParseInt(value, 10, 32)
+				if err != nil || cores < 0 {
+					return nil, derrors.NewInvalidArgumentError(fmt.Sprintf("invalid %s", column)).WithParams(value)
+				}
+				cpu.NumCores = int32(cores)
+			}
+		case storageColumn.MatchString(column):
+			match := storageColumn.FindStringSubmatch(column)
+			index, _ := strconv.Atoi(match[1])
+			disk, exists := storage[index]
+			if !exists {
+				disk = &grpc_inventory_go.StorageHardwareInfo{}
+				storage[index] = disk
+			}
+			if match[2] == "type" {
+				disk.Type = value
+			} else {
+				capacity, err := strconv.ParseInt(value, 10, 64)
+				if err != nil || capacity < 0 {
+					return nil, derrors.NewInvalidArgumentError(fmt.Sprintf("invalid %s", column)).WithParams(value)
+				}
+				disk.TotalCapacity = capacity
+			}
+		}
+	}
+
+	if len(cpus) > 0 {
+		if request.Hardware == nil {
+			request.Hardware = &grpc_inventory_go.HardwareInfo{}
+		}
+		indexes := make([]int, 0, len(cpus))
+		for index := range cpus {
+			indexes = append(indexes, index)
+		}
+		sort.Ints(indexes)
+		request.Hardware.Cpus = make([]*grpc_inventory_go.CPUInfo, 0, len(cpus))
+		for _, index := range indexes {
+			request.Hardware.Cpus = append(request.Hardware.Cpus, cpus[index])
+		}
+	}
+	if len(storage) > 0 {
+		indexes := make([]int, 0, len(storage))
+		for index := range storage {
+			indexes = append(indexes, index)
+		}
+		sort.Ints(indexes)
+		request.Storage = make([]*grpc_inventory_go.StorageHardwareInfo, 0, len(storage))
+		for _, index := range indexes {
+			request.Storage = append(request.Storage, storage[index])
+		}
+	}
+	return request, nil
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inventory
+
+import (
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"github.com/nalej/inventory-manager/internal/pkg/export"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"time"
+)
+
+var _ = ginkgo.Describe("Asset import", func() {
+
+	imported := time.Unix(1560000000, 0)
+
+	ginkgo.Context("reading CSV", func() {
+		ginkgo.It("should read a record per row skipping the empty values", func() {
+			data := "edge_controller_id,os.name,labels.env\nec1,linux,prod\nec2,,\n"
+			rows, err := readCSVRecords([]byte(data))
+			gomega.Expect(err).To(gomega.Succeed())
+			gomega.Expect(rows).To(gomega.HaveLen(2))
+			gomega.Expect(rows[0].err).To(gomega.BeNil())
+			gomega.Expect(rows[0].record).To(gomega.Equal(export.Record{"edge_controller_id": "ec1", "os.name": "linux", "labels.env": "prod"}))
+			gomega.Expect(rows[1].record).To(gomega.Equal(export.Record{"edge_controller_id": "ec2"}))
+		})
+
+		ginkgo.It("should report the rows with a wrong number of fields", func() {
+			rows, err := readCSVRecords([]byte("edge_controller_id,os.name\nec1,linux\nec2\n"))
+			gomega.Expect(err).To(gomega.Succeed())
+			gomega.Expect(rows).To(gomega.HaveLen(2))
+			gomega.Expect(rows[0].err).To(gomega.BeNil())
+			gomega.Expect(rows[1].err).NotTo(gomega.BeNil())
+		})
+
+		ginkgo.It("should reject the unknown columns", func() {
+			_, err := readCSVRecords([]byte("edge_controller_id,color\nec1,red\n"))
+			gomega.Expect(err).NotTo(gomega.Succeed())
+			_, err = readCSVRecords([]byte("edge_controller_id,labels.\nec1,red\n"))
+			gomega.Expect(err).NotTo(gomega.Succeed())
+		})
+
+		ginkgo.It("should accept the columns of an exported file", func() {
+			data := "type,id,asset_id,status,state,created,edge_controller_id,hardware.net_interfaces.0.type,last_op_summary.status,labels.nalej-unmanaged,os.class\n" +
+				"asset,a1,a1,OFFLINE,ACTIVE,1550000000,ec1,ethernet,SUCCESS,1550000000,LINUX\n"
+			rows, err := readCSVRecords([]byte(data))
+			gomega.Expect(err).To(gomega.Succeed())
+			gomega.Expect(rows).To(gomega.HaveLen(1))
+			request, rErr := toAddAssetRequest("org", rows[0].record, imported)
+			gomega.Expect(rErr).To(gomega.Succeed())
+			gomega.Expect(request.EdgeControllerId).To(gomega.Equal("ec1"))
+			gomega.Expect(request.Labels).To(gomega.Equal(map[string]string{entities.UnmanagedLabel: "1560000000"}))
+			gomega.Expect(request.Os.Class).To(gomega.Equal(grpc_inventory_go.OperatingSystemClass_LINUX))
+			gomega.Expect(request.Hardware).To(gomega.BeNil())
+		})
+	})
+
+	ginkgo.Context("reading JSON", func() {
+		ginkgo.It("should flatten the nested objects", func() {
+			data := `[{"edge_controller_id": "ec1", "os": {"name": "linux"}, "hardware": {"cpus": [{"num_cores": 4}]}}]`
+			rows, err := readJSONRecords([]byte(data))
+			gomega.Expect(err).To(gomega.Succeed())
+			gomega.Expect(rows).To(gomega.HaveLen(1))
+			gomega.Expect(rows[0].err).To(gomega.BeNil())
+			gomega.Expect(rows[0].record).To(gomega.Equal(export.Record{
+				"edge_controller_id": "ec1", "os.name": "linux", "hardware.cpus.0.num_cores": "4"}))
+		})
+
+		ginkgo.It("should report the invalid rows", func() {
+			rows, err := readJSONRecords([]byte(`[{"edge_controller_id": "ec1", "color": "red"}, "text", {"edge_controller_id": "ec2"}]`))
+			gomega.Expect(err).To(gomega.Succeed())
+			gomega.Expect(rows).To(gomega.HaveLen(3))
+			gomega.Expect(rows[0].err).NotTo(gomega.BeNil())
+			gomega.Expect(rows[1].err).NotTo(gomega.BeNil())
+			gomega.Expect(rows[2].err).To(gomega.BeNil())
+		})
+
+		ginkgo.It("should reject the documents that are not an array", func() {
+			_, err := readJSONRecords([]byte(`{"edge_controller_id": "ec1"}`))
+			gomega.Expect(err).NotTo(gomega.Succeed())
+		})
+	})
+
+	ginkgo.Context("converting the records", func() {
+		ginkgo.It("should build the request of an unmanaged asset", func() {
+			record := export.Record{
+				"edge_controller_id":              "ec1",
+				"labels.floor":                    "2",
+				"labels." + entities.RAMUnitLabel: "KB",
+				"location.geolocation":            "Madrid",
+				"os.class":                        "windows",
+				"hardware.installed_ram":          "2048",
+				"hardware.cpus.1.num_cores":       "2",
+				"hardware.cpus.0.num_cores":       "4",
+				"storage.0.total_capacity":        "1024",
+				"storage.0.type":                  "ssd",
+			}
+			request, err := toAddAssetRequest("org", record, imported)
+			gomega.Expect(err).To(gomega.Succeed())
+			gomega.Expect(request.OrganizationId).To(gomega.Equal("org"))
+			gomega.Expect(request.Labels).To(gomega.Equal(map[string]string{
+				"floor": "2", entities.RAMUnitLabel: "KB", entities.UnmanagedLabel: "1560000000"}))
+			gomega.Expect(request.Location.Geolocation).To(gomega.Equal("Madrid"))
+			gomega.Expect(request.Os.Class).To(gomega.Equal(grpc_inventory_go.OperatingSystemClass_WINDOWS))
+			gomega.Expect(request.Hardware.InstalledRam).To(gomega.Equal(int64(2048)))
+			gomega.Expect(request.Hardware.Cpus).To(gomega.HaveLen(2))
+			gomega.Expect(request.Hardware.Cpus[0].NumCores).To(gomega.Equal(int32(4)))
+			gomega.Expect(request.Hardware.Cpus[1].NumCores).To(gomega.Equal(int32(2)))
+			gomega.Expect(request.Storage).To(gomega.HaveLen(1))
+			gomega.Expect(request.Storage[0].Type).To(gomega.Equal("ssd"))
+			gomega.Expect(request.Storage[0].TotalCapacity).To(gomega.Equal(int64(1024)))
+		})
+
+		ginkgo.It("should reject the invalid values", func() {
+			invalid := []export.Record{
+				{"os.name": "linux"},
+				{"edge_controller_id": "ec1", "type": "device"},
+				{"edge_controller_id": "ec1", "os.class": "beos"},
+				{"edge_controller_id": "ec1", "hardware.installed_ram": "-1"},
+				{"edge_controller_id": "ec1", "hardware.cpus.0.num_cores": "many"},
+				{"edge_controller_id": "ec1", "storage.0.total_capacity": "1.5"},
+			}
+			for _, record := range invalid {
+				_, err := toAddAssetRequest("org", record, imported)
+				gomega.Expect(err).NotTo(gomega.Succeed(), "record %v", record)
+			}
+		})
+	})
+})
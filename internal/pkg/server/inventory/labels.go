@@ -0,0 +1,227 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inventory
+
+import (
+	"github.com/nalej/grpc-device-manager-go"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"github.com/nalej/inventory-manager/internal/pkg/query"
+	"github.com/nalej/inventory-manager/internal/pkg/server/contexts"
+	"github.com/rs/zerolog/log"
+	"sync"
+)
+
+// entitySelector matches the inventory items selected by all the criteria of a grpc_inventory_manager_go.EntitySelector.
+type entitySelector struct {
+	ids              map[string]bool
+	labels           map[string]string
+	edgeControllerID string
+	expression       query.Expression
+}
+
+// newEntitySelector creates a selector resolving its query. The device identifiers are normalized so the legacy
+// format is also accepted.
+func (m *Manager) newEntitySelector(organizationID string, selector *grpc_inventory_manager_go.EntitySelector) (*entitySelector, error) {
+	result := &entitySelector{
+		labels:           selector.Labels,
+		edgeControllerID: selector.EdgeControllerId,
+	}
+	if len(selector.Ids) > 0 {
+		result.ids = make(map[string]bool, len(selector.Ids))
+		for _, id := range selector.Ids {
+			result.ids[id] = true
+			if deviceID, err := entities.ParseDeviceIdentifier(id); err == nil {
+				result.ids[deviceID.String()] = true
+			}
+		}
+	}
+	if selector.Query != "" || selector.QueryId != "" {
+		expression, _, qErr := m.ResolveQuery(organizationID, selector.Query, selector.QueryId)
+		if qErr != nil {
+			return nil, conversions.ToGRPCError(qErr)
+		}
+		result.expression = expression
+	}
+	return result, nil
+}
+
+// matches checks if an item is selected. The controller criterion selects the controller and its assets.
+func (s *entitySelector) matches(item *inventoryItem) bool {
+	if s.ids != nil && !s.ids[item.id] {
+		return false
+	}
+	for key, value := range s.labels {
+		if current, exists := item.labels[key]; !exists || current != value {
+			return false
+		}
+	}
+	if s.edgeControllerID != "" && item.controllerID != s.edgeControllerID {
+		return false
+	}
+	if s.expression != nil && !s.expression.Matches(item) {
+		return false
+	}
+	return true
+}
+
+// applyLabelChanges returns the labels resulting of removing and adding the given labels, and if they differ
+// from the current ones.
+func applyLabelChanges(current map[string]string, add map[string]string, remove []string) (map[string]string, bool) {
+	result := make(map[string]string, len(current)+len(add))
+	for key, value := range current {
+		result[key] = value
+	}
+	changed := false
+	for _, key := range remove {
+		if _, exists := result[key]; exists {
+			delete(result, key)
+			changed = true
+		}
+	}
+	for key, value := range add {
+		if current, exists := result[key]; !exists || current != value {
+			result[key] = value
+			changed = true
+		}
+	}
+	return result, changed
+}
+
+// BulkUpdateLabels adds and removes labels of the assets, controllers and devices selected by a request. The
+// entities whose labels would not change are not updated. With dry run the selection and the resulting labels are
+// reported without modifying the entities.
+func (m *Manager) BulkUpdateLabels(request *grpc_inventory_manager_go.BulkLabelRequest) (*grpc_inventory_manager_go.BulkLabelResponse, error) {
+	selector, err := m.newEntitySelector(request.OrganizationId, request.Selector)
+	if err != nil {
+		return nil, err
+	}
+	list, err := m.List(&grpc_organization_go.OrganizationId{OrganizationId: request.OrganizationId})
+	if err != nil {
+		return nil, err
+	}
+	selected := make([]*inventoryItem, 0)
+	for _, item := range toInventoryItems(list) {
+		if selector.matches(item) {
+			selected = append(selected, item)
+		}
+	}
+
+	results := make([]*grpc_inventory_manager_go.BulkLabelResult, len(selected))
+	semaphore := make(chan struct{}, m.cfg.InventoryParallelism)
+	var wg sync.WaitGroup
+	for index, item := range selected {
+		labels, changed := applyLabelChanges(item.labels, request.AddLabels, request.RemoveLabels)
+		results[index] = &grpc_inventory_manager_go.BulkLabelResult{
+			EntityType: item.entityType,
+			EntityId:   item.id,
+			Changed:    changed,
+			Success:    true,
+			Labels:     labels,
+		}
+		if !changed || request.DryRun {
+			continue
+		}
+		wg.Add(1)
+		go func(item *inventoryItem, result *grpc_inventory_manager_go.BulkLabelResult) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			if err := m.updateLabels(request.OrganizationId, item, request.AddLabels, request.RemoveLabels); err != nil {
+				log.Warn().Str("organization_id", request.OrganizationId).Str("entity_id", item.id).
+					Str("trace", conversions.ToDerror(err).DebugReport()).Msg("cannot update labels")
+				result.Success = false
+				result.Error = conversions.ToDerror(err).Error()
+				result.Labels = item.labels
+			}
+		}(item, results[index])
+	}
+	wg.Wait()
+
+	response := &grpc_inventory_manager_go.BulkLabelResponse{
+		OrganizationId: request.OrganizationId,
+		DryRun:         request.DryRun,
+		NumSelected:    int32(len(results)),
+		Results:        results,
+		Errors:         list.Errors,
+	}
+	for _, result := range results {
+		if !result.Success {
+			response.NumFailed++
+		} else if result.Changed {
+			response.NumChanged++
+		}
+	}
+	return response, nil
+}
+
+// updateLabels removes and adds labels of an entity in system-model or the device manager.
+func (m *Manager) updateLabels(organizationID string, item *inventoryItem, add map[string]string, remove []string) error {
+	toRemove := make(map[string]string, 0)
+	for _, key := range remove {
+		if value, exists := item.labels[key]; exists {
+			toRemove[key] = value
+		}
+	}
+	if len(toRemove) > 0 {
+		if err := m.updateEntityLabels(organizationID, item, false, toRemove); err != nil {
+			return err
+		}
+	}
+	if len(add) > 0 {
+		return m.updateEntityLabels(organizationID, item, true, add)
+	}
+	return nil
+}
+
+func (m *Manager) updateEntityLabels(organizationID string, item *inventoryItem, add bool, labels map[string]string) error {
+	ctx, cancel := contexts.SMContext()
+	defer cancel()
+	var err error
+	switch item.entityType {
+	case grpc_inventory_manager_go.InventoryEntityType_ASSET:
+		_, err = m.assetsClient.Update(ctx, &grpc_inventory_go.UpdateAssetRequest{
+			OrganizationId: organizationID,
+			AssetId:        item.id,
+			AddLabels:      add,
+			RemoveLabels:   !add,
+			Labels:         labels,
+		})
+	case grpc_inventory_manager_go.InventoryEntityType_CONTROLLER:
+		_, err = m.controllersClient.Update(ctx, &grpc_inventory_go.UpdateEdgeControllerRequest{
+			OrganizationId:   organizationID,
+			EdgeControllerId: item.id,
+			AddLabels:        add,
+			RemoveLabels:     !add,
+			Labels:           labels,
+		})
+	case grpc_inventory_manager_go.InventoryEntityType_DEVICE:
+		device := item.item.Device
+		_, err = m.deviceManagerClient.UpdateDevice(ctx, &grpc_device_manager_go.UpdateDeviceRequest{
+			OrganizationId: organizationID,
+			DeviceGroupId:  device.DeviceGroupId,
+			DeviceId:       device.DeviceId,
+			AddLabels:      add,
+			RemoveLabels:   !add,
+			Labels:         labels,
+		})
+	}
+	return err
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inventory
+
+import (
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Bulk labels", func() {
+
+	ginkgo.It("should compute the resulting labels", func() {
+		current := map[string]string{"env": "dev", "team": "a"}
+		labels, changed := applyLabelChanges(current, map[string]string{"env": "prod", "site": "b"}, []string{"team", "missing"})
+		gomega.Expect(changed).To(gomega.BeTrue())
+		gomega.Expect(labels).To(gomega.Equal(map[string]string{"env": "prod", "site": "b"}))
+		gomega.Expect(current).To(gomega.HaveLen(2))
+
+		_, changed = applyLabelChanges(current, map[string]string{"env": "dev"}, []string{"missing"})
+		gomega.Expect(changed).To(gomega.BeFalse())
+	})
+
+	ginkgo.It("should select the items matching all the criteria", func() {
+		list := &grpc_inventory_manager_go.InventoryList{
+			Assets: []*grpc_inventory_manager_go.Asset{
+				{AssetId: "a1", EdgeControllerId: "ec1", Labels: map[string]string{"env": "dev"}},
+				{AssetId: "a2", EdgeControllerId: "ec1", Labels: map[string]string{"env": "prod"}},
+				{AssetId: "a3", EdgeControllerId: "ec2", Labels: map[string]string{"env": "dev"}},
+			},
+			Controllers: []*grpc_inventory_manager_go.EdgeController{
+				{EdgeControllerId: "ec1", Labels: map[string]string{"env": "dev"}},
+			},
+			Devices: []*grpc_inventory_manager_go.Device{
+				{DeviceGroupId: "g", DeviceId: "d", AssetDeviceId: "v1:g:d", Labels: map[string]string{"env": "dev"}},
+			},
+		}
+		selectIDs := func(selector *entitySelector) []string {
+			result := make([]string, 0)
+			for _, item := range toInventoryItems(list) {
+				if selector.matches(item) {
+					result = append(result, item.id)
+				}
+			}
+			return result
+		}
+		gomega.Expect(selectIDs(&entitySelector{edgeControllerID: "ec1", labels: map[string]string{"env": "dev"}})).
+			To(gomega.ConsistOf("a1", "ec1"))
+		gomega.Expect(selectIDs(&entitySelector{ids: map[string]bool{"a2": true, "v1:g:d": true}})).
+			To(gomega.ConsistOf("a2", "v1:g:d"))
+	})
+})
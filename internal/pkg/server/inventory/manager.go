@@ -23,13 +23,21 @@ import (
 	"github.com/nalej/grpc-device-manager-go"
 	"github.com/nalej/grpc-inventory-go"
 	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-network-go"
 	"github.com/nalej/grpc-organization-go"
 	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/grpc-vpn-server-go"
+	"github.com/nalej/inventory-manager/internal/pkg/cache"
 	"github.com/nalej/inventory-manager/internal/pkg/config"
 	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/attribute"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/savedquery"
 	"github.com/nalej/inventory-manager/internal/pkg/server/contexts"
+	"github.com/nalej/inventory-manager/internal/pkg/units"
+	"github.com/nalej/inventory-manager/internal/pkg/watch"
 	"github.com/rs/zerolog/log"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -39,73 +47,167 @@ type Manager struct {
 	deviceManagerClient grpc_device_manager_go.DevicesClient
 	assetsClient        grpc_inventory_go.AssetsClient
 	controllersClient   grpc_inventory_go.ControllersClient
+	vpnClient           grpc_vpn_server_go.VPNServerClient
+	netMngrClient       grpc_network_go.ServiceDNSClient
+	savedQueries        savedquery.Provider
+	attributeSchemas    attribute.Provider
+	cache               *cache.InventoryCache
+	broadcaster         *watch.Broadcaster
+	history             *watch.OperationHistory
 	cfg                 config.Config
 }
 
 func NewManager(deviceManagerClient grpc_device_manager_go.DevicesClient,
 	assetsClient grpc_inventory_go.AssetsClient,
-	controllersClient grpc_inventory_go.ControllersClient, cfg config.Config) Manager {
+	controllersClient grpc_inventory_go.ControllersClient,
+	vpnClient grpc_vpn_server_go.VPNServerClient, netManagerClient grpc_network_go.ServiceDNSClient,
+	savedQueries savedquery.Provider, attributeSchemas attribute.Provider, inventoryCache *cache.InventoryCache,
+	broadcaster *watch.Broadcaster, history *watch.OperationHistory, cfg config.Config) Manager {
 	return Manager{
 		deviceManagerClient: deviceManagerClient,
 		assetsClient:        assetsClient,
 		controllersClient:   controllersClient,
+		vpnClient:           vpnClient,
+		netMngrClient:       netManagerClient,
+		savedQueries:        savedQueries,
+		attributeSchemas:    attributeSchemas,
+		cache:               inventoryCache,
+		broadcaster:         broadcaster,
+		history:             history,
 		cfg:                 cfg,
 	}
 }
 
+// List assembles the inventory of an organization querying the device manager and system-model concurrently.
+// If a source fails the rest of the inventory is returned with the error of that source, the call only fails
+// when no source can be listed. Only the complete inventories are cached.
 func (m *Manager) List(organizationID *grpc_organization_go.OrganizationId) (*grpc_inventory_manager_go.InventoryList, error) {
-
-	devices, err := m.listDevices(organizationID)
-	if err != nil {
-		return nil, err
-	}
-	assets, err := m.listAssets(organizationID)
-	if err != nil {
-		return nil, err
+	if cached, exists := m.cache.GetList(organizationID.OrganizationId); exists {
+		return cached, nil
 	}
+	generation := m.cache.Generation(organizationID.OrganizationId)
 
-	controllers, err := m.listControllers(organizationID)
-	if err != nil {
-		return nil, err
-	}
+	ctx, cancel := context.WithTimeout(context.Background(), m.cfg.InventoryTimeout)
+	defer cancel()
 
-	return &grpc_inventory_manager_go.InventoryList{
-		Devices: devices,
+	var devices []*grpc_inventory_manager_go.Device
+	var assets []*grpc_inventory_manager_go.Asset
+	var controllers []*grpc_inventory_manager_go.EdgeController
+	var deviceErrors []*grpc_inventory_manager_go.InventorySourceError
+	var devicesErr, assetsErr, controllersErr error
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		devices, deviceErrors, devicesErr = m.listDevices(ctx, organizationID)
+	}()
+	go func() {
+		defer wg.Done()
+		assets, assetsErr = m.listAssets(ctx, organizationID)
+	}()
+	go func() {
+		defer wg.Done()
+		controllers, controllersErr = m.listControllers(ctx, organizationID)
+	}()
+	wg.Wait()
+
+	if devicesErr != nil && assetsErr != nil && controllersErr != nil {
+		return nil, devicesErr
+	}
+
+	sourceErrors := make([]*grpc_inventory_manager_go.InventorySourceError, 0)
+	if devicesErr != nil {
+		sourceErrors = append(sourceErrors, newSourceError(grpc_inventory_manager_go.InventorySource_DEVICES, "", devicesErr))
+	}
+	sourceErrors = append(sourceErrors, deviceErrors...)
+	if assetsErr != nil {
+		sourceErrors = append(sourceErrors, newSourceError(grpc_inventory_manager_go.InventorySource_ASSETS, "", assetsErr))
+	}
+	if controllersErr != nil {
+		sourceErrors = append(sourceErrors, newSourceError(grpc_inventory_manager_go.InventorySource_CONTROLLERS, "", controllersErr))
+	}
+	if len(sourceErrors) > 0 {
+		log.Warn().Str("organization_id", organizationID.OrganizationId).Int("errors", len(sourceErrors)).
+			Msg("partial inventory returned")
+	}
+
+	list := &grpc_inventory_manager_go.InventoryList{
+		Devices:     devices,
 		Assets:      assets,
 		Controllers: controllers,
-	}, nil
+		Errors:      sourceErrors,
+	}
+	if len(sourceErrors) == 0 {
+		m.cache.SetList(organizationID.OrganizationId, generation, list)
+	}
+	return list, nil
 }
 
-func (m *Manager) listDevices(organizationID *grpc_organization_go.OrganizationId) ([]*grpc_inventory_manager_go.Device, error) {
-	ctxDM, cancelDM := context.WithTimeout(context.Background(), DefaultTimeout)
-	defer cancelDM()
-	groups, err := m.deviceManagerClient.ListDeviceGroups(ctxDM, organizationID)
+func newSourceError(source grpc_inventory_manager_go.InventorySource, entityID string, err error) *grpc_inventory_manager_go.InventorySourceError {
+	return &grpc_inventory_manager_go.InventorySourceError{
+		Source:   source,
+		EntityId: entityID,
+		Error:    conversions.ToDerror(err).Error(),
+	}
+}
+
+// listDevices lists the devices of all the device groups of an organization, with at most InventoryParallelism
+// calls in flight. The groups that cannot be listed are returned as source errors.
+func (m *Manager) listDevices(ctx context.Context, organizationID *grpc_organization_go.OrganizationId) ([]*grpc_inventory_manager_go.Device, []*grpc_inventory_manager_go.InventorySourceError, error) {
+	groups, err := m.deviceManagerClient.ListDeviceGroups(ctx, organizationID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+
+	// results are stored by group so the order of the devices does not depend on the order of the responses
+	groupDevices := make([][]*grpc_inventory_manager_go.Device, len(groups.Groups))
+	groupErrors := make([]error, len(groups.Groups))
+	semaphore := make(chan struct{}, m.cfg.InventoryParallelism)
+	var wg sync.WaitGroup
+	for index, deviceGroup := range groups.Groups {
+		wg.Add(1)
+		go func(index int, deviceGroup *grpc_device_manager_go.DeviceGroup) {
+			defer wg.Done()
+			select {
+			case semaphore <- struct{}{}:
+				defer func() { <-semaphore }()
+			case <-ctx.Done():
+				groupErrors[index] = ctx.Err()
+				return
+			}
+			devices, err := m.deviceManagerClient.ListDevices(ctx, &grpc_device_go.DeviceGroupId{
+				OrganizationId: deviceGroup.OrganizationId,
+				DeviceGroupId:  deviceGroup.DeviceGroupId,
+			})
+			if err != nil {
+				groupErrors[index] = err
+				return
+			}
+			result := make([]*grpc_inventory_manager_go.Device, 0, len(devices.Devices))
+			for _, dev := range devices.Devices {
+				result = append(result, entities.NewDeviceFromGRPC(dev, m.cfg.DeviceThreshold))
+			}
+			groupDevices[index] = result
+		}(index, deviceGroup)
+	}
+	wg.Wait()
+
 	result := make([]*grpc_inventory_manager_go.Device, 0)
-	for _, deviceGroup := range groups.Groups {
-		ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
-		defer cancel()
-		deviceGroupId := &grpc_device_go.DeviceGroupId{
-			OrganizationId: deviceGroup.OrganizationId,
-			DeviceGroupId:  deviceGroup.DeviceGroupId,
-		}
-		devices, err := m.deviceManagerClient.ListDevices(ctx, deviceGroupId)
-		if err != nil {
-			return nil, err
-		}
-		for _, dev := range devices.Devices {
-			result = append(result, entities.NewDeviceFromGRPC(dev))
+	sourceErrors := make([]*grpc_inventory_manager_go.InventorySourceError, 0)
+	for index, deviceGroup := range groups.Groups {
+		if groupErrors[index] != nil {
+			log.Warn().Str("organization_id", deviceGroup.OrganizationId).Str("device_group_id", deviceGroup.DeviceGroupId).
+				Str("trace", conversions.ToDerror(groupErrors[index]).DebugReport()).Msg("cannot list devices of group")
+			sourceErrors = append(sourceErrors, newSourceError(grpc_inventory_manager_go.InventorySource_DEVICES, deviceGroup.DeviceGroupId, groupErrors[index]))
+			continue
 		}
-
+		result = append(result, groupDevices[index]...)
 	}
-	return result, nil
+	return result, sourceErrors, nil
 }
 
-func (m *Manager) listAssets(organizationID *grpc_organization_go.OrganizationId) ([]*grpc_inventory_manager_go.Asset, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
-	defer cancel()
+func (m *Manager) listAssets(ctx context.Context, organizationID *grpc_organization_go.OrganizationId) ([]*grpc_inventory_manager_go.Asset, error) {
 	assets, err := m.assetsClient.List(ctx, organizationID)
 	if err != nil {
 		return nil, err
@@ -113,9 +215,7 @@ func (m *Manager) listAssets(organizationID *grpc_organization_go.OrganizationId
 	return m.toAssetFromList(assets.Assets), nil
 }
 
-func (m *Manager) listControllers(organizationID *grpc_organization_go.OrganizationId) ([]*grpc_inventory_manager_go.EdgeController, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
-	defer cancel()
+func (m *Manager) listControllers(ctx context.Context, organizationID *grpc_organization_go.OrganizationId) ([]*grpc_inventory_manager_go.EdgeController, error) {
 	controllers, err := m.controllersClient.List(ctx, organizationID)
 	if err != nil {
 		return nil, err
@@ -128,90 +228,21 @@ func (m *Manager) listControllers(organizationID *grpc_organization_go.Organizat
 	return result, nil
 }
 
-func (m *Manager) GetControllerExtendedInfo(edgeControllerID *grpc_inventory_go.EdgeControllerId) (*grpc_inventory_manager_go.EdgeController, []*grpc_inventory_manager_go.Asset, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
-	defer cancel()
-	controller, err := m.controllersClient.Get(ctx, edgeControllerID)
-	if err != nil {
-		return nil, nil, err
-	}
-	assetCtx, assetCancel := context.WithTimeout(context.Background(), DefaultTimeout)
-	defer assetCancel()
-	assets, err := m.assetsClient.ListControllerAssets(assetCtx, edgeControllerID)
-	if err != nil {
-		return nil, nil, err
+func (m *Manager) GetAssetInfo(assetID *grpc_inventory_go.AssetId) (*grpc_inventory_manager_go.Asset, error) {
+	if cached, exists := m.cache.GetAsset(assetID.OrganizationId, assetID.AssetId); exists {
+		return cached, nil
 	}
-	return m.toController(controller), m.toAssetFromList(assets.Assets), nil
-}
+	generation := m.cache.Generation(assetID.OrganizationId)
 
-func (m *Manager) GetAssetInfo(assetID *grpc_inventory_go.AssetId) (*grpc_inventory_manager_go.Asset, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), DefaultTimeout)
 	defer cancel()
 	asset, err := m.assetsClient.Get(ctx, assetID)
 	if err != nil {
 		return nil, err
 	}
-	return m.toAsset(asset), nil
-}
-
-// getSummaryFromAssetInfo returns totalNumCPUs, totalStorage and totalRAM
-func (m * Manager) getSummaryFromAssetInfo(assetInfo *grpc_inventory_go.AssetInfo) (int32, int64, int64){
-	var totalNumCPUs int32
-	var totalStorage int64
-	var totalRAM int64
-
-	if  assetInfo == nil{
-		return 0, 0, 0
-	}
-
-	if assetInfo.Hardware != nil {
-		for _, cpu := range assetInfo.Hardware.Cpus{
-			if cpu != nil {
-				totalNumCPUs = totalNumCPUs + cpu.NumCores
-			}
-		}
-		totalRAM = totalRAM + assetInfo.Hardware.InstalledRam
-	}
-
-	if assetInfo.Storage != nil {
-		for _, storage := range assetInfo.Storage {
-			if storage != nil {
-				totalStorage = totalStorage + storage.TotalCapacity
-			}
-		}
-	}
-
-	return totalNumCPUs, totalStorage, totalRAM
-}
-
-// getSummaryFromAsset returns totalNumCPUs, totalStorage and totalRAM
-func (m * Manager) getSummaryFromAsset(asset *grpc_inventory_manager_go.Asset) (int32, int64, int64){
-	var totalNumCPUs int32
-	var totalStorage int64
-	var totalRAM int64
-
-	if asset == nil {
-		return 0, 0, 0
-	}
-
-	if asset.Hardware != nil {
-		for _, cpu := range asset.Hardware.Cpus {
-			if cpu != nil{
-				totalNumCPUs = totalNumCPUs + cpu.NumCores
-			}
-		}
-		totalRAM = totalRAM + asset.Hardware.InstalledRam
-	}
-
-	if asset.Storage != nil {
-		for _, storage := range asset.Storage {
-			if storage != nil {
-				totalStorage = totalStorage + storage.TotalCapacity
-			}
-		}
-	}
-
-	return totalNumCPUs, totalStorage, totalRAM
+	result := m.toAsset(asset)
+	m.cache.SetAsset(assetID.OrganizationId, generation, result)
+	return result, nil
 }
 
 func (m *Manager) Summary (organizationID *grpc_organization_go.OrganizationId) (*grpc_inventory_manager_go.InventorySummary, error) {
@@ -219,42 +250,59 @@ func (m *Manager) Summary (organizationID *grpc_organization_go.OrganizationId)
 	if err != nil {
 		return nil, err
 	}
+	return m.SummaryFromList(organizationID.OrganizationId, inventoryList), nil
+}
 
-	var totalNumCPUs int32 = 0
-	var totalStorage int64 = 0
-	var totalRAM int64 = 0
-
-	for _, device := range inventoryList.Devices {
-		cpus, storage, ram := m.getSummaryFromAssetInfo(device.AssetInfo)
-		totalNumCPUs = totalNumCPUs + cpus
-		totalStorage = totalStorage + storage
-		totalRAM = totalRAM + ram
-	}
-
-	for _, asset := range inventoryList.Assets {
-		// TODO Refactor asset to include an AssetInfo
-		cpus, storage, ram := m.getSummaryFromAsset(asset)
-		totalNumCPUs = totalNumCPUs + cpus
-		totalStorage = totalStorage + storage
-		totalRAM = totalRAM + ram
-	}
-
-	for _, ec := range inventoryList.Controllers {
-		cpus, storage, ram := m.getSummaryFromAssetInfo(ec.AssetInfo)
-		totalNumCPUs = totalNumCPUs + cpus
-		totalStorage = totalStorage + storage
-		totalRAM = totalRAM + ram
+// SummaryFromList computes the summary of an inventory list already retrieved. The totals are reported in bytes
+// and MB and, to keep the previous clients working, also in GB in TotalStorage and TotalRam. The entities whose
+// resources are not plausible are counted in NumImplausible.
+func (m *Manager) SummaryFromList(organizationID string, inventoryList *grpc_inventory_manager_go.InventoryList) *grpc_inventory_manager_go.InventorySummary {
+	totals := &grpc_inventory_manager_go.ResourceTotals{}
+	byEntityType := newBreakdown()
+	byStatus := newBreakdown()
+	byController := newBreakdown()
+	byOsFamily := newBreakdown()
+	byDeviceGroup := newBreakdown()
+	byLabelKey := newBreakdown()
+	var numImplausible int64
+
+	for _, item := range toInventoryItems(inventoryList) {
+		if item.normalizedResources().Implausible {
+			numImplausible++
+		}
+		resources := item.resources()
+		addResources(totals, resources)
+		byEntityType.add(entityTypeNames[item.entityType], resources)
+		byStatus.add(strings.ToLower(item.status.String()), resources)
+		if item.entityType != grpc_inventory_manager_go.InventoryEntityType_DEVICE {
+			byController.add(item.controllerID, resources)
+		} else {
+			byDeviceGroup.add(item.deviceGroupID, resources)
+		}
+		byOsFamily.add(item.osFamily(), resources)
+		for key := range item.labels {
+			byLabelKey.add(key, resources)
+		}
 	}
 
-	totalNumCPUs64 := int64 (totalNumCPUs)
-
 	return &grpc_inventory_manager_go.InventorySummary{
-		OrganizationId:       organizationID.OrganizationId,
-		TotalNumCpu:          totalNumCPUs64,
-		// Divided by 1024 to convert MB to GB
-		TotalStorage:         totalStorage/int64(1024),
-		TotalRam:             totalRAM/int64(1024),
-	}, nil
+		OrganizationId:       organizationID,
+		TotalNumCpu:          totals.NumCpu,
+		TotalStorage:         totals.StorageMb / MBPerGB,
+		TotalRam:             totals.RamMb / MBPerGB,
+		Totals:               totals,
+		NumDevices:           int64(len(inventoryList.Devices)),
+		NumAssets:            int64(len(inventoryList.Assets)),
+		NumControllers:       int64(len(inventoryList.Controllers)),
+		ByEntityType:         byEntityType.toList(),
+		ByStatus:             byStatus.toList(),
+		ByController:         byController.toList(),
+		ByOsFamily:           byOsFamily.toList(),
+		ByDeviceGroup:        byDeviceGroup.toList(),
+		ByLabelKey:           byLabelKey.toList(),
+		NumImplausible:       numImplausible,
+		Errors:               inventoryList.Errors,
+	}
 }
 
 func (m *Manager) toAssetFromList(assets []*grpc_inventory_go.Asset) []*grpc_inventory_manager_go.Asset {
@@ -267,13 +315,7 @@ func (m *Manager) toAssetFromList(assets []*grpc_inventory_go.Asset) []*grpc_inv
 }
 
 func (m *Manager) toAsset(asset *grpc_inventory_go.Asset) *grpc_inventory_manager_go.Asset {
-	status := grpc_inventory_manager_go.ConnectedStatus_OFFLINE
-	if asset.LastAliveTimestamp != 0 {
-		timeCalculated := time.Unix(asset.LastAliveTimestamp, 0).Add(m.cfg.AssetThreshold).Unix()
-		if timeCalculated > time.Now().Unix() {
-			status = grpc_inventory_manager_go.ConnectedStatus_ONLINE
-		}
-	}
+	status := entities.GetConnectedStatus(asset.LastAliveTimestamp, m.cfg.AssetThreshold)
 
 	return &grpc_inventory_manager_go.Asset{
 		OrganizationId:     asset.OrganizationId,
@@ -291,17 +333,16 @@ func (m *Manager) toAsset(asset *grpc_inventory_go.Asset) *grpc_inventory_manage
 		LastAliveTimestamp: asset.LastAliveTimestamp,
 		Status:             status,
 		Location:           asset.Location,
+		State:              entities.GetAssetState(asset.Labels),
+		Quarantined:        entities.IsQuarantined(asset.Labels),
+		Unmanaged:          entities.IsUnmanaged(asset.Labels),
+		Resources:          entities.NormalizeResources(asset.Hardware, asset.Storage, asset.Labels, units.HostRanges),
+		Attributes:         entities.AttributesFromLabels(asset.Labels),
 	}
 }
 
 func (m *Manager) toController(ec *grpc_inventory_go.EdgeController) *grpc_inventory_manager_go.EdgeController {
-	status := grpc_inventory_manager_go.ConnectedStatus_OFFLINE
-	if ec.LastAliveTimestamp != 0 {
-		timeCalculated := time.Unix(ec.LastAliveTimestamp, 0).Add(m.cfg.ControllerThreshold).Unix()
-		if timeCalculated > time.Now().Unix() {
-			status = grpc_inventory_manager_go.ConnectedStatus_ONLINE
-		}
-	}
+	status := entities.GetConnectedStatus(ec.LastAliveTimestamp, m.cfg.ControllerThreshold)
 	return &grpc_inventory_manager_go.EdgeController{
 		OrganizationId:     ec.OrganizationId,
 		EdgeControllerId:   ec.EdgeControllerId,
@@ -314,6 +355,8 @@ func (m *Manager) toController(ec *grpc_inventory_go.EdgeController) *grpc_inven
 		Location:           ec.Location,
 		AssetInfo:          ec.AssetInfo,
 		LastOpResult:       ec.LastOpResult,
+		Resources:          entities.NormalizeAssetInfo(ec.AssetInfo, ec.Labels, units.HostRanges),
+		Attributes:         entities.AttributesFromLabels(ec.Labels),
 	}
 }
 
@@ -348,17 +391,15 @@ func (m * Manager) UpdateDeviceLocation (updateDeviceRequest *grpc_inventory_man
 	ctx, cancel := contexts.SMContext()
 	defer cancel()
 
-	deviceInfo := strings.Split(updateDeviceRequest.AssetDeviceId,"#")
-	if len(deviceInfo) != 2{
-		return nil, derrors.NewInvalidArgumentError("invalid asset_device_id")
+	deviceID, decErr := entities.ParseDeviceIdentifier(updateDeviceRequest.AssetDeviceId)
+	if decErr != nil {
+		return nil, conversions.ToGRPCError(decErr)
 	}
-	deviceGroupId := deviceInfo[0]
-	deviceId := deviceInfo[1]
 
 	updateRequest := &grpc_device_manager_go.UpdateDeviceLocationRequest{
 		OrganizationId:       updateDeviceRequest.OrganizationId,
-		DeviceGroupId:        deviceGroupId,
-		DeviceId:             deviceId,
+		DeviceGroupId:        deviceID.DeviceGroupID,
+		DeviceId:             deviceID.DeviceID,
 		UpdateLocation:       true,
 		Location:             updateDeviceRequest.Location,
 	}
@@ -369,30 +410,51 @@ func (m * Manager) UpdateDeviceLocation (updateDeviceRequest *grpc_inventory_man
 		return nil, err
 	}
 
-	return entities.NewDeviceFromGRPC(updated), nil
+	return entities.NewDeviceFromGRPC(updated, m.cfg.DeviceThreshold), nil
+}
+
+// UpdateDevice adds or removes the labels of a device and enables or disables it in the device manager.
+func (m *Manager) UpdateDevice(request *grpc_inventory_manager_go.UpdateDeviceRequest) (*grpc_inventory_manager_go.Device, error) {
+	deviceID, decErr := m.decomposeDeviceAssetID(&grpc_inventory_manager_go.DeviceId{
+		OrganizationId: request.OrganizationId,
+		AssetDeviceId:  request.AssetDeviceId,
+	})
+	if decErr != nil {
+		return nil, conversions.ToGRPCError(decErr)
+	}
+
+	ctx, cancel := contexts.SMContext()
+	defer cancel()
+	updated, err := m.deviceManagerClient.UpdateDevice(ctx, &grpc_device_manager_go.UpdateDeviceRequest{
+		OrganizationId: deviceID.OrganizationId,
+		DeviceGroupId:  deviceID.DeviceGroupId,
+		DeviceId:       deviceID.DeviceId,
+		AddLabels:      request.AddLabels,
+		RemoveLabels:   request.RemoveLabels,
+		Labels:         request.Labels,
+		UpdateEnabled:  request.UpdateEnabled,
+		Enabled:        request.Enabled,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entities.NewDeviceFromGRPC(updated, m.cfg.DeviceThreshold), nil
 }
 
 // decomposeDeviceAssetID convert a grpc_inventory_manager_go.DeviceId into a grpc_device_go.DeviceId
-// asset_device_id is cmmpose by device_group_id#device_id
 func (m *Manager) decomposeDeviceAssetID (deviceID *grpc_inventory_manager_go.DeviceId) (*grpc_device_go.DeviceId, derrors.Error) {
 
 	if deviceID == nil {
 		return nil, derrors.NewInvalidArgumentError("deviceId cannot be empty")
 	}
-	deviceAssetId := deviceID.AssetDeviceId
-	if deviceAssetId == "" {
-		return nil, derrors.NewInvalidArgumentError("device_asset_id cannot be empty")
-	}
-
-	fields := strings.Split(deviceAssetId, "#")
-	if len(fields) != 2  {
-		return nil, derrors.NewInvalidArgumentError("device_asset_id is wrong")
-
+	id, err := entities.ParseDeviceIdentifier(deviceID.AssetDeviceId)
+	if err != nil {
+		return nil, err
 	}
 	return &grpc_device_go.DeviceId{
 		OrganizationId:	deviceID.OrganizationId,
-		DeviceGroupId:	fields[0],
-		DeviceId: 		fields[1],
+		DeviceGroupId:	id.DeviceGroupID,
+		DeviceId: 		id.DeviceID,
 	}, nil
 
 }
@@ -414,6 +476,6 @@ func (m *Manager)GetDeviceInfo( request *grpc_inventory_manager_go.DeviceId) (*g
 		return nil, err
 	}
 	log.Debug().Interface("device", device).Msg("device")
-	return entities.NewDeviceFromGRPC(device), nil
+	return entities.NewDeviceFromGRPC(device, m.cfg.DeviceThreshold), nil
 }
 
@@ -0,0 +1,116 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inventory
+
+import (
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-device-manager-go"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/inventory-manager/internal/pkg/cache"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"time"
+)
+
+var _ = ginkgo.Describe("Inventory listing", func() {
+
+	organizationID := &grpc_organization_go.OrganizationId{OrganizationId: "org"}
+	var devices *fakeDevices
+	var assets *fakeAssets
+	var controllers *fakeControllers
+
+	ginkgo.BeforeEach(func() {
+		devices = &fakeDevices{
+			groups: []*grpc_device_manager_go.DeviceGroup{{OrganizationId: "org", DeviceGroupId: "group"}},
+			devices: map[string][]*grpc_device_manager_go.Device{
+				"group": {{OrganizationId: "org", DeviceGroupId: "group", DeviceId: "device"}},
+			},
+		}
+		assets = &fakeAssets{assets: []*grpc_inventory_go.Asset{{OrganizationId: "org", AssetId: "asset"}}}
+		controllers = &fakeControllers{controllers: []*grpc_inventory_go.EdgeController{{OrganizationId: "org", EdgeControllerId: "ec"}}}
+	})
+
+	sources := func(list *grpc_inventory_manager_go.InventoryList) []grpc_inventory_manager_go.InventorySource {
+		result := make([]grpc_inventory_manager_go.InventorySource, 0, len(list.Errors))
+		for _, sourceError := range list.Errors {
+			result = append(result, sourceError.Source)
+		}
+		return result
+	}
+
+	ginkgo.It("should return the complete inventory", func() {
+		manager := newTestManager(devices, assets, controllers)
+		list, err := manager.List(organizationID)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(list.Devices).To(gomega.HaveLen(1))
+		gomega.Expect(list.Assets).To(gomega.HaveLen(1))
+		gomega.Expect(list.Controllers).To(gomega.HaveLen(1))
+		gomega.Expect(list.Errors).To(gomega.BeEmpty())
+	})
+
+	ginkgo.It("should return the other sources when one fails", func() {
+		controllers.err = derrors.NewUnavailableError("system model not available")
+		manager := newTestManager(devices, assets, controllers)
+		list, err := manager.List(organizationID)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(list.Devices).To(gomega.HaveLen(1))
+		gomega.Expect(list.Assets).To(gomega.HaveLen(1))
+		gomega.Expect(list.Controllers).To(gomega.BeEmpty())
+		gomega.Expect(sources(list)).To(gomega.Equal([]grpc_inventory_manager_go.InventorySource{
+			grpc_inventory_manager_go.InventorySource_CONTROLLERS}))
+		gomega.Expect(list.Errors[0].Error).NotTo(gomega.BeEmpty())
+	})
+
+	ginkgo.It("should return the other sources when one times out", func() {
+		assets.delay = time.Minute
+		cfg := testConfig
+		cfg.InventoryTimeout = 50 * time.Millisecond
+		manager := NewManager(devices, assets, controllers, nil, nil, nil, nil, cache.NewInventoryCache(0), nil, nil, cfg)
+		list, err := manager.List(organizationID)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(list.Devices).To(gomega.HaveLen(1))
+		gomega.Expect(list.Assets).To(gomega.BeEmpty())
+		gomega.Expect(list.Controllers).To(gomega.HaveLen(1))
+		gomega.Expect(sources(list)).To(gomega.Equal([]grpc_inventory_manager_go.InventorySource{
+			grpc_inventory_manager_go.InventorySource_ASSETS}))
+	})
+
+	ginkgo.It("should fail when no source can be listed", func() {
+		devices.err = derrors.NewUnavailableError("device manager not available")
+		assets.err = derrors.NewUnavailableError("system model not available")
+		controllers.err = derrors.NewUnavailableError("system model not available")
+		manager := newTestManager(devices, assets, controllers)
+		_, err := manager.List(organizationID)
+		gomega.Expect(err).NotTo(gomega.Succeed())
+	})
+
+	ginkgo.It("should not cache a partial inventory", func() {
+		assets.err = derrors.NewUnavailableError("system model not available")
+		manager := NewManager(devices, assets, controllers, nil, nil, nil, nil, cache.NewInventoryCache(time.Minute), nil, nil, testConfig)
+		list, err := manager.List(organizationID)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(list.Errors).To(gomega.HaveLen(1))
+
+		assets.err = nil
+		list, err = manager.List(organizationID)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(list.Assets).To(gomega.HaveLen(1))
+		gomega.Expect(list.Errors).To(gomega.BeEmpty())
+	})
+})
@@ -0,0 +1,326 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inventory
+
+import (
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"sort"
+	"strings"
+)
+
+// DefaultPageSize with the number of elements returned when the request does not set a page size.
+const DefaultPageSize = 100
+
+// inventoryItem is a flattened view of a device, asset or controller used to filter and sort the inventory.
+type inventoryItem struct {
+	entityType   grpc_inventory_manager_go.InventoryEntityType
+	id           string
+	name         string
+	created      int64
+	lastAlive    int64
+	status       grpc_inventory_manager_go.ConnectedStatus
+	controllerID string
+	// deviceGroupID is only set for devices
+	deviceGroupID string
+	labels        map[string]string
+	os            *grpc_inventory_go.OperatingSystemInfo
+	hardware      *grpc_inventory_go.HardwareInfo
+	storage       []*grpc_inventory_go.StorageHardwareInfo
+	location      *grpc_inventory_go.InventoryLocation
+	// normalized with the resources in bytes, see normalizedResources
+	normalized *grpc_inventory_manager_go.NormalizedResources
+	item       *grpc_inventory_manager_go.InventoryItem
+}
+
+// pageToken is the content of the opaque token used to retrieve the next page.
+type pageToken struct {
+	Offset int    `json:"offset"`
+	Query  string `json:"query"`
+}
+
+// ListPaged returns a page of the inventory of an organization filtered and sorted as requested.
+func (m *Manager) ListPaged(request *grpc_inventory_manager_go.ListInventoryRequest) (*grpc_inventory_manager_go.InventoryPage, error) {
+	query, qErr := queryHash(request)
+	if qErr != nil {
+		return nil, conversions.ToGRPCError(qErr)
+	}
+	offset := 0
+	if request.PageToken != "" {
+		token, tErr := decodePageToken(request.PageToken)
+		if tErr != nil {
+			return nil, conversions.ToGRPCError(tErr)
+		}
+		if token.Query != query {
+			return nil, conversions.ToGRPCError(derrors.NewInvalidArgumentError("page_token does not match the filter and sorting of the request"))
+		}
+		offset = token.Offset
+	}
+
+	list, err := m.List(&grpc_organization_go.OrganizationId{OrganizationId: request.OrganizationId})
+	if err != nil {
+		return nil, err
+	}
+	items := filterItems(toInventoryItems(list), request.Filter)
+	sortItems(items, request.SortBy, request.SortDescending)
+
+	pageSize := int(request.PageSize)
+	if pageSize == 0 {
+		pageSize = DefaultPageSize
+	}
+	if offset > len(items) {
+		offset = len(items)
+	}
+	end := offset + pageSize
+	if end > len(items) {
+		end = len(items)
+	}
+
+	result := make([]*grpc_inventory_manager_go.InventoryItem, 0, end-offset)
+	for _, item := range items[offset:end] {
+		result = append(result, item.item)
+	}
+	nextToken := ""
+	if end < len(items) {
+		token, tErr := encodePageToken(pageToken{Offset: end, Query: query})
+		if tErr != nil {
+			return nil, conversions.ToGRPCError(tErr)
+		}
+		nextToken = token
+	}
+	return &grpc_inventory_manager_go.InventoryPage{
+		OrganizationId: request.OrganizationId,
+		Items:          result,
+		NextPageToken:  nextToken,
+		TotalSize:      int32(len(items)),
+		Errors:         list.Errors,
+	}, nil
+}
+
+// toInventoryItems flattens an inventory list. Assets do not have a name so their identifiers are used instead.
+func toInventoryItems(list *grpc_inventory_manager_go.InventoryList) []*inventoryItem {
+	result := make([]*inventoryItem, 0, len(list.Devices)+len(list.Assets)+len(list.Controllers))
+	for _, device := range list.Devices {
+		item := &inventoryItem{
+			entityType:    grpc_inventory_manager_go.InventoryEntityType_DEVICE,
+			id:            device.AssetDeviceId,
+			name:          device.DeviceId,
+			created:       device.RegisterSince,
+			lastAlive:     device.LastAliveTimestamp,
+			status:        device.Status,
+			deviceGroupID: device.DeviceGroupId,
+			labels:        device.Labels,
+			location:      device.Location,
+			normalized:    device.Resources,
+			item: &grpc_inventory_manager_go.InventoryItem{
+				Type:   grpc_inventory_manager_go.InventoryEntityType_DEVICE,
+				Device: device,
+			},
+		}
+		item.setAssetInfo(device.AssetInfo)
+		result = append(result, item)
+	}
+	for _, asset := range list.Assets {
+		result = append(result, &inventoryItem{
+			entityType:   grpc_inventory_manager_go.InventoryEntityType_ASSET,
+			id:           asset.AssetId,
+			name:         asset.AssetId,
+			created:      asset.Created,
+			lastAlive:    asset.LastAliveTimestamp,
+			status:       asset.Status,
+			controllerID: asset.EdgeControllerId,
+			labels:       asset.Labels,
+			os:           asset.Os,
+			hardware:     asset.Hardware,
+			storage:      asset.Storage,
+			location:     asset.Location,
+			normalized:   asset.Resources,
+			item: &grpc_inventory_manager_go.InventoryItem{
+				Type:  grpc_inventory_manager_go.InventoryEntityType_ASSET,
+				Asset: asset,
+			},
+		})
+	}
+	for _, ec := range list.Controllers {
+		item := &inventoryItem{
+			entityType:   grpc_inventory_manager_go.InventoryEntityType_CONTROLLER,
+			id:           ec.EdgeControllerId,
+			name:         ec.Name,
+			created:      ec.Created,
+			lastAlive:    ec.LastAliveTimestamp,
+			status:       ec.Status,
+			controllerID: ec.EdgeControllerId,
+			labels:       ec.Labels,
+			location:     ec.Location,
+			normalized:   ec.Resources,
+			item: &grpc_inventory_manager_go.InventoryItem{
+				Type:       grpc_inventory_manager_go.InventoryEntityType_CONTROLLER,
+				Controller: ec,
+			},
+		}
+		item.setAssetInfo(ec.AssetInfo)
+		result = append(result, item)
+	}
+	return result
+}
+
+func (i *inventoryItem) setAssetInfo(assetInfo *grpc_inventory_go.AssetInfo) {
+	if assetInfo == nil {
+		return
+	}
+	i.os = assetInfo.Os
+	i.hardware = assetInfo.Hardware
+	i.storage = assetInfo.Storage
+}
+
+func (i *inventoryItem) osName() string {
+	if i.os == nil {
+		return ""
+	}
+	return i.os.Name
+}
+
+// filterItems returns the items matching all the conditions of the filter.
+func filterItems(items []*inventoryItem, filter *grpc_inventory_manager_go.InventoryFilter) []*inventoryItem {
+	if filter == nil {
+		return items
+	}
+	result := make([]*inventoryItem, 0, len(items))
+	for _, item := range items {
+		if matchesFilter(item, filter) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func matchesFilter(item *inventoryItem, filter *grpc_inventory_manager_go.InventoryFilter) bool {
+	if len(filter.EntityTypes) > 0 {
+		found := false
+		for _, entityType := range filter.EntityTypes {
+			if entityType == item.entityType {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(filter.Status) > 0 {
+		found := false
+		for _, status := range filter.Status {
+			if status == item.status {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if filter.EdgeControllerId != "" && filter.EdgeControllerId != item.controllerID {
+		return false
+	}
+	for key, value := range filter.Labels {
+		current, exists := item.labels[key]
+		if !exists || current != value {
+			return false
+		}
+	}
+	for name, value := range filter.Attributes {
+		current, exists := item.labels[entities.AttributeLabel(name)]
+		if !exists || current != value {
+			return false
+		}
+	}
+	if filter.OsName != "" && !strings.Contains(strings.ToLower(item.osName()), strings.ToLower(filter.OsName)) {
+		return false
+	}
+	return true
+}
+
+// sortItems sorts the items by the requested field. The identifier is used to break ties so the
+// order is stable between pages.
+func sortItems(items []*inventoryItem, sortBy grpc_inventory_manager_go.InventorySortField, descending bool) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if descending {
+			a, b = b, a
+		}
+		switch sortBy {
+		case grpc_inventory_manager_go.InventorySortField_CREATED:
+			if a.created != b.created {
+				return a.created < b.created
+			}
+		case grpc_inventory_manager_go.InventorySortField_LAST_ALIVE:
+			if a.lastAlive != b.lastAlive {
+				return a.lastAlive < b.lastAlive
+			}
+		default:
+			if a.name != b.name {
+				return a.name < b.name
+			}
+		}
+		return a.id < b.id
+	})
+}
+
+// queryHash identifies the filter and sorting of a request so a token is not reused with a different query.
+func queryHash(request *grpc_inventory_manager_go.ListInventoryRequest) (string, derrors.Error) {
+	raw, err := json.Marshal(struct {
+		OrganizationId string
+		Filter         *grpc_inventory_manager_go.InventoryFilter
+		SortBy         grpc_inventory_manager_go.InventorySortField
+		SortDescending bool
+	}{request.OrganizationId, request.Filter, request.SortBy, request.SortDescending})
+	if err != nil {
+		return "", derrors.NewInternalError("cannot compute the query of the request", err)
+	}
+	hash := sha1.Sum(raw)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+func encodePageToken(token pageToken) (string, derrors.Error) {
+	raw, err := json.Marshal(token)
+	if err != nil {
+		return "", derrors.NewInternalError("cannot create the page token", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func decodePageToken(encoded string) (*pageToken, derrors.Error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, derrors.NewInvalidArgumentError("invalid page_token")
+	}
+	token := &pageToken{}
+	err = json.Unmarshal(raw, token)
+	if err != nil || token.Offset < 0 {
+		return nil, derrors.NewInvalidArgumentError(fmt.Sprintf("invalid page_token: %s", encoded))
+	}
+	return token, nil
+}
@@ -0,0 +1,331 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inventory
+
+import (
+	"context"
+	"github.com/nalej/grpc-device-go"
+	"github.com/nalej/grpc-device-manager-go"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/inventory-manager/internal/pkg/cache"
+	"github.com/nalej/inventory-manager/internal/pkg/config"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"time"
+)
+
+type fakeDevices struct {
+	grpc_device_manager_go.DevicesClient
+	groups  []*grpc_device_manager_go.DeviceGroup
+	devices map[string][]*grpc_device_manager_go.Device
+	err     error
+	// updates received by UpdateDevice.
+	updates []*grpc_device_manager_go.UpdateDeviceRequest
+}
+
+func (f *fakeDevices) ListDeviceGroups(ctx context.Context, in *grpc_organization_go.OrganizationId, opts ...grpc.CallOption) (*grpc_device_manager_go.DeviceGroupList, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &grpc_device_manager_go.DeviceGroupList{Groups: f.groups}, nil
+}
+
+func (f *fakeDevices) ListDevices(ctx context.Context, in *grpc_device_go.DeviceGroupId, opts ...grpc.CallOption) (*grpc_device_manager_go.DeviceList, error) {
+	return &grpc_device_manager_go.DeviceList{Devices: f.devices[in.DeviceGroupId]}, nil
+}
+
+func (f *fakeDevices) UpdateDevice(ctx context.Context, in *grpc_device_manager_go.UpdateDeviceRequest, opts ...grpc.CallOption) (*grpc_device_manager_go.Device, error) {
+	f.updates = append(f.updates, in)
+	return &grpc_device_manager_go.Device{
+		OrganizationId: in.OrganizationId,
+		DeviceGroupId:  in.DeviceGroupId,
+		DeviceId:       in.DeviceId,
+		Labels:         in.Labels,
+		Enabled:        in.Enabled,
+	}, nil
+}
+
+type fakeAssets struct {
+	grpc_inventory_go.AssetsClient
+	assets []*grpc_inventory_go.Asset
+	err    error
+	// delay before List answers, used to check the timeout of the inventory.
+	delay time.Duration
+}
+
+func (f *fakeAssets) List(ctx context.Context, in *grpc_organization_go.OrganizationId, opts ...grpc.CallOption) (*grpc_inventory_go.AssetList, error) {
+	if f.delay > 0 {
+		select {
+		case <-time.After(f.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &grpc_inventory_go.AssetList{Assets: f.assets}, nil
+}
+
+type fakeControllers struct {
+	grpc_inventory_go.ControllersClient
+	controllers []*grpc_inventory_go.EdgeController
+	err         error
+}
+
+func (f *fakeControllers) List(ctx context.Context, in *grpc_organization_go.OrganizationId, opts ...grpc.CallOption) (*grpc_inventory_go.EdgeControllerList, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &grpc_inventory_go.EdgeControllerList{Controllers: f.controllers}, nil
+}
+
+// testConfig with the configuration required to list the inventory.
+var testConfig = config.Config{
+	ControllerThreshold:  time.Minute,
+	AssetThreshold:       time.Minute,
+	DeviceThreshold:      time.Minute,
+	InventoryParallelism: 2,
+	InventoryTimeout:     time.Second,
+}
+
+func newTestManager(devices *fakeDevices, assets *fakeAssets, controllers *fakeControllers) Manager {
+	return NewManager(devices, assets, controllers, nil, nil, nil, nil, cache.NewInventoryCache(0), nil, nil, testConfig)
+}
+
+var _ = ginkgo.Describe("Inventory paging", func() {
+
+	newItem := func(entityType grpc_inventory_manager_go.InventoryEntityType, id string, status grpc_inventory_manager_go.ConnectedStatus,
+		controllerID string, osName string, labels map[string]string) *inventoryItem {
+		return &inventoryItem{
+			entityType:   entityType,
+			id:           id,
+			name:         id,
+			status:       status,
+			controllerID: controllerID,
+			labels:       labels,
+			os:           &grpc_inventory_go.OperatingSystemInfo{Name: osName},
+		}
+	}
+	items := []*inventoryItem{
+		newItem(grpc_inventory_manager_go.InventoryEntityType_ASSET, "a1", grpc_inventory_manager_go.ConnectedStatus_ONLINE,
+			"ec1", "Ubuntu 18.04", map[string]string{"env": "prod", entities.AttributeLabel("rack"): "r1"}),
+		newItem(grpc_inventory_manager_go.InventoryEntityType_ASSET, "a2", grpc_inventory_manager_go.ConnectedStatus_OFFLINE,
+			"ec1", "Windows 10", map[string]string{"env": "dev"}),
+		newItem(grpc_inventory_manager_go.InventoryEntityType_ASSET, "a3", grpc_inventory_manager_go.ConnectedStatus_ONLINE,
+			"ec2", "ubuntu 20.04", map[string]string{"env": "prod"}),
+		newItem(grpc_inventory_manager_go.InventoryEntityType_CONTROLLER, "ec1", grpc_inventory_manager_go.ConnectedStatus_ONLINE,
+			"ec1", "Debian", nil),
+		newItem(grpc_inventory_manager_go.InventoryEntityType_DEVICE, "d1", grpc_inventory_manager_go.ConnectedStatus_OFFLINE,
+			"", "", map[string]string{"env": "prod"}),
+	}
+	ids := func(items []*inventoryItem) []string {
+		result := make([]string, 0, len(items))
+		for _, item := range items {
+			result = append(result, item.id)
+		}
+		return result
+	}
+
+	ginkgo.Context("filtering the items", func() {
+		cases := []struct {
+			description string
+			filter      *grpc_inventory_manager_go.InventoryFilter
+			expected    []string
+		}{
+			{"no filter", nil, []string{"a1", "a2", "a3", "ec1", "d1"}},
+			{"an empty filter", &grpc_inventory_manager_go.InventoryFilter{}, []string{"a1", "a2", "a3", "ec1", "d1"}},
+			{"entity types", &grpc_inventory_manager_go.InventoryFilter{
+				EntityTypes: []grpc_inventory_manager_go.InventoryEntityType{
+					grpc_inventory_manager_go.InventoryEntityType_CONTROLLER, grpc_inventory_manager_go.InventoryEntityType_DEVICE},
+			}, []string{"ec1", "d1"}},
+			{"status", &grpc_inventory_manager_go.InventoryFilter{
+				Status: []grpc_inventory_manager_go.ConnectedStatus{grpc_inventory_manager_go.ConnectedStatus_OFFLINE},
+			}, []string{"a2", "d1"}},
+			{"edge controller", &grpc_inventory_manager_go.InventoryFilter{EdgeControllerId: "ec1"}, []string{"a1", "a2", "ec1"}},
+			{"labels", &grpc_inventory_manager_go.InventoryFilter{Labels: map[string]string{"env": "prod"}}, []string{"a1", "a3", "d1"}},
+			{"attributes", &grpc_inventory_manager_go.InventoryFilter{Attributes: map[string]string{"rack": "r1"}}, []string{"a1"}},
+			{"os name ignoring the case", &grpc_inventory_manager_go.InventoryFilter{OsName: "UBUNTU"}, []string{"a1", "a3"}},
+			{"type, status and labels", &grpc_inventory_manager_go.InventoryFilter{
+				EntityTypes: []grpc_inventory_manager_go.InventoryEntityType{grpc_inventory_manager_go.InventoryEntityType_ASSET},
+				Status:      []grpc_inventory_manager_go.ConnectedStatus{grpc_inventory_manager_go.ConnectedStatus_ONLINE},
+				Labels:      map[string]string{"env": "prod"},
+			}, []string{"a1", "a3"}},
+			{"controller and os name", &grpc_inventory_manager_go.InventoryFilter{EdgeControllerId: "ec2", OsName: "windows"}, []string{}},
+			{"a label with a different value", &grpc_inventory_manager_go.InventoryFilter{Labels: map[string]string{"env": "test"}}, []string{}},
+		}
+		for _, current := range cases {
+			current := current
+			ginkgo.It("should filter by "+current.description, func() {
+				gomega.Expect(ids(filterItems(items, current.filter))).To(gomega.Equal(current.expected))
+				for _, item := range items {
+					if current.filter != nil {
+						gomega.Expect(matchesFilter(item, current.filter)).To(gomega.Equal(contains(current.expected, item.id)))
+					}
+				}
+			})
+		}
+	})
+
+	ginkgo.Context("sorting the items", func() {
+		newSortable := func(id string, name string, created int64, lastAlive int64) *inventoryItem {
+			return &inventoryItem{id: id, name: name, created: created, lastAlive: lastAlive}
+		}
+		sortable := func() []*inventoryItem {
+			return []*inventoryItem{
+				newSortable("c", "beta", 20, 300),
+				newSortable("a", "beta", 10, 100),
+				newSortable("b", "alpha", 20, 200),
+				newSortable("d", "gamma", 10, 100),
+			}
+		}
+		cases := []struct {
+			description string
+			sortBy      grpc_inventory_manager_go.InventorySortField
+			descending  bool
+			expected    []string
+		}{
+			{"name", grpc_inventory_manager_go.InventorySortField_NAME, false, []string{"b", "a", "c", "d"}},
+			{"name descending", grpc_inventory_manager_go.InventorySortField_NAME, true, []string{"d", "c", "a", "b"}},
+			{"creation time", grpc_inventory_manager_go.InventorySortField_CREATED, false, []string{"a", "d", "b", "c"}},
+			{"last alive descending", grpc_inventory_manager_go.InventorySortField_LAST_ALIVE, true, []string{"c", "b", "d", "a"}},
+		}
+		for _, current := range cases {
+			current := current
+			ginkgo.It("should sort by "+current.description+" breaking ties by identifier", func() {
+				sorted := sortable()
+				sortItems(sorted, current.sortBy, current.descending)
+				gomega.Expect(ids(sorted)).To(gomega.Equal(current.expected))
+			})
+		}
+	})
+
+	ginkgo.Context("paging the inventory", func() {
+
+		var manager Manager
+		request := func(token string) *grpc_inventory_manager_go.ListInventoryRequest {
+			return &grpc_inventory_manager_go.ListInventoryRequest{
+				OrganizationId: "org",
+				PageSize:       2,
+				PageToken:      token,
+				SortBy:         grpc_inventory_manager_go.InventorySortField_CREATED,
+				Filter: &grpc_inventory_manager_go.InventoryFilter{
+					EntityTypes: []grpc_inventory_manager_go.InventoryEntityType{grpc_inventory_manager_go.InventoryEntityType_ASSET},
+				},
+			}
+		}
+
+		ginkgo.BeforeEach(func() {
+			assets := make([]*grpc_inventory_go.Asset, 0)
+			// the assets are returned in a different order on every call, with repeated creation times
+			for _, assetID := range []string{"a5", "a3", "a1", "a4", "a2"} {
+				assets = append(assets, &grpc_inventory_go.Asset{OrganizationId: "org", AssetId: assetID, Created: 100})
+			}
+			assets[0].Created = 50
+			manager = newTestManager(&fakeDevices{}, &fakeAssets{assets: assets}, &fakeControllers{})
+		})
+
+		ginkgo.It("should return every item once in a stable order across the pages", func() {
+			listed := make([]string, 0)
+			token := ""
+			pages := 0
+			for {
+				page, err := manager.ListPaged(request(token))
+				gomega.Expect(err).To(gomega.Succeed())
+				gomega.Expect(page.TotalSize).To(gomega.Equal(int32(5)))
+				for _, item := range page.Items {
+					listed = append(listed, item.Asset.AssetId)
+				}
+				pages++
+				if page.NextPageToken == "" {
+					break
+				}
+				token = page.NextPageToken
+			}
+			gomega.Expect(pages).To(gomega.Equal(3))
+			gomega.Expect(listed).To(gomega.Equal([]string{"a5", "a1", "a2", "a3", "a4"}))
+		})
+
+		ginkgo.It("should reject a token of a different query", func() {
+			page, err := manager.ListPaged(request(""))
+			gomega.Expect(err).To(gomega.Succeed())
+			stale := request(page.NextPageToken)
+			stale.SortDescending = true
+			_, err = manager.ListPaged(stale)
+			gomega.Expect(err).NotTo(gomega.Succeed())
+		})
+
+		ginkgo.It("should reject a token with a modified query", func() {
+			token, err := encodePageToken(pageToken{Offset: 2, Query: "other"})
+			gomega.Expect(err).To(gomega.Succeed())
+			_, lErr := manager.ListPaged(request(token))
+			gomega.Expect(lErr).NotTo(gomega.Succeed())
+		})
+
+		ginkgo.It("should return an empty last page for an offset past the end", func() {
+			query, err := queryHash(request(""))
+			gomega.Expect(err).To(gomega.Succeed())
+			token, err := encodePageToken(pageToken{Offset: 10, Query: query})
+			gomega.Expect(err).To(gomega.Succeed())
+			page, lErr := manager.ListPaged(request(token))
+			gomega.Expect(lErr).To(gomega.Succeed())
+			gomega.Expect(page.Items).To(gomega.BeEmpty())
+			gomega.Expect(page.NextPageToken).To(gomega.BeEmpty())
+		})
+	})
+
+	ginkgo.Context("encoding the page tokens", func() {
+
+		ginkgo.It("should decode an encoded token", func() {
+			encoded, err := encodePageToken(pageToken{Offset: 42, Query: "hash"})
+			gomega.Expect(err).To(gomega.Succeed())
+			decoded, err := decodePageToken(encoded)
+			gomega.Expect(err).To(gomega.Succeed())
+			gomega.Expect(*decoded).To(gomega.Equal(pageToken{Offset: 42, Query: "hash"}))
+		})
+
+		tampered := []struct {
+			description string
+			token       string
+		}{
+			{"not base64", "not a token!"},
+			{"not JSON", "bm90IGpzb24"},
+			{"a negative offset", "eyJvZmZzZXQiOi0xLCJxdWVyeSI6Imhhc2gifQ"},
+			{"an offset that is not a number", "eyJvZmZzZXQiOiIxIiwicXVlcnkiOiJoYXNoIn0"},
+		}
+		for _, current := range tampered {
+			current := current
+			ginkgo.It("should reject a token with "+current.description, func() {
+				_, err := decodePageToken(current.token)
+				gomega.Expect(err).NotTo(gomega.Succeed())
+			})
+		}
+	})
+})
+
+func contains(values []string, value string) bool {
+	for _, current := range values {
+		if current == value {
+			return true
+		}
+	}
+	return false
+}
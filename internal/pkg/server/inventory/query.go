@@ -0,0 +1,240 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inventory
+
+import (
+	"fmt"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-common-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"github.com/nalej/inventory-manager/internal/pkg/query"
+	"github.com/satori/go.uuid"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LabelFieldPrefix with the prefix of the query fields that refer to a label.
+const LabelFieldPrefix = "label."
+
+// AttributeFieldPrefix with the prefix of the query fields that refer to a custom attribute.
+const AttributeFieldPrefix = "attribute."
+
+// QueryFields with the fields that can be used in an inventory query besides the labels.
+var QueryFields = map[string]bool{
+	"type": true, "id": true, "name": true, "edge_controller_id": true, "status": true,
+	"created": true, "last_alive": true, "location": true,
+	"os.name": true, "os.version": true, "os.class": true, "os.architecture": true,
+	"hardware.ram": true, "hardware.cpus": true, "storage.total": true,
+	"hardware.ram_bytes": true, "storage.total_bytes": true, "resources.implausible": true,
+}
+
+var entityTypeNames = map[grpc_inventory_manager_go.InventoryEntityType]string{
+	grpc_inventory_manager_go.InventoryEntityType_ASSET:      "asset",
+	grpc_inventory_manager_go.InventoryEntityType_CONTROLLER: "controller",
+	grpc_inventory_manager_go.InventoryEntityType_DEVICE:     "device",
+}
+
+// Field returns the value of a query field for an item.
+func (i *inventoryItem) Field(name string) (string, bool) {
+	if strings.HasPrefix(name, LabelFieldPrefix) {
+		value, exists := i.labels[strings.TrimPrefix(name, LabelFieldPrefix)]
+		return value, exists
+	}
+	if strings.HasPrefix(name, AttributeFieldPrefix) {
+		value, exists := i.labels[entities.AttributeLabel(strings.TrimPrefix(name, AttributeFieldPrefix))]
+		return value, exists
+	}
+	switch name {
+	case "type":
+		return entityTypeNames[i.entityType], true
+	case "id":
+		return i.id, true
+	case "name":
+		return i.name, true
+	case "edge_controller_id":
+		return i.controllerID, i.controllerID != ""
+	case "status":
+		return i.status.String(), true
+	case "created":
+		return strconv.FormatInt(i.created, 10), true
+	case "last_alive":
+		return strconv.FormatInt(i.lastAlive, 10), i.lastAlive != 0
+	case "location":
+		if i.location == nil {
+			return "", false
+		}
+		return i.location.Geolocation, true
+	case "os.name", "os.version", "os.class", "os.architecture":
+		if i.os == nil {
+			return "", false
+		}
+		switch name {
+		case "os.name":
+			return i.os.Name, true
+		case "os.version":
+			return i.os.Version, true
+		case "os.class":
+			return i.os.Class.String(), true
+		default:
+			return i.os.Architecture, true
+		}
+	case "hardware.ram_bytes":
+		return strconv.FormatInt(i.normalizedResources().RamBytes, 10), i.hardware != nil
+	case "storage.total_bytes":
+		return strconv.FormatInt(i.normalizedResources().StorageBytes, 10), i.storage != nil
+	case "resources.implausible":
+		return strconv.FormatBool(i.normalizedResources().Implausible), true
+	case "hardware.ram", "hardware.cpus":
+		if i.hardware == nil {
+			return "", false
+		}
+		if name == "hardware.ram" {
+			return strconv.FormatInt(i.hardware.InstalledRam, 10), true
+		}
+		var cores int32
+		for _, cpu := range i.hardware.Cpus {
+			if cpu != nil {
+				cores += cpu.NumCores
+			}
+		}
+		return strconv.FormatInt(int64(cores), 10), true
+	case "storage.total":
+		if i.storage == nil {
+			return "", false
+		}
+		var total int64
+		for _, storage := range i.storage {
+			if storage != nil {
+				total += storage.TotalCapacity
+			}
+		}
+		return strconv.FormatInt(total, 10), true
+	}
+	return "", false
+}
+
+// hasNamePrefix checks if a field is a prefix followed by a name, e.g. label.env.
+func hasNamePrefix(field string, prefix string) bool {
+	return strings.HasPrefix(field, prefix) && len(field) > len(prefix)
+}
+
+// ParseQuery parses an inventory query checking that all the fields are known.
+func ParseQuery(queryString string) (query.Expression, derrors.Error) {
+	expression, err := query.Parse(queryString)
+	if err != nil {
+		return nil, err
+	}
+	for _, field := range expression.Fields() {
+		if !QueryFields[field] && !hasNamePrefix(field, LabelFieldPrefix) && !hasNamePrefix(field, AttributeFieldPrefix) {
+			return nil, derrors.NewInvalidArgumentError(fmt.Sprintf("unknown field %s", field))
+		}
+	}
+	return expression, nil
+}
+
+// ResolveQuery returns the expression of a query, either received as a string or as the identifier of a saved query.
+func (m *Manager) ResolveQuery(organizationID string, queryString string, queryID string) (query.Expression, string, derrors.Error) {
+	if queryID != "" {
+		saved, err := m.savedQueries.Get(organizationID, queryID)
+		if err != nil {
+			return nil, "", err
+		}
+		queryString = saved.Query
+	}
+	expression, err := ParseQuery(queryString)
+	if err != nil {
+		return nil, "", err
+	}
+	return expression, queryString, nil
+}
+
+// Select returns the items of the inventory of an organization matching an expression.
+func (m *Manager) Select(organizationID string, expression query.Expression) ([]*grpc_inventory_manager_go.InventoryItem, []*grpc_inventory_manager_go.InventorySourceError, error) {
+	list, err := m.List(&grpc_organization_go.OrganizationId{OrganizationId: organizationID})
+	if err != nil {
+		return nil, nil, err
+	}
+	result := make([]*grpc_inventory_manager_go.InventoryItem, 0)
+	for _, item := range toInventoryItems(list) {
+		if expression.Matches(item) {
+			result = append(result, item.item)
+		}
+	}
+	return result, list.Errors, nil
+}
+
+// Query searches the inventory of an organization.
+func (m *Manager) Query(request *grpc_inventory_manager_go.QueryRequest) (*grpc_inventory_manager_go.QueryResult, error) {
+	expression, queryString, qErr := m.ResolveQuery(request.OrganizationId, request.Query, request.QueryId)
+	if qErr != nil {
+		return nil, conversions.ToGRPCError(qErr)
+	}
+	items, sourceErrors, err := m.Select(request.OrganizationId, expression)
+	if err != nil {
+		return nil, err
+	}
+	return &grpc_inventory_manager_go.QueryResult{
+		OrganizationId: request.OrganizationId,
+		Query:          queryString,
+		Items:          items,
+		Errors:         sourceErrors,
+	}, nil
+}
+
+// AddSavedQuery stores a query so it can be reused.
+func (m *Manager) AddSavedQuery(request *grpc_inventory_manager_go.AddSavedQueryRequest) (*grpc_inventory_manager_go.SavedQuery, error) {
+	_, qErr := ParseQuery(request.Query)
+	if qErr != nil {
+		return nil, conversions.ToGRPCError(qErr)
+	}
+	saved := &grpc_inventory_manager_go.SavedQuery{
+		OrganizationId: request.OrganizationId,
+		QueryId:        uuid.NewV4().String(),
+		Name:           request.Name,
+		Query:          request.Query,
+		Created:        time.Now().Unix(),
+	}
+	err := m.savedQueries.Add(saved)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return saved, nil
+}
+
+// ListSavedQueries retrieves the saved queries of an organization.
+func (m *Manager) ListSavedQueries(organizationID *grpc_organization_go.OrganizationId) (*grpc_inventory_manager_go.SavedQueryList, error) {
+	queries, err := m.savedQueries.List(organizationID.OrganizationId)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return &grpc_inventory_manager_go.SavedQueryList{
+		SavedQueries: queries,
+	}, nil
+}
+
+// RemoveSavedQuery removes a saved query.
+func (m *Manager) RemoveSavedQuery(queryID *grpc_inventory_manager_go.SavedQueryId) (*grpc_common_go.Success, error) {
+	err := m.savedQueries.Remove(queryID.OrganizationId, queryID.QueryId)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return &grpc_common_go.Success{}, nil
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inventory
+
+import (
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"github.com/nalej/inventory-manager/internal/pkg/units"
+	"sort"
+	"strings"
+)
+
+// MBPerGB is used to report the totals in GB.
+const MBPerGB = 1024
+
+// UnknownBreakdownKey groups the entities without a value for the breakdown, e.g. devices without OS information.
+const UnknownBreakdownKey = "unknown"
+
+// breakdown accumulates the number of entities and their resources for each value of a key.
+type breakdown map[string]*grpc_inventory_manager_go.SummaryBreakdown
+
+func newBreakdown() breakdown {
+	return make(breakdown)
+}
+
+func (b breakdown) add(key string, resources *grpc_inventory_manager_go.ResourceTotals) {
+	if key == "" {
+		key = UnknownBreakdownKey
+	}
+	entry, exists := b[key]
+	if !exists {
+		entry = &grpc_inventory_manager_go.SummaryBreakdown{
+			Key:    key,
+			Totals: &grpc_inventory_manager_go.ResourceTotals{},
+		}
+		b[key] = entry
+	}
+	entry.Count++
+	addResources(entry.Totals, resources)
+}
+
+// toList returns the entries sorted by key.
+func (b breakdown) toList() []*grpc_inventory_manager_go.SummaryBreakdown {
+	result := make([]*grpc_inventory_manager_go.SummaryBreakdown, 0, len(b))
+	for _, entry := range b {
+		result = append(result, entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+// addResources adds the resources to the totals. The values in MB are computed from the totals in bytes.
+func addResources(totals *grpc_inventory_manager_go.ResourceTotals, resources *grpc_inventory_manager_go.ResourceTotals) {
+	totals.NumCpu += resources.NumCpu
+	totals.StorageBytes += resources.StorageBytes
+	totals.RamBytes += resources.RamBytes
+	totals.StorageMb = totals.StorageBytes / units.BytesPerMB
+	totals.RamMb = totals.RamBytes / units.BytesPerMB
+}
+
+// normalizedResources returns the resources of an entity in bytes. They are computed for the entities that do not
+// include them, e.g. those stored in a snapshot taken by a previous version.
+func (i *inventoryItem) normalizedResources() *grpc_inventory_manager_go.NormalizedResources {
+	if i.normalized == nil {
+		i.normalized = entities.NormalizeResources(i.hardware, i.storage, i.labels, entities.ResourceRangesOf(i.entityType))
+	}
+	return i.normalized
+}
+
+// resources returns the number of cores, the storage and the RAM of an entity.
+func (i *inventoryItem) resources() *grpc_inventory_manager_go.ResourceTotals {
+	normalized := i.normalizedResources()
+	return &grpc_inventory_manager_go.ResourceTotals{
+		NumCpu:       normalized.NumCpu,
+		StorageBytes: normalized.StorageBytes,
+		RamBytes:     normalized.RamBytes,
+		StorageMb:    normalized.StorageBytes / units.BytesPerMB,
+		RamMb:        normalized.RamBytes / units.BytesPerMB,
+	}
+}
+
+// osFamily returns the class of the operating system of an entity, e.g. linux.
+func (i *inventoryItem) osFamily() string {
+	if i.os == nil {
+		return ""
+	}
+	return strings.ToLower(i.os.Class.String())
+}
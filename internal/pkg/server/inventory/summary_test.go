@@ -0,0 +1,131 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inventory
+
+import (
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/inventory-manager/internal/pkg/units"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Inventory summary", func() {
+
+	// halfMB makes the totals in MB differ from the sum of the values in MB of each entity.
+	const halfMB = units.BytesPerMB / 2
+	const gigabyte = 1024 * units.BytesPerMB
+
+	ginkgo.It("should group the entities without a key as unknown and sort the breakdown by key", func() {
+		entries := newBreakdown()
+		entries.add("windows", &grpc_inventory_manager_go.ResourceTotals{NumCpu: 2})
+		entries.add("", &grpc_inventory_manager_go.ResourceTotals{NumCpu: 1})
+		entries.add("linux", &grpc_inventory_manager_go.ResourceTotals{NumCpu: 4})
+		entries.add("linux", &grpc_inventory_manager_go.ResourceTotals{NumCpu: 4})
+		list := entries.toList()
+		gomega.Expect(list).To(gomega.HaveLen(3))
+		gomega.Expect(list[0].Key).To(gomega.Equal("linux"))
+		gomega.Expect(list[0].Count).To(gomega.Equal(int64(2)))
+		gomega.Expect(list[0].Totals.NumCpu).To(gomega.Equal(int64(8)))
+		gomega.Expect(list[1].Key).To(gomega.Equal(UnknownBreakdownKey))
+		gomega.Expect(list[2].Key).To(gomega.Equal("windows"))
+	})
+
+	ginkgo.It("should compute the totals in MB from the totals in bytes", func() {
+		totals := &grpc_inventory_manager_go.ResourceTotals{}
+		for index := 0; index < 3; index++ {
+			addResources(totals, &grpc_inventory_manager_go.ResourceTotals{
+				NumCpu:       1,
+				RamBytes:     units.BytesPerMB + halfMB,
+				RamMb:        1,
+				StorageBytes: halfMB,
+			})
+		}
+		gomega.Expect(totals).To(gomega.Equal(&grpc_inventory_manager_go.ResourceTotals{
+			NumCpu:       3,
+			RamBytes:     3*units.BytesPerMB + 3*halfMB,
+			RamMb:        4,
+			StorageBytes: 3 * halfMB,
+			StorageMb:    1,
+		}))
+	})
+
+	ginkgo.It("should break the summary down by operating system and status", func() {
+		list := &grpc_inventory_manager_go.InventoryList{
+			Assets: []*grpc_inventory_manager_go.Asset{
+				{
+					AssetId:   "a1",
+					Status:    grpc_inventory_manager_go.ConnectedStatus_ONLINE,
+					Os:        &grpc_inventory_go.OperatingSystemInfo{Class: grpc_inventory_go.OperatingSystemClass_WINDOWS},
+					Resources: &grpc_inventory_manager_go.NormalizedResources{NumCpu: 2, RamBytes: units.BytesPerMB + halfMB, StorageBytes: gigabyte},
+				},
+				{
+					AssetId:   "a2",
+					Status:    grpc_inventory_manager_go.ConnectedStatus_OFFLINE,
+					Os:        &grpc_inventory_go.OperatingSystemInfo{Class: grpc_inventory_go.OperatingSystemClass_LINUX},
+					Resources: &grpc_inventory_manager_go.NormalizedResources{NumCpu: 4, RamBytes: units.BytesPerMB + halfMB, StorageBytes: gigabyte},
+				},
+			},
+			Controllers: []*grpc_inventory_manager_go.EdgeController{
+				{
+					EdgeControllerId: "ec",
+					Status:           grpc_inventory_manager_go.ConnectedStatus_ONLINE,
+					AssetInfo: &grpc_inventory_go.AssetInfo{
+						Os: &grpc_inventory_go.OperatingSystemInfo{Class: grpc_inventory_go.OperatingSystemClass_LINUX},
+					},
+					Resources: &grpc_inventory_manager_go.NormalizedResources{NumCpu: 1},
+				},
+			},
+			Devices: []*grpc_inventory_manager_go.Device{
+				{
+					DeviceId:  "d1",
+					Status:    grpc_inventory_manager_go.ConnectedStatus_OFFLINE,
+					Resources: &grpc_inventory_manager_go.NormalizedResources{},
+				},
+			},
+		}
+		manager := Manager{}
+		summary := manager.SummaryFromList("org", list)
+
+		gomega.Expect(summary.Totals).To(gomega.Equal(&grpc_inventory_manager_go.ResourceTotals{
+			NumCpu:       7,
+			RamBytes:     3 * units.BytesPerMB,
+			RamMb:        3,
+			StorageBytes: 2 * gigabyte,
+			StorageMb:    2048,
+		}))
+		gomega.Expect(summary.TotalNumCpu).To(gomega.Equal(int64(7)))
+		gomega.Expect(summary.TotalStorage).To(gomega.Equal(int64(2)))
+		gomega.Expect(summary.TotalRam).To(gomega.Equal(int64(0)))
+
+		keys := func(entries []*grpc_inventory_manager_go.SummaryBreakdown) map[string]int64 {
+			result := make(map[string]int64)
+			for _, entry := range entries {
+				result[entry.Key] = entry.Count
+			}
+			return result
+		}
+		gomega.Expect(keys(summary.ByOsFamily)).To(gomega.Equal(map[string]int64{"linux": 2, "windows": 1, UnknownBreakdownKey: 1}))
+		gomega.Expect(summary.ByOsFamily[0].Key).To(gomega.Equal("linux"))
+		gomega.Expect(summary.ByOsFamily[0].Totals.NumCpu).To(gomega.Equal(int64(5)))
+		gomega.Expect(keys(summary.ByStatus)).To(gomega.Equal(map[string]int64{"online": 2, "offline": 2}))
+		gomega.Expect(summary.ByStatus[0].Key).To(gomega.Equal("offline"))
+		gomega.Expect(summary.ByStatus[0].Totals.NumCpu).To(gomega.Equal(int64(4)))
+		gomega.Expect(summary.ByStatus[0].Totals.StorageMb).To(gomega.Equal(int64(1024)))
+		gomega.Expect(summary.ByStatus[1].Totals.RamMb).To(gomega.Equal(int64(1)))
+	})
+})
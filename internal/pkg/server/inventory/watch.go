@@ -0,0 +1,60 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inventory
+
+import (
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/rs/zerolog/log"
+)
+
+// Watch sends the events of an organization until the client disconnects. The events after the requested revision
+// are sent first, and a RESYNC_REQUIRED event is sent if they are no longer available.
+func (m *Manager) Watch(request *grpc_inventory_manager_go.WatchRequest, stream grpc_inventory_manager_go.Inventory_WatchServer) error {
+	subscription, backlog := m.broadcaster.Subscribe(request.OrganizationId, request.FromRevision)
+	defer m.broadcaster.Unsubscribe(subscription)
+	log.Debug().Str("organization_id", request.OrganizationId).Int64("from_revision", request.FromRevision).
+		Int("backlog", len(backlog)).Msg("inventory watch started")
+
+	for _, event := range backlog {
+		err := stream.Send(event)
+		if err != nil {
+			return err
+		}
+	}
+	for {
+		select {
+		case event, open := <-subscription.Events:
+			if !open {
+				if m.broadcaster.Closed() {
+					return conversions.ToGRPCError(derrors.NewUnavailableError("the inventory manager is shutting down, resume from the last revision").
+						WithParams(request.OrganizationId))
+				}
+				return conversions.ToGRPCError(derrors.NewResourceExhaustedError("the watcher is not receiving the events fast enough, resume from the last revision").
+					WithParams(request.OrganizationId))
+			}
+			err := stream.Send(event)
+			if err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			log.Debug().Str("organization_id", request.OrganizationId).Msg("inventory watch finished")
+			return nil
+		}
+	}
+}
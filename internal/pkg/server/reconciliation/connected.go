@@ -0,0 +1,88 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package reconciliation
+
+import (
+	"github.com/nalej/grpc-inventory-manager-go"
+	"sync"
+	"time"
+)
+
+// ConnectedAgent contains the last alive report of an agent as seen by the edge inventory proxy.
+type ConnectedAgent struct {
+	// EdgeControllerId of the controller that reported the agent.
+	EdgeControllerId string
+	// LastAlive timestamp received for the agent.
+	LastAlive int64
+	// Received time when the report reached the inventory manager.
+	Received time.Time
+}
+
+// ConnectedAgents keeps the view of the proxy regarding the agents that are connected. The view
+// is built from the agents alive messages that the proxy forwards to the inventory manager.
+type ConnectedAgents struct {
+	sync.Mutex
+	// agents indexed by organization_id and asset_id.
+	agents map[string]map[string]ConnectedAgent
+}
+
+func NewConnectedAgents() *ConnectedAgents {
+	return &ConnectedAgents{
+		agents: make(map[string]map[string]ConnectedAgent, 0),
+	}
+}
+
+// OnAgentsAlive registers the agents included in an alive message.
+func (ca *ConnectedAgents) OnAgentsAlive(alive *grpc_inventory_manager_go.AgentsAlive) {
+	ca.Lock()
+	defer ca.Unlock()
+	orgAgents, exists := ca.agents[alive.OrganizationId]
+	if !exists {
+		orgAgents = make(map[string]ConnectedAgent, 0)
+		ca.agents[alive.OrganizationId] = orgAgents
+	}
+	now := time.Now()
+	for assetID, timestamp := range alive.Agents {
+		orgAgents[assetID] = ConnectedAgent{
+			EdgeControllerId: alive.EdgeControllerId,
+			LastAlive:        timestamp,
+			Received:         now,
+		}
+	}
+}
+
+// List returns the agents of an organization reported after a given time.
+func (ca *ConnectedAgents) List(organizationID string, since time.Time) map[string]ConnectedAgent {
+	ca.Lock()
+	defer ca.Unlock()
+	result := make(map[string]ConnectedAgent, 0)
+	for assetID, agent := range ca.agents[organizationID] {
+		if agent.Received.After(since) {
+			result[assetID] = agent
+		}
+	}
+	return result
+}
+
+// Remove deletes an agent from the view.
+func (ca *ConnectedAgents) Remove(organizationID string, assetID string) {
+	ca.Lock()
+	defer ca.Unlock()
+	if orgAgents, exists := ca.agents[organizationID]; exists {
+		delete(orgAgents, assetID)
+	}
+}
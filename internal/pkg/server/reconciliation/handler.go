@@ -0,0 +1,55 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package reconciliation
+
+import (
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/net/context"
+)
+
+type Handler struct {
+	manager Manager
+}
+
+func NewHandler(manager Manager) *Handler {
+	return &Handler{
+		manager: manager,
+	}
+}
+
+// Reconcile checks the inventory of an organization looking for inconsistencies.
+func (h *Handler) Reconcile(_ context.Context, request *grpc_inventory_manager_go.ReconcileRequest) (*grpc_inventory_manager_go.ReconciliationReport, error) {
+	vErr := entities.ValidReconcileRequest(request)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	log.Debug().Str("organization_id", request.OrganizationId).Bool("repair", request.Repair).Msg("Reconcile")
+	return h.manager.Reconcile(request)
+}
+
+// GetLastReconciliationReport retrieves the last report generated for an organization.
+func (h *Handler) GetLastReconciliationReport(_ context.Context, orgID *grpc_organization_go.OrganizationId) (*grpc_inventory_manager_go.ReconciliationReport, error) {
+	vErr := entities.ValidOrganizationID(orgID)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.GetLastReconciliationReport(orgID)
+}
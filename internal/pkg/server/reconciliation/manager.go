@@ -0,0 +1,454 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package reconciliation
+
+import (
+	"context"
+	"fmt"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-common-go"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-network-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/grpc-vpn-server-go"
+	"github.com/nalej/inventory-manager/internal/pkg/config"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"github.com/nalej/inventory-manager/internal/pkg/jobs"
+	"github.com/nalej/inventory-manager/internal/pkg/server/contexts"
+	"github.com/rs/zerolog/log"
+	"sync"
+	"time"
+)
+
+// reportStore keeps the last reconciliation report of each organization.
+type reportStore struct {
+	sync.Mutex
+	reports map[string]*grpc_inventory_manager_go.ReconciliationReport
+}
+
+type Manager struct {
+	orgClient         grpc_organization_go.OrganizationsClient
+	controllersClient grpc_inventory_go.ControllersClient
+	assetsClient      grpc_inventory_go.AssetsClient
+	vpnClient         grpc_vpn_server_go.VPNServerClient
+	netMngrClient     grpc_network_go.ServiceDNSClient
+	connectedAgents   *ConnectedAgents
+	lastReports       *reportStore
+	cfg               config.Config
+}
+
+func NewManager(
+	orgClient grpc_organization_go.OrganizationsClient, controllersClient grpc_inventory_go.ControllersClient,
+	assetsClient grpc_inventory_go.AssetsClient, vpnClient grpc_vpn_server_go.VPNServerClient,
+	netMngrClient grpc_network_go.ServiceDNSClient, connectedAgents *ConnectedAgents, cfg config.Config) Manager {
+	return Manager{
+		orgClient:         orgClient,
+		controllersClient: controllersClient,
+		assetsClient:      assetsClient,
+		vpnClient:         vpnClient,
+		netMngrClient:     netMngrClient,
+		connectedAgents:   connectedAgents,
+		lastReports: &reportStore{
+			reports: make(map[string]*grpc_inventory_manager_go.ReconciliationReport, 0),
+		},
+		cfg: cfg,
+	}
+}
+
+// Run launches the periodic reconciliation of all the organizations.
+func (m *Manager) Run(group *jobs.Group) {
+	if m.cfg.ReconciliationPeriod == 0 {
+		log.Info().Msg("periodic reconciliation is disabled")
+		return
+	}
+	log.Debug().Str("period", m.cfg.ReconciliationPeriod.String()).Msg("launching periodic reconciliation")
+	group.Every(m.cfg.ReconciliationPeriod, m.reconcileAll)
+}
+
+// reconcileAll reconciles the organizations until the jobs are stopped.
+func (m *Manager) reconcileAll(stopped context.Context) {
+	ctx, cancel := contexts.SMContext()
+	defer cancel()
+	organizations, err := m.orgClient.ListOrganizations(ctx, &grpc_common_go.Empty{})
+	if err != nil {
+		log.Error().Str("trace", conversions.ToDerror(err).DebugReport()).Msg("cannot list organizations to reconcile")
+		return
+	}
+	repair := m.cfg.ReconciliationPolicy == config.ReconciliationRepairOrphans
+	for _, org := range organizations.Organizations {
+		if stopped.Err() != nil {
+			return
+		}
+		_, err := m.reconcile(org.OrganizationId, repair)
+		if err != nil {
+			log.Warn().Str("organization_id", org.OrganizationId).Str("trace", conversions.ToDerror(err).DebugReport()).
+				Msg("reconciliation failed")
+		}
+	}
+}
+
+// Reconcile checks the inventory of an organization and repairs the inconsistencies if requested.
+func (m *Manager) Reconcile(request *grpc_inventory_manager_go.ReconcileRequest) (*grpc_inventory_manager_go.ReconciliationReport, error) {
+	if request.Repair && m.cfg.ReconciliationPolicy != config.ReconciliationRepairOrphans {
+		return nil, conversions.ToGRPCError(derrors.NewFailedPreconditionError("repair is not allowed by the reconciliation policy").
+			WithParams(m.cfg.ReconciliationPolicy))
+	}
+	return m.reconcile(request.OrganizationId, request.Repair)
+}
+
+// GetLastReconciliationReport retrieves the last report generated for an organization.
+func (m *Manager) GetLastReconciliationReport(organizationID *grpc_organization_go.OrganizationId) (*grpc_inventory_manager_go.ReconciliationReport, error) {
+	m.lastReports.Lock()
+	defer m.lastReports.Unlock()
+	report, exists := m.lastReports.reports[organizationID.OrganizationId]
+	if !exists {
+		return nil, conversions.ToGRPCError(derrors.NewNotFoundError("no reconciliation report found").WithParams(organizationID.OrganizationId))
+	}
+	return report, nil
+}
+
+func (m *Manager) reconcile(organizationID string, repair bool) (*grpc_inventory_manager_go.ReconciliationReport, error) {
+	orgID := &grpc_organization_go.OrganizationId{
+		OrganizationId: organizationID,
+	}
+
+	// the controllers are listed last so a controller that joins during the reconciliation is found
+	// before any of its assets, VPN user or DNS entry could be reported as an orphan
+	assets, err := m.listAssets(orgID)
+	if err != nil {
+		return nil, err
+	}
+	vpnUsers, err := m.listVPNUsers(organizationID)
+	if err != nil {
+		return nil, err
+	}
+	dnsEntries, err := m.listDNSEntries(orgID)
+	if err != nil {
+		return nil, err
+	}
+	controllers, err := m.listControllers(orgID)
+	if err != nil {
+		return nil, err
+	}
+	connected := m.connectedAgents.List(organizationID, time.Now().Add(-m.cfg.AssetThreshold))
+
+	report := &grpc_inventory_manager_go.ReconciliationReport{
+		OrganizationId:  organizationID,
+		Timestamp:       time.Now().Unix(),
+		Repair:          repair,
+		Inconsistencies: make([]*grpc_inventory_manager_go.Inconsistency, 0),
+	}
+	report.Inconsistencies = append(report.Inconsistencies, m.checkAssets(organizationID, assets, controllers)...)
+	report.Inconsistencies = append(report.Inconsistencies, m.checkControllers(organizationID, controllers, vpnUsers, dnsEntries)...)
+	report.Inconsistencies = append(report.Inconsistencies, m.checkVPNUsers(organizationID, vpnUsers, controllers)...)
+	report.Inconsistencies = append(report.Inconsistencies, m.checkDNSEntries(organizationID, dnsEntries, controllers)...)
+	report.Inconsistencies = append(report.Inconsistencies, m.checkConnectedAgents(organizationID, connected, assets)...)
+
+	if repair {
+		for _, inconsistency := range report.Inconsistencies {
+			m.repair(inconsistency)
+		}
+	}
+
+	m.lastReports.Lock()
+	m.lastReports.reports[organizationID] = report
+	m.lastReports.Unlock()
+
+	log.Info().Str("organization_id", organizationID).Int("inconsistencies", len(report.Inconsistencies)).
+		Bool("repair", repair).Msg("reconciliation finished")
+	return report, nil
+}
+
+// listControllers returns the controllers of an organization indexed by edge_controller_id.
+func (m *Manager) listControllers(orgID *grpc_organization_go.OrganizationId) (map[string]*grpc_inventory_go.EdgeController, error) {
+	ctx, cancel := contexts.SMContext()
+	defer cancel()
+	controllers, err := m.controllersClient.List(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]*grpc_inventory_go.EdgeController, 0)
+	for _, ec := range controllers.Controllers {
+		result[ec.EdgeControllerId] = ec
+	}
+	return result, nil
+}
+
+// listAssets returns the assets of an organization indexed by asset_id.
+func (m *Manager) listAssets(orgID *grpc_organization_go.OrganizationId) (map[string]*grpc_inventory_go.Asset, error) {
+	ctx, cancel := contexts.SMContext()
+	defer cancel()
+	assets, err := m.assetsClient.List(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]*grpc_inventory_go.Asset, 0)
+	for _, asset := range assets.Assets {
+		result[asset.AssetId] = asset
+	}
+	return result, nil
+}
+
+// listVPNUsers returns the VPN user names of the edge controllers indexed by edge_controller_id.
+func (m *Manager) listVPNUsers(organizationID string) (map[string]string, error) {
+	ctx, cancel := contexts.VPNManagerContext()
+	defer cancel()
+	users, err := m.vpnClient.ListVPNUsers(ctx, &grpc_vpn_server_go.GetVPNUserListRequest{
+		OrganizationId: organizationID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]string, 0)
+	for _, username := range users.Usernames {
+		edgeControllerID, isController := entities.GetEdgeControllerIDFromName(organizationID, username)
+		if isController {
+			result[edgeControllerID] = username
+		}
+	}
+	return result, nil
+}
+
+// listDNSEntries returns the FQDNs of the edge controllers indexed by edge_controller_id.
+func (m *Manager) listDNSEntries(orgID *grpc_organization_go.OrganizationId) (map[string]string, error) {
+	ctx, cancel := contexts.NetworkManagerContext()
+	defer cancel()
+	entries, err := m.netMngrClient.ListEntries(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]string, 0)
+	for _, entry := range entries.Entries {
+		if !hasTag(entry.Tags, entities.EdgeControllerDNSTag) {
+			continue
+		}
+		edgeControllerID, isController := entities.GetEdgeControllerIDFromFQDN(entry.Fqdn)
+		if isController {
+			result[edgeControllerID] = entry.Fqdn
+		}
+	}
+	return result, nil
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAssets looks for assets managed by controllers that no longer exist.
+func (m *Manager) checkAssets(organizationID string, assets map[string]*grpc_inventory_go.Asset,
+	controllers map[string]*grpc_inventory_go.EdgeController) []*grpc_inventory_manager_go.Inconsistency {
+	result := make([]*grpc_inventory_manager_go.Inconsistency, 0)
+	for _, asset := range assets {
+		if _, exists := controllers[asset.EdgeControllerId]; !exists {
+			result = append(result, &grpc_inventory_manager_go.Inconsistency{
+				OrganizationId:   organizationID,
+				Type:             grpc_inventory_manager_go.InconsistencyType_ORPHAN_ASSET,
+				EdgeControllerId: asset.EdgeControllerId,
+				AssetId:          asset.AssetId,
+				EntityId:         asset.AssetId,
+				Info:             "asset managed by a controller that does not exist",
+			})
+		}
+	}
+	return result
+}
+
+// checkControllers looks for controllers without VPN user or DNS entry.
+func (m *Manager) checkControllers(organizationID string, controllers map[string]*grpc_inventory_go.EdgeController,
+	vpnUsers map[string]string, dnsEntries map[string]string) []*grpc_inventory_manager_go.Inconsistency {
+	result := make([]*grpc_inventory_manager_go.Inconsistency, 0)
+	for edgeControllerID := range controllers {
+		if _, exists := vpnUsers[edgeControllerID]; !exists {
+			result = append(result, &grpc_inventory_manager_go.Inconsistency{
+				OrganizationId:   organizationID,
+				Type:             grpc_inventory_manager_go.InconsistencyType_CONTROLLER_WITHOUT_VPN_USER,
+				EdgeControllerId: edgeControllerID,
+				EntityId:         entities.GetEdgeControllerName(organizationID, edgeControllerID),
+				Info:             "controller without VPN user, it needs to join again",
+			})
+		}
+		if _, exists := dnsEntries[edgeControllerID]; !exists {
+			result = append(result, &grpc_inventory_manager_go.Inconsistency{
+				OrganizationId:   organizationID,
+				Type:             grpc_inventory_manager_go.InconsistencyType_CONTROLLER_WITHOUT_DNS_ENTRY,
+				EdgeControllerId: edgeControllerID,
+				EntityId:         entities.GetEdgeControllerFQDN(edgeControllerID),
+				Info:             "controller without DNS entry, it will be registered on the next start",
+			})
+		}
+	}
+	return result
+}
+
+// checkVPNUsers looks for VPN users of controllers that no longer exist.
+func (m *Manager) checkVPNUsers(organizationID string, vpnUsers map[string]string,
+	controllers map[string]*grpc_inventory_go.EdgeController) []*grpc_inventory_manager_go.Inconsistency {
+	result := make([]*grpc_inventory_manager_go.Inconsistency, 0)
+	for edgeControllerID, username := range vpnUsers {
+		if _, exists := controllers[edgeControllerID]; !exists {
+			result = append(result, &grpc_inventory_manager_go.Inconsistency{
+				OrganizationId:   organizationID,
+				Type:             grpc_inventory_manager_go.InconsistencyType_ORPHAN_VPN_USER,
+				EdgeControllerId: edgeControllerID,
+				EntityId:         username,
+				Info:             "VPN user of a controller that does not exist",
+			})
+		}
+	}
+	return result
+}
+
+// checkDNSEntries looks for DNS entries of controllers that no longer exist.
+func (m *Manager) checkDNSEntries(organizationID string, dnsEntries map[string]string,
+	controllers map[string]*grpc_inventory_go.EdgeController) []*grpc_inventory_manager_go.Inconsistency {
+	result := make([]*grpc_inventory_manager_go.Inconsistency, 0)
+	for edgeControllerID, fqdn := range dnsEntries {
+		if _, exists := controllers[edgeControllerID]; !exists {
+			result = append(result, &grpc_inventory_manager_go.Inconsistency{
+				OrganizationId:   organizationID,
+				Type:             grpc_inventory_manager_go.InconsistencyType_ORPHAN_DNS_ENTRY,
+				EdgeControllerId: edgeControllerID,
+				EntityId:         fqdn,
+				Info:             "DNS entry of a controller that does not exist",
+			})
+		}
+	}
+	return result
+}
+
+// checkConnectedAgents compares the agents reported by the proxy with the assets in system-model.
+func (m *Manager) checkConnectedAgents(organizationID string, connected map[string]ConnectedAgent,
+	assets map[string]*grpc_inventory_go.Asset) []*grpc_inventory_manager_go.Inconsistency {
+	result := make([]*grpc_inventory_manager_go.Inconsistency, 0)
+	for assetID, agent := range connected {
+		asset, exists := assets[assetID]
+		if !exists {
+			result = append(result, &grpc_inventory_manager_go.Inconsistency{
+				OrganizationId:   organizationID,
+				Type:             grpc_inventory_manager_go.InconsistencyType_UNKNOWN_AGENT,
+				EdgeControllerId: agent.EdgeControllerId,
+				AssetId:          assetID,
+				EntityId:         assetID,
+				Info:             "agent connected through the proxy without asset in system-model",
+			})
+			continue
+		}
+		if asset.EdgeControllerId != agent.EdgeControllerId {
+			result = append(result, &grpc_inventory_manager_go.Inconsistency{
+				OrganizationId:   organizationID,
+				Type:             grpc_inventory_manager_go.InconsistencyType_AGENT_CONTROLLER_MISMATCH,
+				EdgeControllerId: agent.EdgeControllerId,
+				AssetId:          assetID,
+				EntityId:         assetID,
+				Info:             fmt.Sprintf("agent reported by %s but managed by %s", agent.EdgeControllerId, asset.EdgeControllerId),
+			})
+		}
+	}
+	return result
+}
+
+// orphanTypes contains the inconsistencies of entities whose controller does not exist.
+var orphanTypes = map[grpc_inventory_manager_go.InconsistencyType]bool{
+	grpc_inventory_manager_go.InconsistencyType_ORPHAN_ASSET:     true,
+	grpc_inventory_manager_go.InconsistencyType_ORPHAN_VPN_USER:  true,
+	grpc_inventory_manager_go.InconsistencyType_ORPHAN_DNS_ENTRY: true,
+}
+
+// controllerExists checks again that the controller of an orphan does not exist before removing it.
+func (m *Manager) controllerExists(organizationID string, edgeControllerID string) (bool, derrors.Error) {
+	ctx, cancel := contexts.SMContext()
+	defer cancel()
+	_, err := m.controllersClient.Get(ctx, &grpc_inventory_go.EdgeControllerId{
+		OrganizationId:   organizationID,
+		EdgeControllerId: edgeControllerID,
+	})
+	if err != nil {
+		dErr := conversions.ToDerror(err)
+		if dErr.Type() == derrors.NotFound {
+			return false, nil
+		}
+		return false, dErr
+	}
+	return true, nil
+}
+
+// repair fixes an inconsistency if it is an orphan whose controller is still missing. The rest of
+// inconsistencies require the intervention of the user and are only reported.
+func (m *Manager) repair(inconsistency *grpc_inventory_manager_go.Inconsistency) {
+	if orphanTypes[inconsistency.Type] {
+		exists, err := m.controllerExists(inconsistency.OrganizationId, inconsistency.EdgeControllerId)
+		if err != nil {
+			inconsistency.RepairError = err.Error()
+			log.Warn().Str("organization_id", inconsistency.OrganizationId).Str("type", inconsistency.Type.String()).
+				Str("entity_id", inconsistency.EntityId).Str("trace", err.DebugReport()).Msg("cannot check the controller of an orphan")
+			return
+		}
+		if exists {
+			inconsistency.RepairError = "the controller exists, it joined during the reconciliation"
+			log.Info().Str("organization_id", inconsistency.OrganizationId).Str("type", inconsistency.Type.String()).
+				Str("entity_id", inconsistency.EntityId).Msg("orphan not repaired, its controller exists")
+			return
+		}
+	}
+	var err error
+	switch inconsistency.Type {
+	case grpc_inventory_manager_go.InconsistencyType_ORPHAN_ASSET:
+		ctx, cancel := contexts.SMContext()
+		_, err = m.assetsClient.Remove(ctx, &grpc_inventory_go.AssetId{
+			OrganizationId: inconsistency.OrganizationId,
+			AssetId:        inconsistency.AssetId,
+		})
+		cancel()
+		if err == nil {
+			m.connectedAgents.Remove(inconsistency.OrganizationId, inconsistency.AssetId)
+		}
+	case grpc_inventory_manager_go.InconsistencyType_ORPHAN_VPN_USER:
+		ctx, cancel := contexts.VPNManagerContext()
+		_, err = m.vpnClient.DeleteVPNUser(ctx, &grpc_vpn_server_go.DeleteVPNUserRequest{
+			Username:       inconsistency.EntityId,
+			OrganizationId: inconsistency.OrganizationId,
+		})
+		cancel()
+	case grpc_inventory_manager_go.InconsistencyType_ORPHAN_DNS_ENTRY:
+		ctx, cancel := contexts.NetworkManagerContext()
+		_, err = m.netMngrClient.DeleteEntry(ctx, &grpc_network_go.DeleteServiceDNSEntryRequest{
+			OrganizationId: inconsistency.OrganizationId,
+			Fqdn:           inconsistency.EntityId,
+		})
+		cancel()
+	default:
+		return
+	}
+
+	if err != nil {
+		inconsistency.RepairError = conversions.ToDerror(err).Error()
+		log.Warn().Str("organization_id", inconsistency.OrganizationId).Str("type", inconsistency.Type.String()).
+			Str("entity_id", inconsistency.EntityId).Str("trace", conversions.ToDerror(err).DebugReport()).
+			Msg("cannot repair inconsistency")
+		return
+	}
+	inconsistency.Repaired = true
+	log.Info().Str("organization_id", inconsistency.OrganizationId).Str("type", inconsistency.Type.String()).
+		Str("entity_id", inconsistency.EntityId).Msg("inconsistency repaired")
+}
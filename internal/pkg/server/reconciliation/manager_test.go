@@ -0,0 +1,215 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package reconciliation
+
+import (
+	"context"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-common-go"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-network-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-vpn-server-go"
+	"github.com/nalej/inventory-manager/internal/pkg/config"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"time"
+)
+
+const testOrganizationID = "org"
+
+// calls records the order in which the clients are listed.
+type calls []string
+
+type fakeControllers struct {
+	grpc_inventory_go.ControllersClient
+	calls *calls
+	// listed are the controllers returned by List.
+	listed []string
+	// joined are the controllers found by Get that were not listed.
+	joined map[string]bool
+}
+
+func (f *fakeControllers) List(ctx context.Context, in *grpc_organization_go.OrganizationId, opts ...grpc.CallOption) (*grpc_inventory_go.EdgeControllerList, error) {
+	*f.calls = append(*f.calls, "controllers")
+	result := &grpc_inventory_go.EdgeControllerList{}
+	for _, id := range f.listed {
+		result.Controllers = append(result.Controllers, &grpc_inventory_go.EdgeController{OrganizationId: in.OrganizationId, EdgeControllerId: id})
+	}
+	return result, nil
+}
+
+func (f *fakeControllers) Get(ctx context.Context, in *grpc_inventory_go.EdgeControllerId, opts ...grpc.CallOption) (*grpc_inventory_go.EdgeController, error) {
+	for _, id := range f.listed {
+		if id == in.EdgeControllerId {
+			return &grpc_inventory_go.EdgeController{OrganizationId: in.OrganizationId, EdgeControllerId: id}, nil
+		}
+	}
+	if f.joined[in.EdgeControllerId] {
+		return &grpc_inventory_go.EdgeController{OrganizationId: in.OrganizationId, EdgeControllerId: in.EdgeControllerId}, nil
+	}
+	return nil, derrors.NewNotFoundError("edge controller").WithParams(in.EdgeControllerId)
+}
+
+type fakeAssets struct {
+	grpc_inventory_go.AssetsClient
+	calls   *calls
+	assets  []*grpc_inventory_go.Asset
+	removed []string
+}
+
+func (f *fakeAssets) List(ctx context.Context, in *grpc_organization_go.OrganizationId, opts ...grpc.CallOption) (*grpc_inventory_go.AssetList, error) {
+	*f.calls = append(*f.calls, "assets")
+	return &grpc_inventory_go.AssetList{Assets: f.assets}, nil
+}
+
+func (f *fakeAssets) Remove(ctx context.Context, in *grpc_inventory_go.AssetId, opts ...grpc.CallOption) (*grpc_common_go.Success, error) {
+	f.removed = append(f.removed, in.AssetId)
+	return &grpc_common_go.Success{}, nil
+}
+
+type fakeVPN struct {
+	grpc_vpn_server_go.VPNServerClient
+	usernames []string
+	deleted   []string
+}
+
+func (f *fakeVPN) ListVPNUsers(ctx context.Context, in *grpc_vpn_server_go.GetVPNUserListRequest, opts ...grpc.CallOption) (*grpc_vpn_server_go.VPNUserList, error) {
+	return &grpc_vpn_server_go.VPNUserList{Usernames: f.usernames}, nil
+}
+
+func (f *fakeVPN) DeleteVPNUser(ctx context.Context, in *grpc_vpn_server_go.DeleteVPNUserRequest, opts ...grpc.CallOption) (*grpc_common_go.Success, error) {
+	f.deleted = append(f.deleted, in.Username)
+	return &grpc_common_go.Success{}, nil
+}
+
+type fakeDNS struct {
+	grpc_network_go.ServiceDNSClient
+	entries []*grpc_network_go.ServiceDNSEntry
+	deleted []string
+}
+
+func (f *fakeDNS) ListEntries(ctx context.Context, in *grpc_organization_go.OrganizationId, opts ...grpc.CallOption) (*grpc_network_go.ServiceDNSEntryList, error) {
+	return &grpc_network_go.ServiceDNSEntryList{Entries: f.entries}, nil
+}
+
+func (f *fakeDNS) DeleteEntry(ctx context.Context, in *grpc_network_go.DeleteServiceDNSEntryRequest, opts ...grpc.CallOption) (*grpc_common_go.Success, error) {
+	f.deleted = append(f.deleted, in.Fqdn)
+	return &grpc_common_go.Success{}, nil
+}
+
+func dnsEntry(edgeControllerID string) *grpc_network_go.ServiceDNSEntry {
+	return &grpc_network_go.ServiceDNSEntry{
+		OrganizationId: testOrganizationID,
+		Fqdn:           entities.GetEdgeControllerFQDN(edgeControllerID),
+		Tags:           []string{entities.EdgeControllerDNSTag},
+	}
+}
+
+// orphans returns the identifiers of the orphan inconsistencies of a report indexed by type.
+func orphans(report *grpc_inventory_manager_go.ReconciliationReport) map[grpc_inventory_manager_go.InconsistencyType][]string {
+	result := make(map[grpc_inventory_manager_go.InconsistencyType][]string, 0)
+	for _, inconsistency := range report.Inconsistencies {
+		if orphanTypes[inconsistency.Type] {
+			result[inconsistency.Type] = append(result[inconsistency.Type], inconsistency.EntityId)
+		}
+	}
+	return result
+}
+
+var _ = ginkgo.Describe("Reconciliation", func() {
+
+	var listCalls calls
+	var controllers *fakeControllers
+	var assets *fakeAssets
+	var vpn *fakeVPN
+	var dns *fakeDNS
+	var manager Manager
+
+	ginkgo.BeforeEach(func() {
+		listCalls = make(calls, 0)
+		controllers = &fakeControllers{calls: &listCalls, listed: []string{"ec1"}, joined: map[string]bool{"ec2": true}}
+		assets = &fakeAssets{calls: &listCalls, assets: []*grpc_inventory_go.Asset{
+			{OrganizationId: testOrganizationID, EdgeControllerId: "ec1", AssetId: "a1"},
+			{OrganizationId: testOrganizationID, EdgeControllerId: "ec2", AssetId: "a2"},
+			{OrganizationId: testOrganizationID, EdgeControllerId: "ec3", AssetId: "a3"},
+		}}
+		vpn = &fakeVPN{usernames: []string{
+			entities.GetEdgeControllerName(testOrganizationID, "ec1"),
+			entities.GetEdgeControllerName(testOrganizationID, "ec3"),
+		}}
+		dns = &fakeDNS{entries: []*grpc_network_go.ServiceDNSEntry{dnsEntry("ec1"), dnsEntry("ec3")}}
+		manager = NewManager(nil, controllers, assets, vpn, dns, NewConnectedAgents(),
+			config.Config{AssetThreshold: time.Minute, ReconciliationPolicy: config.ReconciliationRepairOrphans})
+	})
+
+	ginkgo.It("should list the controllers after the entities that depend on them", func() {
+		_, err := manager.reconcile(testOrganizationID, false)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(listCalls).To(gomega.Equal(calls{"assets", "controllers"}))
+	})
+
+	ginkgo.It("should report the orphans without repairing them", func() {
+		report, err := manager.reconcile(testOrganizationID, false)
+		gomega.Expect(err).To(gomega.Succeed())
+		found := orphans(report)
+		gomega.Expect(found[grpc_inventory_manager_go.InconsistencyType_ORPHAN_ASSET]).To(gomega.ConsistOf("a2", "a3"))
+		gomega.Expect(found[grpc_inventory_manager_go.InconsistencyType_ORPHAN_VPN_USER]).To(
+			gomega.ConsistOf(entities.GetEdgeControllerName(testOrganizationID, "ec3")))
+		gomega.Expect(found[grpc_inventory_manager_go.InconsistencyType_ORPHAN_DNS_ENTRY]).To(
+			gomega.ConsistOf(entities.GetEdgeControllerFQDN("ec3")))
+		for _, inconsistency := range report.Inconsistencies {
+			gomega.Expect(inconsistency.Repaired).To(gomega.BeFalse())
+		}
+		gomega.Expect(assets.removed).To(gomega.BeEmpty())
+		gomega.Expect(vpn.deleted).To(gomega.BeEmpty())
+		gomega.Expect(dns.deleted).To(gomega.BeEmpty())
+
+		last, err := manager.GetLastReconciliationReport(&grpc_organization_go.OrganizationId{OrganizationId: testOrganizationID})
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(last).To(gomega.Equal(report))
+	})
+
+	ginkgo.It("should only repair the orphans whose controller is still missing", func() {
+		report, err := manager.reconcile(testOrganizationID, true)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(assets.removed).To(gomega.Equal([]string{"a3"}))
+		gomega.Expect(vpn.deleted).To(gomega.Equal([]string{entities.GetEdgeControllerName(testOrganizationID, "ec3")}))
+		gomega.Expect(dns.deleted).To(gomega.Equal([]string{entities.GetEdgeControllerFQDN("ec3")}))
+		for _, inconsistency := range report.Inconsistencies {
+			if !orphanTypes[inconsistency.Type] {
+				continue
+			}
+			if inconsistency.AssetId == "a2" {
+				gomega.Expect(inconsistency.Repaired).To(gomega.BeFalse())
+				gomega.Expect(inconsistency.RepairError).NotTo(gomega.BeEmpty())
+			} else {
+				gomega.Expect(inconsistency.Repaired).To(gomega.BeTrue())
+			}
+		}
+	})
+
+	ginkgo.It("should reject repairs not allowed by the policy", func() {
+		manager.cfg.ReconciliationPolicy = config.ReconciliationReportOnly
+		_, err := manager.Reconcile(&grpc_inventory_manager_go.ReconcileRequest{OrganizationId: testOrganizationID, Repair: true})
+		gomega.Expect(err).NotTo(gomega.Succeed())
+		gomega.Expect(assets.removed).To(gomega.BeEmpty())
+	})
+})
@@ -0,0 +1,81 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package retentions
+
+import (
+	"github.com/nalej/grpc-common-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"golang.org/x/net/context"
+)
+
+type Handler struct {
+	manager Manager
+}
+
+func NewHandler(manager Manager) *Handler {
+	return &Handler{
+		manager: manager,
+	}
+}
+
+// SetRetentionPolicy adds or replaces the retention policy of an organization.
+func (h *Handler) SetRetentionPolicy(_ context.Context, policy *grpc_inventory_manager_go.RetentionPolicy) (*grpc_inventory_manager_go.RetentionPolicy, error) {
+	vErr := entities.ValidRetentionPolicy(policy)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.SetRetentionPolicy(policy)
+}
+
+// GetRetentionPolicy retrieves the retention policy of an organization.
+func (h *Handler) GetRetentionPolicy(_ context.Context, orgID *grpc_organization_go.OrganizationId) (*grpc_inventory_manager_go.RetentionPolicy, error) {
+	vErr := entities.ValidOrganizationID(orgID)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.GetRetentionPolicy(orgID)
+}
+
+// RemoveRetentionPolicy removes the retention policy of an organization.
+func (h *Handler) RemoveRetentionPolicy(_ context.Context, orgID *grpc_organization_go.OrganizationId) (*grpc_common_go.Success, error) {
+	vErr := entities.ValidOrganizationID(orgID)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.RemoveRetentionPolicy(orgID)
+}
+
+// PreviewRetention lists the entities on which the retention policy of an organization would act.
+func (h *Handler) PreviewRetention(_ context.Context, orgID *grpc_organization_go.OrganizationId) (*grpc_inventory_manager_go.RetentionPreview, error) {
+	vErr := entities.ValidOrganizationID(orgID)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.PreviewRetention(orgID)
+}
+
+// ListArchived retrieves the entities archived by the retention policy of an organization.
+func (h *Handler) ListArchived(_ context.Context, orgID *grpc_organization_go.OrganizationId) (*grpc_inventory_manager_go.ArchivedEntityList, error) {
+	vErr := entities.ValidOrganizationID(orgID)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.ListArchived(orgID)
+}
@@ -0,0 +1,373 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package retentions
+
+import (
+	"context"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-common-go"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/inventory-manager/internal/pkg/config"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"github.com/nalej/inventory-manager/internal/pkg/jobs"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/retention"
+	"github.com/nalej/inventory-manager/internal/pkg/server/audits"
+	"github.com/nalej/inventory-manager/internal/pkg/server/contexts"
+	"github.com/nalej/inventory-manager/internal/pkg/server/edgecontroller"
+	"github.com/nalej/inventory-manager/internal/pkg/server/inventory"
+	"github.com/rs/zerolog/log"
+	"strconv"
+	"time"
+)
+
+// RetentionAuditPrefix precedes the name of the changes recorded in the audit log by the retention policies.
+const RetentionAuditPrefix = "/retention/"
+
+type Manager struct {
+	inventoryManager  inventory.Manager
+	ecManager         edgecontroller.Manager
+	assetsClient      grpc_inventory_go.AssetsClient
+	controllersClient grpc_inventory_go.ControllersClient
+	provider          retention.Provider
+	auditManager      audits.Manager
+	cfg               config.Config
+}
+
+func NewManager(inventoryManager inventory.Manager, ecManager edgecontroller.Manager,
+	assetsClient grpc_inventory_go.AssetsClient, controllersClient grpc_inventory_go.ControllersClient,
+	provider retention.Provider, auditManager audits.Manager, cfg config.Config) Manager {
+	return Manager{
+		inventoryManager:  inventoryManager,
+		ecManager:         ecManager,
+		assetsClient:      assetsClient,
+		controllersClient: controllersClient,
+		provider:          provider,
+		auditManager:      auditManager,
+		cfg:               cfg,
+	}
+}
+
+// Run launches the periodic evaluation of the retention policies.
+func (m *Manager) Run(group *jobs.Group) {
+	if m.cfg.RetentionPeriod == 0 {
+		log.Info().Msg("retention policies are disabled")
+		return
+	}
+	log.Debug().Str("period", m.cfg.RetentionPeriod.String()).Msg("launching retention evaluations")
+	group.Every(m.cfg.RetentionPeriod, m.scheduledEvaluations)
+}
+
+// scheduledEvaluations applies the retention policies of the organizations until the jobs are stopped.
+func (m *Manager) scheduledEvaluations(stopped context.Context) {
+	policies, err := m.provider.ListPolicies()
+	if err != nil {
+		log.Error().Str("trace", err.DebugReport()).Msg("cannot list the retention policies")
+		return
+	}
+	for _, policy := range policies {
+		if stopped.Err() != nil {
+			return
+		}
+		aErr := m.apply(policy)
+		if aErr != nil {
+			log.Warn().Str("organization_id", policy.OrganizationId).Str("trace", conversions.ToDerror(aErr).DebugReport()).
+				Msg("cannot apply the retention policy")
+		}
+	}
+}
+
+// SetRetentionPolicy adds or replaces the retention policy of an organization.
+func (m *Manager) SetRetentionPolicy(policy *grpc_inventory_manager_go.RetentionPolicy) (*grpc_inventory_manager_go.RetentionPolicy, error) {
+	toAdd := &grpc_inventory_manager_go.RetentionPolicy{
+		OrganizationId:      policy.OrganizationId,
+		FlagAfterSeconds:    policy.FlagAfterSeconds,
+		ArchiveAfterSeconds: policy.ArchiveAfterSeconds,
+		DeleteAfterSeconds:  policy.DeleteAfterSeconds,
+		Updated:             time.Now().Unix(),
+	}
+	err := m.provider.SetPolicy(toAdd)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return toAdd, nil
+}
+
+// GetRetentionPolicy retrieves the retention policy of an organization.
+func (m *Manager) GetRetentionPolicy(organizationID *grpc_organization_go.OrganizationId) (*grpc_inventory_manager_go.RetentionPolicy, error) {
+	policy, err := m.provider.GetPolicy(organizationID.OrganizationId)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return policy, nil
+}
+
+// RemoveRetentionPolicy removes the retention policy of an organization. The labels already added by the policy are
+// kept.
+func (m *Manager) RemoveRetentionPolicy(organizationID *grpc_organization_go.OrganizationId) (*grpc_common_go.Success, error) {
+	err := m.provider.RemovePolicy(organizationID.OrganizationId)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return &grpc_common_go.Success{}, nil
+}
+
+// PreviewRetention lists the entities on which the policy of an organization would act now without modifying them.
+func (m *Manager) PreviewRetention(organizationID *grpc_organization_go.OrganizationId) (*grpc_inventory_manager_go.RetentionPreview, error) {
+	policy, err := m.provider.GetPolicy(organizationID.OrganizationId)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	list, lErr := m.inventoryManager.List(organizationID)
+	if lErr != nil {
+		return nil, lErr
+	}
+	return &grpc_inventory_manager_go.RetentionPreview{
+		OrganizationId: organizationID.OrganizationId,
+		Policy:         policy,
+		Candidates:     evaluate(policy, list, time.Now()),
+		Errors:         list.Errors,
+	}, nil
+}
+
+// ListArchived retrieves the records of the entities archived in an organization.
+func (m *Manager) ListArchived(organizationID *grpc_organization_go.OrganizationId) (*grpc_inventory_manager_go.ArchivedEntityList, error) {
+	archived, err := m.provider.ListArchived(organizationID.OrganizationId)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return &grpc_inventory_manager_go.ArchivedEntityList{
+		OrganizationId: organizationID.OrganizationId,
+		Entities:       archived,
+	}, nil
+}
+
+// evaluation with the state of an organization while a policy is applied.
+type evaluation struct {
+	organizationID string
+	now            time.Time
+	assets         map[string]*grpc_inventory_manager_go.Asset
+	controllers    map[string]*grpc_inventory_manager_go.EdgeController
+	// archived contains the identifiers of the entities archived during the evaluation.
+	archived map[string]bool
+}
+
+// apply evaluates the policy of an organization and acts on the resulting entities. The assets are processed before
+// the controllers as deleting a controller removes its assets. The retention labels are removed from the entities
+// that are online again. The policy is not applied if any source of the inventory fails.
+func (m *Manager) apply(policy *grpc_inventory_manager_go.RetentionPolicy) error {
+	list, err := m.inventoryManager.List(&grpc_organization_go.OrganizationId{OrganizationId: policy.OrganizationId})
+	if err != nil {
+		return err
+	}
+	// deleting a controller whose assets could not be listed would remove them without an archive record
+	if len(list.Errors) > 0 {
+		log.Warn().Str("organization_id", policy.OrganizationId).Int("errors", len(list.Errors)).
+			Msg("retention policy not applied on a partial inventory")
+		return nil
+	}
+	current := &evaluation{
+		organizationID: policy.OrganizationId,
+		now:            time.Now(),
+		assets:         make(map[string]*grpc_inventory_manager_go.Asset, len(list.Assets)),
+		controllers:    make(map[string]*grpc_inventory_manager_go.EdgeController, len(list.Controllers)),
+		archived:       make(map[string]bool),
+	}
+	for _, asset := range list.Assets {
+		current.assets[asset.AssetId] = asset
+		if asset.Status == grpc_inventory_manager_go.ConnectedStatus_ONLINE {
+			m.clearLabels(grpc_inventory_manager_go.InventoryEntityType_ASSET, asset.OrganizationId, asset.AssetId, asset.Labels)
+		}
+	}
+	for _, controller := range list.Controllers {
+		current.controllers[controller.EdgeControllerId] = controller
+		if controller.Status == grpc_inventory_manager_go.ConnectedStatus_ONLINE {
+			m.clearLabels(grpc_inventory_manager_go.InventoryEntityType_CONTROLLER, controller.OrganizationId, controller.EdgeControllerId, controller.Labels)
+		}
+	}
+	candidates := evaluate(policy, list, current.now)
+	for _, entityType := range []grpc_inventory_manager_go.InventoryEntityType{
+		grpc_inventory_manager_go.InventoryEntityType_ASSET, grpc_inventory_manager_go.InventoryEntityType_CONTROLLER} {
+		for _, candidate := range candidates {
+			if candidate.EntityType != entityType || candidate.Applied {
+				continue
+			}
+			aErr := m.applyAction(current, candidate)
+			if aErr != nil {
+				log.Warn().Str("organization_id", policy.OrganizationId).Str("entity_id", candidate.EntityId).
+					Str("action", candidate.Action.String()).Str("trace", conversions.ToDerror(aErr).DebugReport()).
+					Msg("cannot apply the retention action")
+			}
+		}
+	}
+	return nil
+}
+
+// applyAction flags, archives or deletes an entity. An entity is only deleted once its archive record is stored.
+func (m *Manager) applyAction(current *evaluation, candidate *grpc_inventory_manager_go.RetentionCandidate) error {
+	switch candidate.Action {
+	case grpc_inventory_manager_go.RetentionAction_FLAG:
+		return m.addLabel(current, candidate.EntityType, candidate.EntityId, entities.RetentionFlaggedLabel)
+	case grpc_inventory_manager_go.RetentionAction_ARCHIVE:
+		err := m.archive(current, candidate.EntityType, candidate.EntityId, candidate.LastSeen)
+		if err != nil {
+			return err
+		}
+		return m.addLabel(current, candidate.EntityType, candidate.EntityId, entities.RetentionArchivedLabel)
+	case grpc_inventory_manager_go.RetentionAction_DELETE:
+		err := m.archive(current, candidate.EntityType, candidate.EntityId, candidate.LastSeen)
+		if err != nil {
+			return err
+		}
+		return m.remove(current, candidate)
+	}
+	return conversions.ToGRPCError(derrors.NewInvalidArgumentError("unknown retention action").WithParams(candidate.Action))
+}
+
+// archive stores the record of an entity unless it was already archived.
+func (m *Manager) archive(current *evaluation, entityType grpc_inventory_manager_go.InventoryEntityType, entityID string, seen int64) error {
+	if current.archived[entityID] {
+		return nil
+	}
+	record := &grpc_inventory_manager_go.ArchivedEntity{
+		OrganizationId: current.organizationID,
+		EntityType:     entityType,
+		EntityId:       entityID,
+		Archived:       current.now.Unix(),
+		LastSeen:       seen,
+	}
+	var labels map[string]string
+	switch entityType {
+	case grpc_inventory_manager_go.InventoryEntityType_ASSET:
+		asset := current.assets[entityID]
+		record.EdgeControllerId = asset.EdgeControllerId
+		record.Asset = asset
+		labels = asset.Labels
+	case grpc_inventory_manager_go.InventoryEntityType_CONTROLLER:
+		controller := current.controllers[entityID]
+		record.EdgeControllerId = controller.EdgeControllerId
+		record.Controller = controller
+		labels = controller.Labels
+	}
+	if _, archived := labels[entities.RetentionArchivedLabel]; archived {
+		return nil
+	}
+	err := m.provider.Archive(record)
+	if err != nil {
+		return conversions.ToGRPCError(err)
+	}
+	current.archived[entityID] = true
+	return nil
+}
+
+// remove deletes an entity from the inventory. The assets of a controller are archived before the controller is
+// unlinked as the unlink removes them.
+func (m *Manager) remove(current *evaluation, candidate *grpc_inventory_manager_go.RetentionCandidate) error {
+	if candidate.EntityType == grpc_inventory_manager_go.InventoryEntityType_ASSET {
+		ctx, cancel := contexts.SMContext()
+		defer cancel()
+		assetID := &grpc_inventory_go.AssetId{
+			OrganizationId: current.organizationID,
+			AssetId:        candidate.EntityId,
+		}
+		_, err := m.assetsClient.Remove(ctx, assetID)
+		m.auditManager.RecordSystem(current.organizationID, RetentionAuditPrefix+"RemoveAsset", assetID, err)
+		if err != nil {
+			return err
+		}
+		delete(current.assets, candidate.EntityId)
+		log.Info().Str("audit", "retentionRemoveAsset").Str("organization_id", current.organizationID).
+			Str("asset_id", candidate.EntityId).Int64("offline_seconds", candidate.OfflineSeconds).Msg("asset removed by the retention policy")
+		return nil
+	}
+	for _, asset := range current.assets {
+		if asset.EdgeControllerId != candidate.EntityId {
+			continue
+		}
+		err := m.archive(current, grpc_inventory_manager_go.InventoryEntityType_ASSET, asset.AssetId,
+			lastSeen(asset.LastAliveTimestamp, asset.Created))
+		if err != nil {
+			return err
+		}
+	}
+	unlinkRequest := &grpc_inventory_manager_go.UnlinkECRequest{
+		OrganizationId:   current.organizationID,
+		EdgeControllerId: candidate.EntityId,
+		Force:            true,
+	}
+	_, err := m.ecManager.UnlinkEIC(unlinkRequest)
+	m.auditManager.RecordSystem(current.organizationID, RetentionAuditPrefix+"UnlinkEIC", unlinkRequest, err)
+	if err != nil {
+		return err
+	}
+	log.Info().Str("audit", "retentionUnlinkEC").Str("organization_id", current.organizationID).
+		Str("edge_controller_id", candidate.EntityId).Int64("offline_seconds", candidate.OfflineSeconds).
+		Msg("edge controller unlinked by the retention policy")
+	return nil
+}
+
+// addLabel marks an entity with a retention label whose value is the time of the evaluation.
+func (m *Manager) addLabel(current *evaluation, entityType grpc_inventory_manager_go.InventoryEntityType, entityID string, label string) error {
+	return m.updateLabels(entityType, current.organizationID, entityID, true, map[string]string{
+		label: strconv.FormatInt(current.now.Unix(), 10),
+	})
+}
+
+// clearLabels removes the retention labels of an entity that is online again.
+func (m *Manager) clearLabels(entityType grpc_inventory_manager_go.InventoryEntityType, organizationID string, entityID string, labels map[string]string) {
+	toRemove := make(map[string]string)
+	for _, label := range []string{entities.RetentionFlaggedLabel, entities.RetentionArchivedLabel} {
+		if value, exists := labels[label]; exists {
+			toRemove[label] = value
+		}
+	}
+	if len(toRemove) == 0 {
+		return
+	}
+	err := m.updateLabels(entityType, organizationID, entityID, false, toRemove)
+	if err != nil {
+		log.Warn().Str("organization_id", organizationID).Str("entity_id", entityID).
+			Str("trace", conversions.ToDerror(err).DebugReport()).Msg("cannot clear the retention labels")
+	}
+}
+
+func (m *Manager) updateLabels(entityType grpc_inventory_manager_go.InventoryEntityType, organizationID string, entityID string, add bool, labels map[string]string) error {
+	ctx, cancel := contexts.SMContext()
+	defer cancel()
+	var err error
+	switch entityType {
+	case grpc_inventory_manager_go.InventoryEntityType_ASSET:
+		_, err = m.assetsClient.Update(ctx, &grpc_inventory_go.UpdateAssetRequest{
+			OrganizationId: organizationID,
+			AssetId:        entityID,
+			AddLabels:      add,
+			RemoveLabels:   !add,
+			Labels:         labels,
+		})
+	case grpc_inventory_manager_go.InventoryEntityType_CONTROLLER:
+		_, err = m.controllersClient.Update(ctx, &grpc_inventory_go.UpdateEdgeControllerRequest{
+			OrganizationId:   organizationID,
+			EdgeControllerId: entityID,
+			AddLabels:        add,
+			RemoveLabels:     !add,
+			Labels:           labels,
+		})
+	}
+	return err
+}
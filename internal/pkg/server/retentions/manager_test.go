@@ -0,0 +1,125 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package retentions
+
+import (
+	"context"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-device-manager-go"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/inventory-manager/internal/pkg/config"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/audit"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/retention"
+	"github.com/nalej/inventory-manager/internal/pkg/server/audits"
+	"github.com/nalej/inventory-manager/internal/pkg/server/edgecontroller"
+	"github.com/nalej/inventory-manager/internal/pkg/server/inventory"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"time"
+)
+
+type fakeDevices struct {
+	grpc_device_manager_go.DevicesClient
+}
+
+func (f *fakeDevices) ListDeviceGroups(ctx context.Context, in *grpc_organization_go.OrganizationId, opts ...grpc.CallOption) (*grpc_device_manager_go.DeviceGroupList, error) {
+	return &grpc_device_manager_go.DeviceGroupList{}, nil
+}
+
+type fakeAssets struct {
+	grpc_inventory_go.AssetsClient
+	// failing makes List return an error.
+	failing bool
+}
+
+func (f *fakeAssets) List(ctx context.Context, in *grpc_organization_go.OrganizationId, opts ...grpc.CallOption) (*grpc_inventory_go.AssetList, error) {
+	if f.failing {
+		return nil, derrors.NewUnavailableError("system model not available")
+	}
+	return &grpc_inventory_go.AssetList{}, nil
+}
+
+type fakeControllers struct {
+	grpc_inventory_go.ControllersClient
+	controllers []*grpc_inventory_go.EdgeController
+	updated     []string
+}
+
+func (f *fakeControllers) List(ctx context.Context, in *grpc_organization_go.OrganizationId, opts ...grpc.CallOption) (*grpc_inventory_go.EdgeControllerList, error) {
+	return &grpc_inventory_go.EdgeControllerList{Controllers: f.controllers}, nil
+}
+
+func (f *fakeControllers) Update(ctx context.Context, in *grpc_inventory_go.UpdateEdgeControllerRequest, opts ...grpc.CallOption) (*grpc_inventory_go.EdgeController, error) {
+	f.updated = append(f.updated, in.EdgeControllerId)
+	return &grpc_inventory_go.EdgeController{OrganizationId: in.OrganizationId, EdgeControllerId: in.EdgeControllerId}, nil
+}
+
+var _ = ginkgo.Describe("Retention policy application", func() {
+
+	day := int64(24 * 3600)
+	policy := &grpc_inventory_manager_go.RetentionPolicy{
+		OrganizationId:      "org",
+		ArchiveAfterSeconds: 90 * day,
+	}
+	cfg := config.Config{
+		ControllerThreshold:  time.Minute,
+		AssetThreshold:       time.Minute,
+		DeviceThreshold:      time.Minute,
+		InventoryParallelism: 1,
+		InventoryTimeout:     time.Second,
+	}
+
+	var assets *fakeAssets
+	var controllers *fakeControllers
+	var provider *retention.MemoryProvider
+	var manager Manager
+
+	ginkgo.BeforeEach(func() {
+		assets = &fakeAssets{}
+		controllers = &fakeControllers{
+			controllers: []*grpc_inventory_go.EdgeController{{
+				OrganizationId:     "org",
+				EdgeControllerId:   "ec",
+				LastAliveTimestamp: time.Now().Unix() - 100*day,
+			}},
+		}
+		provider = retention.NewMemoryProvider()
+		inventoryManager := inventory.NewManager(&fakeDevices{}, assets, controllers, nil, nil, nil, nil, nil, nil, nil, cfg)
+		manager = NewManager(inventoryManager, edgecontroller.Manager{}, assets, controllers, provider,
+			audits.NewManager(audit.NewMemoryProvider()), cfg)
+	})
+
+	ginkgo.It("should archive the controllers of a complete inventory", func() {
+		gomega.Expect(manager.apply(policy)).To(gomega.Succeed())
+		archived, err := provider.ListArchived("org")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(archived).To(gomega.HaveLen(1))
+		gomega.Expect(controllers.updated).To(gomega.Equal([]string{"ec"}))
+	})
+
+	ginkgo.It("should skip the organization when a source of the inventory fails", func() {
+		assets.failing = true
+		gomega.Expect(manager.apply(policy)).To(gomega.Succeed())
+		archived, err := provider.ListArchived("org")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(archived).To(gomega.BeEmpty())
+		gomega.Expect(controllers.updated).To(gomega.BeEmpty())
+	})
+})
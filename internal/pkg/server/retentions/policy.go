@@ -0,0 +1,113 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package retentions
+
+import (
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"sort"
+	"time"
+)
+
+// lastSeen returns when an entity was last seen online. The entities that never connected are considered seen when
+// they were created.
+func lastSeen(lastAlive int64, created int64) int64 {
+	if lastAlive != 0 {
+		return lastAlive
+	}
+	return created
+}
+
+// reachedAction returns the most advanced action of a policy reached by an entity offline for the given time.
+func reachedAction(policy *grpc_inventory_manager_go.RetentionPolicy, offline int64) (grpc_inventory_manager_go.RetentionAction, bool) {
+	switch {
+	case policy.DeleteAfterSeconds > 0 && offline >= policy.DeleteAfterSeconds:
+		return grpc_inventory_manager_go.RetentionAction_DELETE, true
+	case policy.ArchiveAfterSeconds > 0 && offline >= policy.ArchiveAfterSeconds:
+		return grpc_inventory_manager_go.RetentionAction_ARCHIVE, true
+	case policy.FlagAfterSeconds > 0 && offline >= policy.FlagAfterSeconds:
+		return grpc_inventory_manager_go.RetentionAction_FLAG, true
+	}
+	return grpc_inventory_manager_go.RetentionAction_FLAG, false
+}
+
+// isApplied checks from the labels of an entity if an action has already been applied on it. A deletion is never
+// applied as the entity would not be in the inventory.
+func isApplied(action grpc_inventory_manager_go.RetentionAction, labels map[string]string) bool {
+	switch action {
+	case grpc_inventory_manager_go.RetentionAction_FLAG:
+		_, flagged := labels[entities.RetentionFlaggedLabel]
+		return flagged
+	case grpc_inventory_manager_go.RetentionAction_ARCHIVE:
+		_, archived := labels[entities.RetentionArchivedLabel]
+		return archived
+	}
+	return false
+}
+
+// newCandidate returns the candidate of an offline entity, or nil if it has not reached any threshold of the policy.
+func newCandidate(policy *grpc_inventory_manager_go.RetentionPolicy, entityType grpc_inventory_manager_go.InventoryEntityType,
+	entityID string, edgeControllerID string, seen int64, labels map[string]string, now time.Time) *grpc_inventory_manager_go.RetentionCandidate {
+	offline := now.Unix() - seen
+	action, reached := reachedAction(policy, offline)
+	if !reached {
+		return nil
+	}
+	return &grpc_inventory_manager_go.RetentionCandidate{
+		EntityType:       entityType,
+		EntityId:         entityID,
+		EdgeControllerId: edgeControllerID,
+		LastSeen:         seen,
+		OfflineSeconds:   offline,
+		Action:           action,
+		Applied:          isApplied(action, labels),
+	}
+}
+
+// evaluate returns the assets and controllers of an inventory on which a policy acts at the given time. Only the
+// OFFLINE entities are considered, the ones that have been offline the longest come first. The unmanaged assets are
+// skipped as they never send alive messages and would always be reported as offline.
+func evaluate(policy *grpc_inventory_manager_go.RetentionPolicy, list *grpc_inventory_manager_go.InventoryList, now time.Time) []*grpc_inventory_manager_go.RetentionCandidate {
+	candidates := make([]*grpc_inventory_manager_go.RetentionCandidate, 0)
+	for _, asset := range list.Assets {
+		if asset.Status != grpc_inventory_manager_go.ConnectedStatus_OFFLINE || entities.IsUnmanaged(asset.Labels) {
+			continue
+		}
+		candidate := newCandidate(policy, grpc_inventory_manager_go.InventoryEntityType_ASSET, asset.AssetId, asset.EdgeControllerId,
+			lastSeen(asset.LastAliveTimestamp, asset.Created), asset.Labels, now)
+		if candidate != nil {
+			candidates = append(candidates, candidate)
+		}
+	}
+	for _, controller := range list.Controllers {
+		if controller.Status != grpc_inventory_manager_go.ConnectedStatus_OFFLINE {
+			continue
+		}
+		candidate := newCandidate(policy, grpc_inventory_manager_go.InventoryEntityType_CONTROLLER, controller.EdgeControllerId,
+			controller.EdgeControllerId, lastSeen(controller.LastAliveTimestamp, controller.Created), controller.Labels, now)
+		if candidate != nil {
+			candidates = append(candidates, candidate)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].OfflineSeconds != candidates[j].OfflineSeconds {
+			return candidates[i].OfflineSeconds > candidates[j].OfflineSeconds
+		}
+		return candidates[i].EntityId < candidates[j].EntityId
+	})
+	return candidates
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package retentions
+
+import (
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"time"
+)
+
+var _ = ginkgo.Describe("Retention policy evaluation", func() {
+
+	day := int64(24 * 3600)
+	now := time.Unix(1000*day, 0)
+	policy := &grpc_inventory_manager_go.RetentionPolicy{
+		OrganizationId:      "org",
+		FlagAfterSeconds:    30 * day,
+		ArchiveAfterSeconds: 90 * day,
+		DeleteAfterSeconds:  180 * day,
+	}
+	offlineAsset := func(assetID string, daysOffline int64, labels map[string]string) *grpc_inventory_manager_go.Asset {
+		return &grpc_inventory_manager_go.Asset{
+			AssetId:            assetID,
+			EdgeControllerId:   "ec",
+			LastAliveTimestamp: now.Unix() - daysOffline*day,
+			Status:             grpc_inventory_manager_go.ConnectedStatus_OFFLINE,
+			Labels:             labels,
+		}
+	}
+
+	ginkgo.It("should choose the most advanced action reached", func() {
+		list := &grpc_inventory_manager_go.InventoryList{
+			Assets: []*grpc_inventory_manager_go.Asset{
+				offlineAsset("recent", 10, nil),
+				offlineAsset("flag", 40, nil),
+				offlineAsset("archive", 100, nil),
+				offlineAsset("delete", 200, nil),
+			},
+		}
+		candidates := evaluate(policy, list, now)
+		gomega.Expect(candidates).To(gomega.HaveLen(3))
+		gomega.Expect(candidates[0].EntityId).To(gomega.Equal("delete"))
+		gomega.Expect(candidates[0].Action).To(gomega.Equal(grpc_inventory_manager_go.RetentionAction_DELETE))
+		gomega.Expect(candidates[0].OfflineSeconds).To(gomega.Equal(200 * day))
+		gomega.Expect(candidates[1].Action).To(gomega.Equal(grpc_inventory_manager_go.RetentionAction_ARCHIVE))
+		gomega.Expect(candidates[2].Action).To(gomega.Equal(grpc_inventory_manager_go.RetentionAction_FLAG))
+	})
+
+	ginkgo.It("should ignore the online entities", func() {
+		asset := offlineAsset("online", 200, nil)
+		asset.Status = grpc_inventory_manager_go.ConnectedStatus_ONLINE
+		list := &grpc_inventory_manager_go.InventoryList{
+			Assets: []*grpc_inventory_manager_go.Asset{asset},
+		}
+		gomega.Expect(evaluate(policy, list, now)).To(gomega.BeEmpty())
+	})
+
+	ginkgo.It("should ignore the unmanaged assets", func() {
+		imported := offlineAsset("printer", 200, map[string]string{entities.UnmanagedLabel: "1"})
+		imported.LastAliveTimestamp = 0
+		imported.Created = now.Unix() - 200*day
+		list := &grpc_inventory_manager_go.InventoryList{
+			Assets: []*grpc_inventory_manager_go.Asset{imported},
+		}
+		gomega.Expect(evaluate(policy, list, now)).To(gomega.BeEmpty())
+	})
+
+	ginkgo.It("should use the creation time of the entities that never connected", func() {
+		list := &grpc_inventory_manager_go.InventoryList{
+			Controllers: []*grpc_inventory_manager_go.EdgeController{{
+				EdgeControllerId: "ec",
+				Created:          now.Unix() - 50*day,
+				Status:           grpc_inventory_manager_go.ConnectedStatus_OFFLINE,
+			}},
+		}
+		candidates := evaluate(policy, list, now)
+		gomega.Expect(candidates).To(gomega.HaveLen(1))
+		gomega.Expect(candidates[0].EntityType).To(gomega.Equal(grpc_inventory_manager_go.InventoryEntityType_CONTROLLER))
+		gomega.Expect(candidates[0].LastSeen).To(gomega.Equal(now.Unix() - 50*day))
+		gomega.Expect(candidates[0].Action).To(gomega.Equal(grpc_inventory_manager_go.RetentionAction_FLAG))
+	})
+
+	ginkgo.It("should skip the thresholds not set", func() {
+		deleteOnly := &grpc_inventory_manager_go.RetentionPolicy{OrganizationId: "org", DeleteAfterSeconds: 180 * day}
+		list := &grpc_inventory_manager_go.InventoryList{
+			Assets: []*grpc_inventory_manager_go.Asset{offlineAsset("archive", 100, nil), offlineAsset("delete", 200, nil)},
+		}
+		candidates := evaluate(deleteOnly, list, now)
+		gomega.Expect(candidates).To(gomega.HaveLen(1))
+		gomega.Expect(candidates[0].EntityId).To(gomega.Equal("delete"))
+	})
+
+	ginkgo.It("should report the actions already applied", func() {
+		list := &grpc_inventory_manager_go.InventoryList{
+			Assets: []*grpc_inventory_manager_go.Asset{
+				offlineAsset("flagged", 40, map[string]string{entities.RetentionFlaggedLabel: "1"}),
+				offlineAsset("flaggedOnly", 100, map[string]string{entities.RetentionFlaggedLabel: "1"}),
+			},
+		}
+		candidates := evaluate(policy, list, now)
+		gomega.Expect(candidates).To(gomega.HaveLen(2))
+		gomega.Expect(candidates[0].EntityId).To(gomega.Equal("flaggedOnly"))
+		gomega.Expect(candidates[0].Applied).To(gomega.BeFalse())
+		gomega.Expect(candidates[1].Applied).To(gomega.BeTrue())
+	})
+})
@@ -17,6 +17,7 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"github.com/nalej/derrors"
 	"github.com/nalej/grpc-authx-go"
@@ -25,12 +26,30 @@ import (
 	"github.com/nalej/grpc-inventory-go"
 	"github.com/nalej/grpc-inventory-manager-go"
 	"github.com/nalej/grpc-network-go"
+	"github.com/nalej/grpc-organization-go"
 	"github.com/nalej/grpc-vpn-server-go"
+	"github.com/nalej/inventory-manager/internal/pkg/cache"
 	"github.com/nalej/inventory-manager/internal/pkg/config"
+	"github.com/nalej/inventory-manager/internal/pkg/jobs"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/attribute"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/audit"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/deadletter"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/operation"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/retention"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/savedquery"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/snapshot"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/trend"
 	"github.com/nalej/inventory-manager/internal/pkg/server/agent"
+	"github.com/nalej/inventory-manager/internal/pkg/server/audits"
 	"github.com/nalej/inventory-manager/internal/pkg/server/bus"
+	"github.com/nalej/inventory-manager/internal/pkg/server/deadletters"
 	"github.com/nalej/inventory-manager/internal/pkg/server/edgecontroller"
 	"github.com/nalej/inventory-manager/internal/pkg/server/inventory"
+	"github.com/nalej/inventory-manager/internal/pkg/server/reconciliation"
+	"github.com/nalej/inventory-manager/internal/pkg/server/retentions"
+	"github.com/nalej/inventory-manager/internal/pkg/server/snapshots"
+	"github.com/nalej/inventory-manager/internal/pkg/server/trends"
+	"github.com/nalej/inventory-manager/internal/pkg/watch"
 	"github.com/nalej/nalej-bus/pkg/bus/pulsar-comcast"
 	"github.com/nalej/nalej-bus/pkg/queue/inventory/events"
 	"github.com/nalej/nalej-bus/pkg/queue/inventory/ops"
@@ -38,6 +57,9 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 	"net"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 type Service struct {
@@ -60,9 +82,13 @@ type Clients struct {
 	deviceManagerClient          grpc_device_manager_go.DevicesClient
 	netManagerClient             grpc_network_go.ServiceDNSClient
 	edgeInvProxyControllerClient grpc_edge_inventory_proxy_go.EdgeControllerProxyClient
+	orgClient                    grpc_organization_go.OrganizationsClient
+	// connections to close on shutdown.
+	connections []*grpc.ClientConn
 }
 
 type BusClients struct {
+	queueClient *pulsar_comcast.PulsarClient
 	inventoryEventsConsumer *events.InventoryEventsConsumer
 	inventoryOpsConsumer *ops.InventoryOpsConsumer
 }
@@ -71,7 +97,7 @@ func (s *Service) GetBusClients() (*BusClients, derrors.Error) {
 	queueClient := pulsar_comcast.NewClient(s.Configuration.QueueAddress, nil)
 
 	// inventory Events Consumer
-	invEventsOpts := events.NewConfigInventoryEventsConsumer(1, events.ConsumableStructsInventoryEventsConsumer{
+	invEventsOpts := events.NewConfigInventoryEventsConsumer(s.Configuration.BusBufferSize, events.ConsumableStructsInventoryEventsConsumer{
 		AgentsAclive:     true,
 		EdgeControllerId: true,
 		EICStartInfo:     true,
@@ -83,13 +109,14 @@ func (s *Service) GetBusClients() (*BusClients, derrors.Error) {
 	}
 
 	// inventory Ops Consumer
-	invOpOpts := ops.NewConfigInventoryOpsConsumer(1, ops.ConsumableStructsInventoryOpsConsumer{
+	invOpOpts := ops.NewConfigInventoryOpsConsumer(s.Configuration.BusBufferSize, ops.ConsumableStructsInventoryOpsConsumer{
 		AgentOpResponse: true,
 		EdgeControllerOpResponse: true,
 	})
 
 	invOpConsumer, err := ops.NewInventoryOpsConsumer(queueClient, "invmng-invops", true, invOpOpts)
 	return &BusClients{
+		queueClient: queueClient,
 		inventoryEventsConsumer: invEventConsumer,
 		inventoryOpsConsumer: invOpConsumer,
 	}, nil
@@ -129,6 +156,7 @@ func (s *Service) GetClients() (*Clients, derrors.Error) {
 	dmClient := grpc_device_manager_go.NewDevicesClient(dmConn)
 	netMngrClient := grpc_network_go.NewServiceDNSClient(netConn)
 	edgeInvProxyControllerClient := grpc_edge_inventory_proxy_go.NewEdgeControllerProxyClient(proxyConn)
+	orgClient := grpc_organization_go.NewOrganizationsClient(smConn)
 
 	return &Clients{
 		imClient,
@@ -139,6 +167,8 @@ func (s *Service) GetClients() (*Clients, derrors.Error) {
 		dmClient,
 		netMngrClient,
 		edgeInvProxyControllerClient,
+		orgClient,
+		[]*grpc.ClientConn{vpnConn, aConn, smConn, dmConn, netConn, proxyConn},
 	}, nil
 }
 
@@ -155,6 +185,27 @@ func (s *Service) Run() error {
 		log.Fatal().Str("err", cErr.DebugReport()).Msg("Cannot create clients")
 	}
 
+	// the modifications made through these clients update the inventory cache
+	inventoryCache := cache.NewInventoryCache(s.Configuration.CacheTTL)
+	clients.assetsClient = cache.NewAssetsClient(clients.assetsClient, inventoryCache)
+	clients.controllersClient = cache.NewControllersClient(clients.controllersClient, inventoryCache)
+	clients.deviceManagerClient = cache.NewDevicesClient(clients.deviceManagerClient, inventoryCache)
+
+	// and are published to the watchers of the inventory
+	broadcaster := watch.NewBroadcaster(s.Configuration.WatchBufferSize)
+	// the periodic jobs are stopped on shutdown
+	backgroundJobs := jobs.NewGroup()
+	statusMonitor := watch.NewStatusMonitor(broadcaster, s.Configuration)
+	statusMonitor.Run(backgroundJobs)
+	operationProvider, pErr := operation.NewProvider(s.Configuration.StoragePath, s.Configuration.OperationHistorySize)
+	if pErr != nil {
+		log.Fatal().Str("err", pErr.DebugReport()).Msg("Cannot create operation history provider")
+	}
+	operationHistory := watch.NewOperationHistory(operationProvider)
+	clients.assetsClient = watch.NewAssetsClient(clients.assetsClient, broadcaster, statusMonitor, operationHistory)
+	clients.controllersClient = watch.NewControllersClient(clients.controllersClient, broadcaster, statusMonitor, operationHistory)
+	clients.deviceManagerClient = watch.NewDevicesClient(clients.deviceManagerClient, broadcaster)
+
 	busClients, bErr := s.GetBusClients()
 	if bErr != nil {
 		log.Fatal().Str("err", cErr.DebugReport()).Msg("Cannot create bus clients")
@@ -167,9 +218,13 @@ func (s *Service) Run() error {
 
 	// Create handlers
 
+	connectedAgents := reconciliation.NewConnectedAgents()
+
 	agentManager := agent.NewManager(
-		clients.edgeInvProxyControllerClient, clients.assetsClient, clients.controllersClient, s.Configuration.CACertRaw)
+		clients.edgeInvProxyControllerClient, clients.assetsClient, clients.controllersClient, clients.orgClient,
+		connectedAgents, s.Configuration)
 	agentHandler := agent.NewHandler(agentManager)
+	agentManager.Run(backgroundJobs)
 
 	ecManager := edgecontroller.NewManager(
 		clients.authxClient,
@@ -182,22 +237,91 @@ func (s *Service) Run() error {
 		s.Configuration)
 	ecHandler := edgecontroller.NewHandler(ecManager)
 
-	invManager := inventory.NewManager(clients.deviceManagerClient, clients.assetsClient, clients.controllersClient, s.Configuration)
+	savedQueries, pErr := savedquery.NewProvider(s.Configuration.StoragePath)
+	if pErr != nil {
+		log.Fatal().Str("err", pErr.DebugReport()).Msg("Cannot create saved queries provider")
+	}
+
+	attributeSchemas, pErr := attribute.NewProvider(s.Configuration.StoragePath)
+	if pErr != nil {
+		log.Fatal().Str("err", pErr.DebugReport()).Msg("Cannot create attribute schemas provider")
+	}
+
+	invManager := inventory.NewManager(clients.deviceManagerClient, clients.assetsClient, clients.controllersClient,
+		clients.vpnClient, clients.netManagerClient, savedQueries, attributeSchemas, inventoryCache, broadcaster,
+		operationHistory, s.Configuration)
 	invHandler := inventory.NewHandler(invManager)
 
+	reconciliationManager := reconciliation.NewManager(
+		clients.orgClient,
+		clients.controllersClient,
+		clients.assetsClient,
+		clients.vpnClient,
+		clients.netManagerClient,
+		connectedAgents,
+		s.Configuration)
+	reconciliationHandler := reconciliation.NewHandler(reconciliationManager)
+	reconciliationManager.Run(backgroundJobs)
+
+	snapshotProvider, pErr := snapshot.NewProvider(s.Configuration.StoragePath)
+	if pErr != nil {
+		log.Fatal().Str("err", pErr.DebugReport()).Msg("Cannot create snapshot provider")
+	}
+	snapshotsManager := snapshots.NewManager(clients.orgClient, invManager, snapshotProvider, s.Configuration)
+	snapshotsHandler := snapshots.NewHandler(snapshotsManager)
+	snapshotsManager.Run(backgroundJobs)
+
+	trendProvider, pErr := trend.NewProvider(s.Configuration.StoragePath)
+	if pErr != nil {
+		log.Fatal().Str("err", pErr.DebugReport()).Msg("Cannot create trend provider")
+	}
+	trendsManager := trends.NewManager(clients.orgClient, invManager, trendProvider, s.Configuration)
+	trendsHandler := trends.NewHandler(trendsManager)
+	trendsManager.Run(backgroundJobs)
+
+	auditProvider, pErr := audit.NewProvider(s.Configuration.StoragePath)
+	if pErr != nil {
+		log.Fatal().Str("err", pErr.DebugReport()).Msg("Cannot create audit provider")
+	}
+	auditManager := audits.NewManager(auditProvider)
+	auditHandler := audits.NewHandler(auditManager)
+
+	retentionProvider, pErr := retention.NewProvider(s.Configuration.StoragePath)
+	if pErr != nil {
+		log.Fatal().Str("err", pErr.DebugReport()).Msg("Cannot create retention provider")
+	}
+	retentionsManager := retentions.NewManager(invManager, ecManager, clients.assetsClient, clients.controllersClient,
+		retentionProvider, auditManager, s.Configuration)
+	retentionsHandler := retentions.NewHandler(retentionsManager)
+	retentionsManager.Run(backgroundJobs)
+
 	// Consumers
 
-	inventoryEventsConsumer := bus.NewInventoryEventsHandler(ecHandler, agentHandler, busClients.inventoryEventsConsumer)
+	deadLetterProvider, pErr := deadletter.NewProvider(s.Configuration.StoragePath)
+	if pErr != nil {
+		log.Fatal().Str("err", pErr.DebugReport()).Msg("Cannot create dead letter provider")
+	}
+	busProcessor := bus.NewProcessor(ecHandler, agentHandler, deadLetterProvider, s.Configuration)
+	deadLettersManager := deadletters.NewManager(deadLetterProvider, busProcessor)
+	deadLettersHandler := deadletters.NewHandler(deadLettersManager)
+
+	inventoryEventsConsumer := bus.NewInventoryEventsHandler(busProcessor, busClients.inventoryEventsConsumer, s.Configuration)
 	inventoryEventsConsumer.Run()
 
-	inventoryOpsConsumer := bus.NewInventoryOpsHandler(agentHandler, ecHandler, busClients.inventoryOpsConsumer)
+	inventoryOpsConsumer := bus.NewInventoryOpsHandler(busProcessor, busClients.inventoryOpsConsumer, s.Configuration)
 	inventoryOpsConsumer.Run()
 
 
-	grpcServer := grpc.NewServer()
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(auditManager.UnaryInterceptor()))
 	grpc_inventory_manager_go.RegisterInventoryServer(grpcServer, invHandler)
 	grpc_inventory_manager_go.RegisterAgentServer(grpcServer, agentHandler)
 	grpc_inventory_manager_go.RegisterEICServer(grpcServer, ecHandler)
+	grpc_inventory_manager_go.RegisterReconciliationServer(grpcServer, reconciliationHandler)
+	grpc_inventory_manager_go.RegisterSnapshotsServer(grpcServer, snapshotsHandler)
+	grpc_inventory_manager_go.RegisterTrendsServer(grpcServer, trendsHandler)
+	grpc_inventory_manager_go.RegisterRetentionServer(grpcServer, retentionsHandler)
+	grpc_inventory_manager_go.RegisterAuditServer(grpcServer, auditHandler)
+	grpc_inventory_manager_go.RegisterDeadLettersServer(grpcServer, deadLettersHandler)
 
 	if s.Configuration.Debug {
 		log.Info().Msg("Enabling gRPC server reflection")
@@ -205,8 +329,68 @@ func (s *Service) Run() error {
 		reflection.Register(grpcServer)
 	}
 	log.Info().Int("port", s.Configuration.Port).Msg("Launching gRPC server")
-	if err := grpcServer.Serve(lis); err != nil {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- grpcServer.Serve(lis)
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-serveErr:
 		log.Fatal().Errs("failed to serve: %v", []error{err})
+	case received := <-signals:
+		log.Info().Str("signal", received.String()).Msg("shutting down the inventory manager")
+	}
+
+	// everything is stopped before waiting so the consumers and the jobs share the whole grace period
+	ctx, cancel := context.WithTimeout(context.Background(), s.Configuration.ShutdownGracePeriod)
+	defer cancel()
+	inventoryEventsConsumer.Stop()
+	inventoryOpsConsumer.Stop()
+	backgroundJobs.Stop()
+	stopServer(ctx, grpcServer, broadcaster)
+	if err := inventoryEventsConsumer.Wait(ctx); err != nil {
+		log.Warn().Str("err", err.DebugReport()).Msg("inventory events consumer not stopped gracefully")
 	}
+	if err := inventoryOpsConsumer.Wait(ctx); err != nil {
+		log.Warn().Str("err", err.DebugReport()).Msg("inventory ops consumer not stopped gracefully")
+	}
+	if err := backgroundJobs.Wait(ctx); err != nil {
+		log.Warn().Str("err", err.DebugReport()).Msg("periodic jobs not stopped gracefully")
+	}
+	closeClients(clients, busClients)
+	log.Info().Msg("inventory manager stopped")
 	return nil
 }
+
+// stopServer stops accepting requests and waits for the in-flight ones to finish. The remaining requests are
+// cancelled when the context expires.
+func stopServer(ctx context.Context, grpcServer *grpc.Server, broadcaster *watch.Broadcaster) {
+	// the watch streams do not finish by themselves
+	broadcaster.Close()
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		log.Info().Msg("gRPC server stopped")
+	case <-ctx.Done():
+		log.Warn().Msg("grace period expired, cancelling the in-flight requests")
+		grpcServer.Stop()
+	}
+}
+
+// closeClients closes the connections with the queue and the remote services.
+func closeClients(clients *Clients, busClients *BusClients) {
+	if err := busClients.queueClient.Close(); err != nil {
+		log.Warn().Str("err", err.DebugReport()).Msg("cannot close the queue client")
+	}
+	for _, conn := range clients.connections {
+		if err := conn.Close(); err != nil {
+			log.Warn().Err(err).Str("target", conn.Target()).Msg("cannot close the connection")
+		}
+	}
+}
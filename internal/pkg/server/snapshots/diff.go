@@ -0,0 +1,166 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package snapshots
+
+import (
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"github.com/nalej/inventory-manager/internal/pkg/export"
+	"sort"
+	"strings"
+)
+
+// IgnoredFields contains the fields that change on every ping or are derived from other fields and are not reported in the diffs.
+// The nested fields of an ignored field are ignored too.
+var IgnoredFields = map[string]bool{
+	"last_alive_timestamp": true,
+	"status":               true,
+	"device_status":        true,
+	"asset_device_id":      true,
+	"resources":            true,
+	"attributes":           true,
+}
+
+// diffInventories compares two inventories returning the entities added, removed or changed.
+func diffInventories(from *grpc_inventory_manager_go.InventoryList, to *grpc_inventory_manager_go.InventoryList) ([]*grpc_inventory_manager_go.EntityChange, derrors.Error) {
+	fromEntities, err := indexEntities(from)
+	if err != nil {
+		return nil, err
+	}
+	toEntities, err := indexEntities(to)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*grpc_inventory_manager_go.EntityChange, 0)
+	for key, before := range fromEntities {
+		after, exists := toEntities[key]
+		if !exists {
+			result = append(result, &grpc_inventory_manager_go.EntityChange{
+				Type:     key.entityType,
+				EntityId: key.id,
+				Change:   grpc_inventory_manager_go.ChangeType_REMOVED,
+			})
+			continue
+		}
+		fields := diffFields(before, after)
+		if len(fields) > 0 {
+			result = append(result, &grpc_inventory_manager_go.EntityChange{
+				Type:     key.entityType,
+				EntityId: key.id,
+				Change:   grpc_inventory_manager_go.ChangeType_CHANGED,
+				Fields:   fields,
+			})
+		}
+	}
+	for key := range toEntities {
+		if _, exists := fromEntities[key]; !exists {
+			result = append(result, &grpc_inventory_manager_go.EntityChange{
+				Type:     key.entityType,
+				EntityId: key.id,
+				Change:   grpc_inventory_manager_go.ChangeType_ADDED,
+			})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Type != result[j].Type {
+			return result[i].Type < result[j].Type
+		}
+		return result[i].EntityId < result[j].EntityId
+	})
+	return result, nil
+}
+
+type entityKey struct {
+	entityType grpc_inventory_manager_go.InventoryEntityType
+	id         string
+}
+
+// indexEntities flattens the entities of an inventory indexed by type and identifier.
+func indexEntities(list *grpc_inventory_manager_go.InventoryList) (map[entityKey]map[string]string, derrors.Error) {
+	result := make(map[entityKey]map[string]string)
+	if list == nil {
+		return result, nil
+	}
+	add := func(entityType grpc_inventory_manager_go.InventoryEntityType, id string, entity interface{}) derrors.Error {
+		fields, err := export.Flatten(entity, export.DefaultOptions())
+		if err != nil {
+			return err
+		}
+		for field := range fields {
+			if isIgnored(field) {
+				delete(fields, field)
+			}
+		}
+		result[entityKey{entityType: entityType, id: id}] = fields
+		return nil
+	}
+	for _, asset := range list.Assets {
+		if err := add(grpc_inventory_manager_go.InventoryEntityType_ASSET, asset.AssetId, asset); err != nil {
+			return nil, err
+		}
+	}
+	for _, controller := range list.Controllers {
+		if err := add(grpc_inventory_manager_go.InventoryEntityType_CONTROLLER, controller.EdgeControllerId, controller); err != nil {
+			return nil, err
+		}
+	}
+	// Devices are indexed by their current identifier so snapshots taken with a previous version can be compared.
+	for _, device := range list.Devices {
+		if err := add(grpc_inventory_manager_go.InventoryEntityType_DEVICE, entities.GetAssetDeviceID(device.DeviceGroupId, device.DeviceId), device); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// isIgnored checks if a flattened field is one of the IgnoredFields or is nested in one of them.
+func isIgnored(field string) bool {
+	for ignored := range IgnoredFields {
+		if field == ignored || strings.HasPrefix(field, ignored+export.DefaultSeparator) {
+			return true
+		}
+	}
+	return false
+}
+
+// diffFields returns the fields with different values, including the ones only present in one of the entities.
+func diffFields(before map[string]string, after map[string]string) []*grpc_inventory_manager_go.FieldChange {
+	result := make([]*grpc_inventory_manager_go.FieldChange, 0)
+	for field, value := range before {
+		if after[field] != value {
+			result = append(result, &grpc_inventory_manager_go.FieldChange{
+				Field:  field,
+				Before: value,
+				After:  after[field],
+			})
+		}
+	}
+	for field, value := range after {
+		if _, exists := before[field]; !exists {
+			result = append(result, &grpc_inventory_manager_go.FieldChange{
+				Field: field,
+				After: value,
+			})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Field < result[j].Field
+	})
+	return result
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package snapshots
+
+import (
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Inventory diffs", func() {
+
+	asset := func(assetID string, ip string, ramBytes int64, lastAlive int64) *grpc_inventory_manager_go.Asset {
+		return &grpc_inventory_manager_go.Asset{
+			OrganizationId:     "org",
+			AssetId:            assetID,
+			EicNetIp:           ip,
+			LastAliveTimestamp: lastAlive,
+			Resources:          &grpc_inventory_manager_go.NormalizedResources{RamBytes: ramBytes},
+			Attributes:         map[string]string{"rack": ip},
+		}
+	}
+
+	ginkgo.It("should not report the changes of the ignored fields or their nested fields", func() {
+		from := &grpc_inventory_manager_go.InventoryList{
+			Assets: []*grpc_inventory_manager_go.Asset{asset("a1", "10.0.0.1", 1024, 100)},
+		}
+		to := &grpc_inventory_manager_go.InventoryList{
+			Assets: []*grpc_inventory_manager_go.Asset{asset("a1", "10.0.0.1", 2048, 200)},
+		}
+		to.Assets[0].Attributes["rack"] = "r2"
+		changes, err := diffInventories(from, to)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(changes).To(gomega.BeEmpty())
+	})
+
+	ginkgo.It("should report the other fields of an entity with changed ignored fields", func() {
+		from := &grpc_inventory_manager_go.InventoryList{
+			Assets: []*grpc_inventory_manager_go.Asset{asset("a1", "10.0.0.1", 1024, 100)},
+		}
+		to := &grpc_inventory_manager_go.InventoryList{
+			Assets: []*grpc_inventory_manager_go.Asset{asset("a1", "10.0.0.2", 2048, 200)},
+		}
+		changes, err := diffInventories(from, to)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(changes).To(gomega.HaveLen(1))
+		gomega.Expect(changes[0].Fields).To(gomega.Equal([]*grpc_inventory_manager_go.FieldChange{
+			{Field: "eic_net_ip", Before: "10.0.0.1", After: "10.0.0.2"},
+		}))
+	})
+
+	ginkgo.It("should report the added, removed and changed entities sorted by type and identifier", func() {
+		from := &grpc_inventory_manager_go.InventoryList{
+			Assets: []*grpc_inventory_manager_go.Asset{
+				asset("kept", "10.0.0.1", 1024, 100),
+				asset("removed", "10.0.0.2", 1024, 100),
+				asset("changed", "10.0.0.3", 1024, 100),
+			},
+			Controllers: []*grpc_inventory_manager_go.EdgeController{
+				{OrganizationId: "org", EdgeControllerId: "ec", Name: "old"},
+			},
+			Devices: []*grpc_inventory_manager_go.Device{
+				{OrganizationId: "org", DeviceGroupId: "group", DeviceId: "removed"},
+			},
+		}
+		to := &grpc_inventory_manager_go.InventoryList{
+			Assets: []*grpc_inventory_manager_go.Asset{
+				asset("added", "10.0.0.4", 1024, 100),
+				asset("changed", "10.0.0.5", 1024, 100),
+				asset("kept", "10.0.0.1", 1024, 200),
+			},
+			Controllers: []*grpc_inventory_manager_go.EdgeController{
+				{OrganizationId: "org", EdgeControllerId: "ec", Name: "new"},
+			},
+			Devices: []*grpc_inventory_manager_go.Device{
+				{OrganizationId: "org", DeviceGroupId: "group", DeviceId: "added"},
+			},
+		}
+		to.Assets[1].Labels = map[string]string{"env": "prod"}
+		changes, err := diffInventories(from, to)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(changes).To(gomega.Equal([]*grpc_inventory_manager_go.EntityChange{
+			{Type: grpc_inventory_manager_go.InventoryEntityType_ASSET, EntityId: "added", Change: grpc_inventory_manager_go.ChangeType_ADDED},
+			{Type: grpc_inventory_manager_go.InventoryEntityType_ASSET, EntityId: "changed", Change: grpc_inventory_manager_go.ChangeType_CHANGED,
+				Fields: []*grpc_inventory_manager_go.FieldChange{
+					{Field: "eic_net_ip", Before: "10.0.0.3", After: "10.0.0.5"},
+					{Field: "labels.env", After: "prod"},
+				}},
+			{Type: grpc_inventory_manager_go.InventoryEntityType_ASSET, EntityId: "removed", Change: grpc_inventory_manager_go.ChangeType_REMOVED},
+			{Type: grpc_inventory_manager_go.InventoryEntityType_CONTROLLER, EntityId: "ec", Change: grpc_inventory_manager_go.ChangeType_CHANGED,
+				Fields: []*grpc_inventory_manager_go.FieldChange{{Field: "name", Before: "old", After: "new"}}},
+			{Type: grpc_inventory_manager_go.InventoryEntityType_DEVICE, EntityId: entities.GetAssetDeviceID("group", "added"),
+				Change: grpc_inventory_manager_go.ChangeType_ADDED},
+			{Type: grpc_inventory_manager_go.InventoryEntityType_DEVICE, EntityId: entities.GetAssetDeviceID("group", "removed"),
+				Change: grpc_inventory_manager_go.ChangeType_REMOVED},
+		}))
+	})
+
+	ginkgo.It("should report every entity as added from an empty inventory", func() {
+		to := &grpc_inventory_manager_go.InventoryList{
+			Assets: []*grpc_inventory_manager_go.Asset{asset("a1", "10.0.0.1", 1024, 100)},
+		}
+		changes, err := diffInventories(nil, to)
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(changes).To(gomega.HaveLen(1))
+		gomega.Expect(changes[0].Change).To(gomega.Equal(grpc_inventory_manager_go.ChangeType_ADDED))
+	})
+})
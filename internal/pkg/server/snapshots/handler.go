@@ -0,0 +1,81 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package snapshots
+
+import (
+	"github.com/nalej/grpc-common-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"golang.org/x/net/context"
+)
+
+type Handler struct {
+	manager Manager
+}
+
+func NewHandler(manager Manager) *Handler {
+	return &Handler{
+		manager: manager,
+	}
+}
+
+// TakeSnapshot captures the current inventory of an organization.
+func (h *Handler) TakeSnapshot(_ context.Context, orgID *grpc_organization_go.OrganizationId) (*grpc_inventory_manager_go.InventorySnapshotInfo, error) {
+	vErr := entities.ValidOrganizationID(orgID)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.TakeSnapshot(orgID)
+}
+
+// ListSnapshots retrieves the information of the snapshots of an organization.
+func (h *Handler) ListSnapshots(_ context.Context, orgID *grpc_organization_go.OrganizationId) (*grpc_inventory_manager_go.SnapshotList, error) {
+	vErr := entities.ValidOrganizationID(orgID)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.ListSnapshots(orgID)
+}
+
+// GetSnapshot retrieves a snapshot with its inventory.
+func (h *Handler) GetSnapshot(_ context.Context, snapshotID *grpc_inventory_manager_go.SnapshotId) (*grpc_inventory_manager_go.InventorySnapshot, error) {
+	vErr := entities.ValidSnapshotId(snapshotID)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.GetSnapshot(snapshotID)
+}
+
+// RemoveSnapshot removes a snapshot.
+func (h *Handler) RemoveSnapshot(_ context.Context, snapshotID *grpc_inventory_manager_go.SnapshotId) (*grpc_common_go.Success, error) {
+	vErr := entities.ValidSnapshotId(snapshotID)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.RemoveSnapshot(snapshotID)
+}
+
+// DiffSnapshots compares two snapshots reporting the added, removed and changed entities.
+func (h *Handler) DiffSnapshots(_ context.Context, request *grpc_inventory_manager_go.DiffSnapshotsRequest) (*grpc_inventory_manager_go.InventoryDiff, error) {
+	vErr := entities.ValidDiffSnapshotsRequest(request)
+	if vErr != nil {
+		return nil, conversions.ToGRPCError(vErr)
+	}
+	return h.manager.DiffSnapshots(request)
+}
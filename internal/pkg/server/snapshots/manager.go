@@ -0,0 +1,166 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package snapshots
+
+import (
+	"context"
+	"github.com/nalej/derrors"
+	"github.com/nalej/grpc-common-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/inventory-manager/internal/pkg/config"
+	"github.com/nalej/inventory-manager/internal/pkg/jobs"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/snapshot"
+	"github.com/nalej/inventory-manager/internal/pkg/server/contexts"
+	"github.com/nalej/inventory-manager/internal/pkg/server/inventory"
+	"github.com/rs/zerolog/log"
+	"github.com/satori/go.uuid"
+	"time"
+)
+
+type Manager struct {
+	orgClient        grpc_organization_go.OrganizationsClient
+	inventoryManager inventory.Manager
+	provider         snapshot.Provider
+	cfg              config.Config
+}
+
+func NewManager(orgClient grpc_organization_go.OrganizationsClient, inventoryManager inventory.Manager,
+	provider snapshot.Provider, cfg config.Config) Manager {
+	return Manager{
+		orgClient:        orgClient,
+		inventoryManager: inventoryManager,
+		provider:         provider,
+		cfg:              cfg,
+	}
+}
+
+// Run launches the scheduled snapshots of all the organizations.
+func (m *Manager) Run(group *jobs.Group) {
+	if m.cfg.SnapshotPeriod == 0 {
+		log.Info().Msg("scheduled snapshots are disabled")
+		return
+	}
+	log.Debug().Str("period", m.cfg.SnapshotPeriod.String()).Msg("launching scheduled snapshots")
+	group.Every(m.cfg.SnapshotPeriod, m.scheduledSnapshots)
+}
+
+// scheduledSnapshots takes snapshots of the organizations until the jobs are stopped.
+func (m *Manager) scheduledSnapshots(stopped context.Context) {
+	ctx, cancel := contexts.SMContext()
+	organizations, err := m.orgClient.ListOrganizations(ctx, &grpc_common_go.Empty{})
+	cancel()
+	if err != nil {
+		log.Error().Str("trace", conversions.ToDerror(err).DebugReport()).Msg("cannot list organizations to take snapshots")
+		return
+	}
+	for _, org := range organizations.Organizations {
+		if stopped.Err() != nil {
+			return
+		}
+		_, err := m.takeSnapshot(org.OrganizationId, grpc_inventory_manager_go.SnapshotTrigger_SCHEDULED)
+		if err != nil {
+			log.Warn().Str("organization_id", org.OrganizationId).Str("trace", conversions.ToDerror(err).DebugReport()).
+				Msg("scheduled snapshot failed")
+		}
+	}
+}
+
+// TakeSnapshot captures the current inventory of an organization. Partial inventories are rejected.
+func (m *Manager) TakeSnapshot(organizationID *grpc_organization_go.OrganizationId) (*grpc_inventory_manager_go.InventorySnapshotInfo, error) {
+	return m.takeSnapshot(organizationID.OrganizationId, grpc_inventory_manager_go.SnapshotTrigger_MANUAL)
+}
+
+func (m *Manager) takeSnapshot(organizationID string, trigger grpc_inventory_manager_go.SnapshotTrigger) (*grpc_inventory_manager_go.InventorySnapshotInfo, error) {
+	list, err := m.inventoryManager.List(&grpc_organization_go.OrganizationId{OrganizationId: organizationID})
+	if err != nil {
+		return nil, err
+	}
+	// a partial inventory would be reported as removed entities in the diffs
+	if len(list.Errors) > 0 {
+		return nil, conversions.ToGRPCError(derrors.NewUnavailableError("the inventory cannot be completely retrieved").
+			WithParams(organizationID, len(list.Errors)))
+	}
+	info := &grpc_inventory_manager_go.InventorySnapshotInfo{
+		OrganizationId: organizationID,
+		SnapshotId:     uuid.NewV4().String(),
+		Created:        time.Now().Unix(),
+		Trigger:        trigger,
+		Summary:        m.inventoryManager.SummaryFromList(organizationID, list),
+	}
+	pErr := m.provider.Add(&grpc_inventory_manager_go.InventorySnapshot{
+		Info:      info,
+		Inventory: list,
+	})
+	if pErr != nil {
+		return nil, conversions.ToGRPCError(pErr)
+	}
+	log.Debug().Str("organization_id", organizationID).Str("snapshot_id", info.SnapshotId).Msg("inventory snapshot taken")
+	return info, nil
+}
+
+// ListSnapshots retrieves the information of the snapshots of an organization.
+func (m *Manager) ListSnapshots(organizationID *grpc_organization_go.OrganizationId) (*grpc_inventory_manager_go.SnapshotList, error) {
+	snapshots, err := m.provider.List(organizationID.OrganizationId)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return &grpc_inventory_manager_go.SnapshotList{
+		Snapshots: snapshots,
+	}, nil
+}
+
+// GetSnapshot retrieves a snapshot with its inventory.
+func (m *Manager) GetSnapshot(snapshotID *grpc_inventory_manager_go.SnapshotId) (*grpc_inventory_manager_go.InventorySnapshot, error) {
+	result, err := m.provider.Get(snapshotID.OrganizationId, snapshotID.SnapshotId)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return result, nil
+}
+
+// RemoveSnapshot removes a snapshot.
+func (m *Manager) RemoveSnapshot(snapshotID *grpc_inventory_manager_go.SnapshotId) (*grpc_common_go.Success, error) {
+	err := m.provider.Remove(snapshotID.OrganizationId, snapshotID.SnapshotId)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return &grpc_common_go.Success{}, nil
+}
+
+// DiffSnapshots compares two snapshots of an organization.
+func (m *Manager) DiffSnapshots(request *grpc_inventory_manager_go.DiffSnapshotsRequest) (*grpc_inventory_manager_go.InventoryDiff, error) {
+	from, err := m.provider.Get(request.OrganizationId, request.FromSnapshotId)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	to, err := m.provider.Get(request.OrganizationId, request.ToSnapshotId)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	changes, err := diffInventories(from.Inventory, to.Inventory)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	return &grpc_inventory_manager_go.InventoryDiff{
+		OrganizationId: request.OrganizationId,
+		From:           from.Info,
+		To:             to.Info,
+		Changes:        changes,
+	}, nil
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package trends
+
+import (
+	"github.com/nalej/grpc-inventory-manager-go"
+)
+
+// MaxTrendPoints with the maximum number of points returned when the request does not set a step.
+const MaxTrendPoints = 500
+
+// defaultStep returns the step that keeps a series under MaxTrendPoints, or zero if the points do not
+// need to be downsampled.
+func defaultStep(from int64, to int64, numPoints int) int64 {
+	if numPoints <= MaxTrendPoints {
+		return 0
+	}
+	step := (to - from + 1) / MaxTrendPoints
+	if (to-from+1)%MaxTrendPoints != 0 {
+		step++
+	}
+	return step
+}
+
+// downsample aggregates the points in buckets of step seconds starting at from. Each resulting point is
+// timestamped with the start of its bucket and the buckets without points are omitted.
+func downsample(points []*grpc_inventory_manager_go.TrendPoint, from int64, step int64,
+	aggregation grpc_inventory_manager_go.TrendAggregation) []*grpc_inventory_manager_go.TrendPoint {
+	result := make([]*grpc_inventory_manager_go.TrendPoint, 0)
+	var bucket []*grpc_inventory_manager_go.TrendPoint
+	var bucketStart int64
+	for _, point := range points {
+		start := from + (point.Timestamp-from)/step*step
+		if len(bucket) > 0 && start != bucketStart {
+			result = append(result, aggregate(bucket, bucketStart, aggregation))
+			bucket = nil
+		}
+		bucketStart = start
+		bucket = append(bucket, point)
+	}
+	if len(bucket) > 0 {
+		result = append(result, aggregate(bucket, bucketStart, aggregation))
+	}
+	return result
+}
+
+// aggregate combines the points of a bucket. The samples of the result add the samples of the points.
+func aggregate(points []*grpc_inventory_manager_go.TrendPoint, timestamp int64,
+	aggregation grpc_inventory_manager_go.TrendAggregation) *grpc_inventory_manager_go.TrendPoint {
+	result := &grpc_inventory_manager_go.TrendPoint{Timestamp: timestamp}
+	for _, point := range points {
+		result.Samples += point.Samples
+	}
+	switch aggregation {
+	case grpc_inventory_manager_go.TrendAggregation_LAST:
+		last := points[len(points)-1]
+		setValues(result, values(last))
+	case grpc_inventory_manager_go.TrendAggregation_MIN, grpc_inventory_manager_go.TrendAggregation_MAX:
+		combined := values(points[0])
+		for _, point := range points[1:] {
+			for index, value := range values(point) {
+				if (aggregation == grpc_inventory_manager_go.TrendAggregation_MIN && value < combined[index]) ||
+					(aggregation == grpc_inventory_manager_go.TrendAggregation_MAX && value > combined[index]) {
+					combined[index] = value
+				}
+			}
+		}
+		setValues(result, combined)
+	default:
+		sums := make([]int64, len(values(points[0])))
+		for _, point := range points {
+			for index, value := range values(point) {
+				sums[index] += value
+			}
+		}
+		for index := range sums {
+			sums[index] /= int64(len(points))
+		}
+		setValues(result, sums)
+	}
+	return result
+}
+
+// values returns the measures of a point in a fixed order.
+func values(point *grpc_inventory_manager_go.TrendPoint) []int64 {
+	return []int64{point.NumCpu, point.StorageMb, point.RamMb, point.NumDevices, point.NumAssets, point.NumControllers}
+}
+
+func setValues(point *grpc_inventory_manager_go.TrendPoint, values []int64) {
+	point.NumCpu, point.StorageMb, point.RamMb = values[0], values[1], values[2]
+	point.NumDevices, point.NumAssets, point.NumControllers = values[3], values[4], values[5]
+}
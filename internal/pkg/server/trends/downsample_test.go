@@ -0,0 +1,61 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package trends
+
+import (
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Trend downsampling", func() {
+
+	points := []*grpc_inventory_manager_go.TrendPoint{
+		{Timestamp: 100, NumCpu: 4, RamMb: 1024, NumAssets: 1, Samples: 1},
+		{Timestamp: 150, NumCpu: 8, RamMb: 2048, NumAssets: 2, Samples: 1},
+		{Timestamp: 230, NumCpu: 16, RamMb: 4096, NumAssets: 3, Samples: 1},
+	}
+
+	ginkgo.It("should average the points of each bucket", func() {
+		result := downsample(points, 100, 100, grpc_inventory_manager_go.TrendAggregation_AVERAGE)
+		gomega.Expect(result).To(gomega.HaveLen(2))
+		gomega.Expect(*result[0]).To(gomega.Equal(grpc_inventory_manager_go.TrendPoint{
+			Timestamp: 100, NumCpu: 6, RamMb: 1536, NumAssets: 1, Samples: 2,
+		}))
+		gomega.Expect(result[1].Timestamp).To(gomega.Equal(int64(200)))
+		gomega.Expect(result[1].NumCpu).To(gomega.Equal(int64(16)))
+	})
+
+	ginkgo.It("should keep the maximum, minimum or last values", func() {
+		maximum := downsample(points, 0, 1000, grpc_inventory_manager_go.TrendAggregation_MAX)
+		gomega.Expect(maximum).To(gomega.HaveLen(1))
+		gomega.Expect(maximum[0].NumCpu).To(gomega.Equal(int64(16)))
+		gomega.Expect(maximum[0].Samples).To(gomega.Equal(int32(3)))
+
+		minimum := downsample(points, 0, 1000, grpc_inventory_manager_go.TrendAggregation_MIN)
+		gomega.Expect(minimum[0].RamMb).To(gomega.Equal(int64(1024)))
+
+		last := downsample(points, 0, 1000, grpc_inventory_manager_go.TrendAggregation_LAST)
+		gomega.Expect(last[0].NumAssets).To(gomega.Equal(int64(3)))
+	})
+
+	ginkgo.It("should choose a step that limits the number of points", func() {
+		gomega.Expect(defaultStep(0, 999, MaxTrendPoints)).To(gomega.BeZero())
+		step := defaultStep(0, 9999, MaxTrendPoints+1)
+		gomega.Expect(10000 / step).To(gomega.BeNumerically("<=", MaxTrendPoints))
+	})
+})
@@ -0,0 +1,140 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package trends
+
+import (
+	"context"
+	"github.com/nalej/grpc-common-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/grpc-organization-go"
+	"github.com/nalej/grpc-utils/pkg/conversions"
+	"github.com/nalej/inventory-manager/internal/pkg/config"
+	"github.com/nalej/inventory-manager/internal/pkg/jobs"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/trend"
+	"github.com/nalej/inventory-manager/internal/pkg/server/contexts"
+	"github.com/nalej/inventory-manager/internal/pkg/server/inventory"
+	"github.com/rs/zerolog/log"
+	"time"
+)
+
+type Manager struct {
+	orgClient        grpc_organization_go.OrganizationsClient
+	inventoryManager inventory.Manager
+	provider         trend.Provider
+	cfg              config.Config
+}
+
+func NewManager(orgClient grpc_organization_go.OrganizationsClient, inventoryManager inventory.Manager,
+	provider trend.Provider, cfg config.Config) Manager {
+	return Manager{
+		orgClient:        orgClient,
+		inventoryManager: inventoryManager,
+		provider:         provider,
+		cfg:              cfg,
+	}
+}
+
+// Run launches the periodic records of the capacity of all the organizations.
+func (m *Manager) Run(group *jobs.Group) {
+	if m.cfg.TrendPeriod == 0 {
+		log.Info().Msg("capacity trends are disabled")
+		return
+	}
+	log.Debug().Str("period", m.cfg.TrendPeriod.String()).Msg("launching capacity records")
+	group.Every(m.cfg.TrendPeriod, m.scheduledRecords)
+}
+
+// scheduledRecords records the capacity of the organizations until the jobs are stopped.
+func (m *Manager) scheduledRecords(stopped context.Context) {
+	ctx, cancel := contexts.SMContext()
+	organizations, err := m.orgClient.ListOrganizations(ctx, &grpc_common_go.Empty{})
+	cancel()
+	if err != nil {
+		log.Error().Str("trace", conversions.ToDerror(err).DebugReport()).Msg("cannot list organizations to record their capacity")
+		return
+	}
+	for _, org := range organizations.Organizations {
+		if stopped.Err() != nil {
+			return
+		}
+		err := m.record(org.OrganizationId)
+		if err != nil {
+			log.Warn().Str("organization_id", org.OrganizationId).Str("trace", conversions.ToDerror(err).DebugReport()).
+				Msg("cannot record the capacity")
+		}
+	}
+	pErr := m.provider.Prune(time.Now().Add(-m.cfg.TrendRetention).Unix())
+	if pErr != nil {
+		log.Error().Str("trace", pErr.DebugReport()).Msg("cannot prune the capacity records")
+	}
+}
+
+// record stores the current capacity of an organization. The partial inventories are not recorded as they would
+// appear as drops in the trend.
+func (m *Manager) record(organizationID string) error {
+	summary, err := m.inventoryManager.Summary(&grpc_organization_go.OrganizationId{OrganizationId: organizationID})
+	if err != nil {
+		return err
+	}
+	if len(summary.Errors) > 0 {
+		log.Warn().Str("organization_id", organizationID).Int("errors", len(summary.Errors)).
+			Msg("capacity not recorded from a partial inventory")
+		return nil
+	}
+	pErr := m.provider.Add(organizationID, &grpc_inventory_manager_go.TrendPoint{
+		Timestamp:      time.Now().Unix(),
+		NumCpu:         summary.Totals.NumCpu,
+		StorageMb:      summary.Totals.StorageMb,
+		RamMb:          summary.Totals.RamMb,
+		NumDevices:     summary.NumDevices,
+		NumAssets:      summary.NumAssets,
+		NumControllers: summary.NumControllers,
+		Samples:        1,
+	})
+	if pErr != nil {
+		return conversions.ToGRPCError(pErr)
+	}
+	return nil
+}
+
+// GetTrend retrieves the capacity of an organization in a time range. If no step is requested, the step is chosen
+// so no more than MaxTrendPoints are returned.
+func (m *Manager) GetTrend(request *grpc_inventory_manager_go.TrendRequest) (*grpc_inventory_manager_go.TrendSeries, error) {
+	to := request.To
+	if to == 0 {
+		to = time.Now().Unix()
+	}
+	points, err := m.provider.List(request.OrganizationId, request.From, to)
+	if err != nil {
+		return nil, conversions.ToGRPCError(err)
+	}
+	step := request.Step
+	if step == 0 {
+		step = defaultStep(request.From, to, len(points))
+	}
+	if step > 0 {
+		points = downsample(points, request.From, step, request.Aggregation)
+	}
+	return &grpc_inventory_manager_go.TrendSeries{
+		OrganizationId: request.OrganizationId,
+		From:           request.From,
+		To:             to,
+		Step:           step,
+		Aggregation:    request.Aggregation,
+		Points:         points,
+	}, nil
+}
@@ -0,0 +1,169 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package units
+
+import (
+	"fmt"
+	"github.com/nalej/derrors"
+	"math"
+	"strings"
+)
+
+// Unit of a hardware or storage value reported by an agent or a device.
+type Unit string
+
+const (
+	Bytes     Unit = "B"
+	Kilobytes Unit = "KB"
+	Megabytes Unit = "MB"
+	Gigabytes Unit = "GB"
+)
+
+// DefaultUnit is the unit the agents use to report the RAM and the storage.
+const DefaultUnit = Megabytes
+
+// CanonicalUnit is the unit of the normalized values.
+const CanonicalUnit = "bytes"
+
+// BytesPerMB is used to report the normalized values in MB.
+const BytesPerMB = 1024 * 1024
+
+var multipliers = map[Unit]int64{
+	Bytes:     1,
+	Kilobytes: 1024,
+	Megabytes: 1024 * 1024,
+	Gigabytes: 1024 * 1024 * 1024,
+}
+
+// scale with the units from the smallest to the largest.
+var scale = []Unit{Bytes, Kilobytes, Megabytes, Gigabytes}
+
+// Range of plausible values in bytes.
+type Range struct {
+	Min int64
+	Max int64
+}
+
+// ResourceRanges with the plausible installed RAM and capacity of a storage device of a type of entity. The ranges
+// are narrow enough for a value reported in a unit 1024 times smaller or larger than the declared one to fall out
+// of them in the usual sizes, e.g. 16 GB of RAM reported in KB read as MB is 16 TB.
+type ResourceRanges struct {
+	RAM     Range
+	Storage Range
+}
+
+const (
+	kb = int64(1024)
+	mb = 1024 * kb
+	gb = 1024 * mb
+	tb = 1024 * gb
+)
+
+// HostRanges with the plausible resources of the assets and edge controllers: from 128 MB to 1 TB of RAM and
+// storage devices from 128 MB to 32 TB.
+var HostRanges = ResourceRanges{
+	RAM:     Range{Min: 128 * mb, Max: tb},
+	Storage: Range{Min: 128 * mb, Max: 32 * tb},
+}
+
+// DeviceRanges with the plausible resources of the devices: from 64 KB to 64 GB of RAM and storage devices from
+// 64 KB to 16 TB.
+var DeviceRanges = ResourceRanges{
+	RAM:     Range{Min: 64 * kb, Max: 64 * gb},
+	Storage: Range{Min: 64 * kb, Max: 16 * tb},
+}
+
+// Contains checks if a value in bytes is plausible.
+func (r Range) Contains(bytes int64) bool {
+	return bytes >= r.Min && bytes <= r.Max
+}
+
+// ParseUnit parses the name of a unit, case insensitive.
+func ParseUnit(name string) (Unit, derrors.Error) {
+	unit := Unit(strings.ToUpper(strings.TrimSpace(name)))
+	if _, exists := multipliers[unit]; !exists {
+		return "", derrors.NewInvalidArgumentError("unknown unit").WithParams(name)
+	}
+	return unit, nil
+}
+
+// ToBytes converts a value to bytes. It returns false if the result does not fit in an int64.
+func ToBytes(value int64, unit Unit) (int64, bool) {
+	multiplier, exists := multipliers[unit]
+	if !exists || value > math.MaxInt64/multiplier {
+		return 0, false
+	}
+	return value * multiplier, true
+}
+
+// Normalization with the result of converting a value to bytes.
+type Normalization struct {
+	// Bytes with the normalized value.
+	Bytes int64
+	// Unit in which the value is considered to be reported.
+	Unit Unit
+	// Plausible is false if the value is out of range in the declared unit.
+	Plausible bool
+}
+
+// Warning describes why a normalization is not plausible.
+func (n Normalization) Warning(field string, value int64, declared Unit) string {
+	if n.Unit != declared {
+		return fmt.Sprintf("%s of %d %s is out of range, assuming %s", field, value, declared, n.Unit)
+	}
+	return fmt.Sprintf("%s of %d %s is out of range", field, value, declared)
+}
+
+// inferenceOrder returns the units to try when a value is not plausible in the declared unit. The closest units
+// come first as the usual mistake is a factor of 1024, and the smaller one is tried first.
+func inferenceOrder(declared Unit) []Unit {
+	position := 0
+	for index, unit := range scale {
+		if unit == declared {
+			position = index
+		}
+	}
+	result := make([]Unit, 0, len(scale)-1)
+	for distance := 1; distance < len(scale); distance++ {
+		if position-distance >= 0 {
+			result = append(result, scale[position-distance])
+		}
+		if position+distance < len(scale) {
+			result = append(result, scale[position+distance])
+		}
+	}
+	return result
+}
+
+// Normalize converts a value reported in the declared unit to bytes. If the result is out of the plausible range
+// the closest unit that gives a plausible value is used instead and the normalization is flagged. Zero values mean
+// the information is not available and are not flagged.
+func Normalize(value int64, declared Unit, plausible Range) Normalization {
+	bytes, fits := ToBytes(value, declared)
+	if value <= 0 || (fits && plausible.Contains(bytes)) {
+		return Normalization{Bytes: bytes, Unit: declared, Plausible: true}
+	}
+	for _, unit := range inferenceOrder(declared) {
+		if inferred, fits := ToBytes(value, unit); fits && plausible.Contains(inferred) {
+			return Normalization{Bytes: inferred, Unit: unit, Plausible: false}
+		}
+	}
+	if !fits {
+		bytes = math.MaxInt64
+	}
+	return Normalization{Bytes: bytes, Unit: declared, Plausible: false}
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package units
+
+import (
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"math"
+)
+
+var _ = ginkgo.Describe("Units", func() {
+
+	ginkgo.It("should convert plausible values in the declared unit", func() {
+		result := Normalize(4096, Megabytes, HostRanges.RAM)
+		gomega.Expect(result).To(gomega.Equal(Normalization{Bytes: 4096 * 1024 * 1024, Unit: Megabytes, Plausible: true}))
+	})
+
+	ginkgo.It("should not flag missing values", func() {
+		result := Normalize(0, Megabytes, HostRanges.RAM)
+		gomega.Expect(result.Plausible).To(gomega.BeTrue())
+		gomega.Expect(result.Bytes).To(gomega.BeZero())
+	})
+
+	ginkgo.It("should infer the unit of values out of range", func() {
+		result := Normalize(8*1024*1024*1024, Megabytes, HostRanges.RAM)
+		gomega.Expect(result).To(gomega.Equal(Normalization{Bytes: 8 * 1024 * 1024 * 1024, Unit: Bytes, Plausible: false}))
+		result = Normalize(2*1024*1024*1024*1024, Megabytes, HostRanges.Storage)
+		gomega.Expect(result).To(gomega.Equal(Normalization{Bytes: 2 * 1024 * 1024 * 1024 * 1024, Unit: Bytes, Plausible: false}))
+	})
+
+	ginkgo.It("should detect the values reported in KB as MB", func() {
+		// 16 GB of RAM in KB read as MB would be 16 TB
+		result := Normalize(16*1024*1024, Megabytes, HostRanges.RAM)
+		gomega.Expect(result).To(gomega.Equal(Normalization{Bytes: 16 * 1024 * 1024 * 1024, Unit: Kilobytes, Plausible: false}))
+		// 512 MB of RAM of a device in KB read as MB would be 512 GB
+		result = Normalize(512*1024, Megabytes, DeviceRanges.RAM)
+		gomega.Expect(result).To(gomega.Equal(Normalization{Bytes: 512 * 1024 * 1024, Unit: Kilobytes, Plausible: false}))
+		// 500 GB of storage in KB read as MB would be 500 TB
+		result = Normalize(500*1024*1024, Megabytes, HostRanges.Storage)
+		gomega.Expect(result).To(gomega.Equal(Normalization{Bytes: 500 * 1024 * 1024 * 1024, Unit: Kilobytes, Plausible: false}))
+	})
+
+	ginkgo.It("should detect the values reported in MB as GB", func() {
+		// 16 GB of RAM in MB read as GB would be 16 TB
+		result := Normalize(16*1024, Gigabytes, HostRanges.RAM)
+		gomega.Expect(result).To(gomega.Equal(Normalization{Bytes: 16 * 1024 * 1024 * 1024, Unit: Megabytes, Plausible: false}))
+		// 2 GB of RAM of a device in MB read as GB would be 2 TB
+		result = Normalize(2*1024, Gigabytes, DeviceRanges.RAM)
+		gomega.Expect(result).To(gomega.Equal(Normalization{Bytes: 2 * 1024 * 1024 * 1024, Unit: Megabytes, Plausible: false}))
+	})
+
+	ginkgo.It("should flag values out of range in any unit", func() {
+		result := Normalize(math.MaxInt64, Gigabytes, HostRanges.RAM)
+		gomega.Expect(result.Plausible).To(gomega.BeFalse())
+		gomega.Expect(result.Unit).To(gomega.Equal(Gigabytes))
+		gomega.Expect(result.Bytes).To(gomega.Equal(int64(math.MaxInt64)))
+	})
+
+	ginkgo.It("should parse the unit names", func() {
+		unit, err := ParseUnit(" kb")
+		gomega.Expect(err).To(gomega.Succeed())
+		gomega.Expect(unit).To(gomega.Equal(Kilobytes))
+		_, err = ParseUnit("MiB")
+		gomega.Expect(err).NotTo(gomega.Succeed())
+	})
+})
@@ -0,0 +1,177 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"github.com/nalej/grpc-inventory-manager-go"
+	"sync"
+	"time"
+)
+
+// SubscriptionBufferSize with the number of events that can be pending to be sent to a watcher. The watchers that
+// fall further behind are disconnected and have to resume from their last revision.
+const SubscriptionBufferSize = 256
+
+// Broadcaster assigns a revision to the inventory events and sends them to the watchers of the organization. The
+// last events of each organization are kept so a watcher can resume from the last revision it received.
+type Broadcaster struct {
+	sync.Mutex
+	bufferSize int
+	// firstRevision is the revision before the first event published by this process. It is taken from the clock
+	// so the revisions keep increasing after a restart, and the revisions of a previous process can be detected.
+	firstRevision int64
+	// revision of the last event published.
+	revision int64
+	// organizations indexed by organization_id.
+	organizations map[string]*organizationEvents
+	// closed is set when the broadcaster is closed on shutdown.
+	closed bool
+}
+
+type organizationEvents struct {
+	// events with the last revisions of the organization.
+	events []*grpc_inventory_manager_go.InventoryEvent
+	// dropped with the revision of the last event removed from the buffer.
+	dropped     int64
+	subscribers map[*Subscription]bool
+}
+
+// Subscription receives the events of an organization. The channel is closed when the watcher is too slow.
+type Subscription struct {
+	organizationID string
+	Events         chan *grpc_inventory_manager_go.InventoryEvent
+}
+
+// NewBroadcaster creates a broadcaster that keeps bufferSize events per organization.
+func NewBroadcaster(bufferSize int) *Broadcaster {
+	revision := time.Now().UnixNano()
+	return &Broadcaster{
+		bufferSize:    bufferSize,
+		firstRevision: revision,
+		revision:      revision,
+		organizations: make(map[string]*organizationEvents, 0),
+	}
+}
+
+// organization returns the events of an organization creating them if needed. The lock must be held.
+func (b *Broadcaster) organization(organizationID string) *organizationEvents {
+	org, exists := b.organizations[organizationID]
+	if !exists {
+		org = &organizationEvents{
+			events:      make([]*grpc_inventory_manager_go.InventoryEvent, 0),
+			subscribers: make(map[*Subscription]bool, 0),
+		}
+		b.organizations[organizationID] = org
+	}
+	return org
+}
+
+// Publish assigns the next revision to an event and sends it to the watchers of its organization.
+func (b *Broadcaster) Publish(event *grpc_inventory_manager_go.InventoryEvent) {
+	b.Lock()
+	defer b.Unlock()
+	b.revision++
+	event.Revision = b.revision
+	event.Timestamp = time.Now().Unix()
+
+	org := b.organization(event.OrganizationId)
+	org.events = append(org.events, event)
+	if len(org.events) > b.bufferSize {
+		removed := len(org.events) - b.bufferSize
+		org.dropped = org.events[removed-1].Revision
+		org.events = append([]*grpc_inventory_manager_go.InventoryEvent{}, org.events[removed:]...)
+	}
+
+	for subscription := range org.subscribers {
+		select {
+		case subscription.Events <- event:
+		default:
+			delete(org.subscribers, subscription)
+			close(subscription.Events)
+		}
+	}
+}
+
+// Subscribe registers a watcher of an organization. The events after fromRevision that are still kept are
+// returned to be sent before the ones received by the subscription. If those events are not available, a
+// RESYNC_REQUIRED event is returned instead so the watcher retrieves the whole inventory again. A zero
+// fromRevision only subscribes to the new events.
+func (b *Broadcaster) Subscribe(organizationID string, fromRevision int64) (*Subscription, []*grpc_inventory_manager_go.InventoryEvent) {
+	b.Lock()
+	defer b.Unlock()
+	org := b.organization(organizationID)
+	subscription := &Subscription{
+		organizationID: organizationID,
+		Events:         make(chan *grpc_inventory_manager_go.InventoryEvent, SubscriptionBufferSize),
+	}
+	if b.closed {
+		close(subscription.Events)
+		return subscription, nil
+	}
+	org.subscribers[subscription] = true
+
+	if fromRevision == 0 {
+		return subscription, nil
+	}
+	if fromRevision < b.firstRevision || fromRevision > b.revision || fromRevision < org.dropped {
+		return subscription, []*grpc_inventory_manager_go.InventoryEvent{{
+			OrganizationId: organizationID,
+			Revision:       b.revision,
+			Timestamp:      time.Now().Unix(),
+			Type:           grpc_inventory_manager_go.InventoryEventType_RESYNC_REQUIRED,
+		}}
+	}
+	backlog := make([]*grpc_inventory_manager_go.InventoryEvent, 0)
+	for _, event := range org.events {
+		if event.Revision > fromRevision {
+			backlog = append(backlog, event)
+		}
+	}
+	return subscription, backlog
+}
+
+// Unsubscribe removes a watcher.
+func (b *Broadcaster) Unsubscribe(subscription *Subscription) {
+	b.Lock()
+	defer b.Unlock()
+	org := b.organization(subscription.organizationID)
+	if _, exists := org.subscribers[subscription]; exists {
+		delete(org.subscribers, subscription)
+		close(subscription.Events)
+	}
+}
+
+// Close closes the subscriptions of all the watchers so their streams finish on shutdown. The subscriptions
+// created afterwards are already closed.
+func (b *Broadcaster) Close() {
+	b.Lock()
+	defer b.Unlock()
+	b.closed = true
+	for _, org := range b.organizations {
+		for subscription := range org.subscribers {
+			delete(org.subscribers, subscription)
+			close(subscription.Events)
+		}
+	}
+}
+
+// Closed returns whether the broadcaster has been closed.
+func (b *Broadcaster) Closed() bool {
+	b.Lock()
+	defer b.Unlock()
+	return b.closed
+}
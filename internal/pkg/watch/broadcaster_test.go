@@ -0,0 +1,100 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Broadcaster", func() {
+
+	const organizationID = "org"
+
+	var broadcaster *Broadcaster
+
+	publish := func(organizationID string, entityID string) *grpc_inventory_manager_go.InventoryEvent {
+		event := &grpc_inventory_manager_go.InventoryEvent{
+			OrganizationId: organizationID,
+			Type:           grpc_inventory_manager_go.InventoryEventType_ASSET_ADDED,
+			EntityId:       entityID,
+		}
+		broadcaster.Publish(event)
+		return event
+	}
+
+	ginkgo.BeforeEach(func() {
+		broadcaster = NewBroadcaster(2)
+	})
+
+	ginkgo.It("should send the new events to the watchers of the organization", func() {
+		subscription, backlog := broadcaster.Subscribe(organizationID, 0)
+		gomega.Expect(backlog).To(gomega.BeEmpty())
+		publish("other", "asset0")
+		event := publish(organizationID, "asset1")
+		gomega.Expect(subscription.Events).To(gomega.Receive(gomega.Equal(event)))
+		gomega.Expect(subscription.Events).ShouldNot(gomega.Receive())
+		broadcaster.Unsubscribe(subscription)
+		gomega.Expect(subscription.Events).To(gomega.BeClosed())
+	})
+
+	ginkgo.It("should resume from a revision", func() {
+		first := publish(organizationID, "asset1")
+		second := publish(organizationID, "asset2")
+		gomega.Expect(second.Revision).To(gomega.BeNumerically(">", first.Revision))
+		_, backlog := broadcaster.Subscribe(organizationID, first.Revision)
+		gomega.Expect(backlog).To(gomega.Equal([]*grpc_inventory_manager_go.InventoryEvent{second}))
+	})
+
+	ginkgo.It("should require a resync when the events are no longer available", func() {
+		first := publish(organizationID, "asset1")
+		publish(organizationID, "asset2")
+		publish(organizationID, "asset3")
+		publish(organizationID, "asset4")
+		_, backlog := broadcaster.Subscribe(organizationID, first.Revision)
+		gomega.Expect(backlog).To(gomega.HaveLen(1))
+		gomega.Expect(backlog[0].Type).To(gomega.Equal(grpc_inventory_manager_go.InventoryEventType_RESYNC_REQUIRED))
+
+		_, backlog = broadcaster.Subscribe(organizationID, 1)
+		gomega.Expect(backlog[0].Type).To(gomega.Equal(grpc_inventory_manager_go.InventoryEventType_RESYNC_REQUIRED))
+	})
+
+	ginkgo.It("should disconnect the watchers that fall behind", func() {
+		subscription, _ := broadcaster.Subscribe(organizationID, 0)
+		for index := 0; index <= SubscriptionBufferSize; index++ {
+			publish(organizationID, "asset")
+		}
+		for index := 0; index < SubscriptionBufferSize; index++ {
+			<-subscription.Events
+		}
+		gomega.Expect(subscription.Events).To(gomega.BeClosed())
+		broadcaster.Unsubscribe(subscription)
+	})
+
+	ginkgo.It("should close the subscriptions on shutdown", func() {
+		subscription, _ := broadcaster.Subscribe(organizationID, 0)
+		broadcaster.Close()
+		gomega.Expect(broadcaster.Closed()).To(gomega.BeTrue())
+		gomega.Expect(subscription.Events).To(gomega.BeClosed())
+		broadcaster.Unsubscribe(subscription)
+
+		late, _ := broadcaster.Subscribe(organizationID, 0)
+		gomega.Expect(late.Events).To(gomega.BeClosed())
+		publish(organizationID, "asset1")
+	})
+})
@@ -0,0 +1,221 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"context"
+	"github.com/nalej/grpc-common-go"
+	"github.com/nalej/grpc-device-go"
+	"github.com/nalej/grpc-device-manager-go"
+	"github.com/nalej/grpc-inventory-go"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/inventory-manager/internal/pkg/entities"
+	"google.golang.org/grpc"
+)
+
+// The clients below wrap the clients of system-model and the device manager so the modifications made by the
+// inventory manager, either requested through the API or triggered by a bus message, are published as events.
+
+func newOperationResult(operationID string, timestamp int64, status grpc_inventory_go.OpStatus, info string) *grpc_inventory_manager_go.OperationResult {
+	return &grpc_inventory_manager_go.OperationResult{
+		OperationId: operationID,
+		Timestamp:   timestamp,
+		Status:      status,
+		Info:        info,
+	}
+}
+
+type assetsClient struct {
+	grpc_inventory_go.AssetsClient
+	broadcaster *Broadcaster
+	monitor     *StatusMonitor
+	history     *OperationHistory
+}
+
+// NewAssetsClient returns an assets client that publishes the modifications of the assets and records the results
+// of their operations.
+func NewAssetsClient(client grpc_inventory_go.AssetsClient, broadcaster *Broadcaster, monitor *StatusMonitor, history *OperationHistory) grpc_inventory_go.AssetsClient {
+	return &assetsClient{AssetsClient: client, broadcaster: broadcaster, monitor: monitor, history: history}
+}
+
+func (ac *assetsClient) Add(ctx context.Context, in *grpc_inventory_go.AddAssetRequest, opts ...grpc.CallOption) (*grpc_inventory_go.Asset, error) {
+	added, err := ac.AssetsClient.Add(ctx, in, opts...)
+	if err == nil {
+		ac.broadcaster.Publish(&grpc_inventory_manager_go.InventoryEvent{
+			OrganizationId:   in.OrganizationId,
+			Type:             grpc_inventory_manager_go.InventoryEventType_ASSET_ADDED,
+			EntityType:       grpc_inventory_manager_go.InventoryEntityType_ASSET,
+			EntityId:         added.AssetId,
+			EdgeControllerId: added.EdgeControllerId,
+		})
+	}
+	return added, err
+}
+
+func (ac *assetsClient) Remove(ctx context.Context, in *grpc_inventory_go.AssetId, opts ...grpc.CallOption) (*grpc_common_go.Success, error) {
+	success, err := ac.AssetsClient.Remove(ctx, in, opts...)
+	if err == nil {
+		ac.monitor.Remove(grpc_inventory_manager_go.InventoryEntityType_ASSET, in.OrganizationId, in.AssetId)
+		ac.broadcaster.Publish(&grpc_inventory_manager_go.InventoryEvent{
+			OrganizationId: in.OrganizationId,
+			Type:           grpc_inventory_manager_go.InventoryEventType_ASSET_REMOVED,
+			EntityType:     grpc_inventory_manager_go.InventoryEntityType_ASSET,
+			EntityId:       in.AssetId,
+		})
+	}
+	return success, err
+}
+
+func (ac *assetsClient) Update(ctx context.Context, in *grpc_inventory_go.UpdateAssetRequest, opts ...grpc.CallOption) (*grpc_inventory_go.Asset, error) {
+	updated, err := ac.AssetsClient.Update(ctx, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if entities.IsAssetAliveUpdate(in) {
+		ac.monitor.Alive(grpc_inventory_manager_go.InventoryEntityType_ASSET, in.OrganizationId, in.AssetId,
+			updated.EdgeControllerId, in.LastAliveTimestamp)
+		return updated, nil
+	}
+	event := &grpc_inventory_manager_go.InventoryEvent{
+		OrganizationId:   in.OrganizationId,
+		Type:             grpc_inventory_manager_go.InventoryEventType_ASSET_UPDATED,
+		EntityType:       grpc_inventory_manager_go.InventoryEntityType_ASSET,
+		EntityId:         in.AssetId,
+		EdgeControllerId: updated.EdgeControllerId,
+	}
+	if in.UpdateLastOpSummary && in.LastOpSummary != nil {
+		event.Type = grpc_inventory_manager_go.InventoryEventType_OPERATION_RESULT
+		event.Operation = newOperationResult(in.LastOpSummary.OperationId, in.LastOpSummary.Timestamp,
+			in.LastOpSummary.Status, in.LastOpSummary.Info)
+	}
+	ac.broadcaster.Publish(event)
+	ac.history.Record(event)
+	return updated, nil
+}
+
+type controllersClient struct {
+	grpc_inventory_go.ControllersClient
+	broadcaster *Broadcaster
+	monitor     *StatusMonitor
+	history     *OperationHistory
+}
+
+// NewControllersClient returns a controllers client that publishes the modifications of the controllers and records
+// the results of their operations.
+func NewControllersClient(client grpc_inventory_go.ControllersClient, broadcaster *Broadcaster, monitor *StatusMonitor, history *OperationHistory) grpc_inventory_go.ControllersClient {
+	return &controllersClient{ControllersClient: client, broadcaster: broadcaster, monitor: monitor, history: history}
+}
+
+func (cc *controllersClient) Add(ctx context.Context, in *grpc_inventory_go.AddEdgeControllerRequest, opts ...grpc.CallOption) (*grpc_inventory_go.EdgeController, error) {
+	added, err := cc.ControllersClient.Add(ctx, in, opts...)
+	if err == nil {
+		cc.broadcaster.Publish(&grpc_inventory_manager_go.InventoryEvent{
+			OrganizationId:   in.OrganizationId,
+			Type:             grpc_inventory_manager_go.InventoryEventType_CONTROLLER_ADDED,
+			EntityType:       grpc_inventory_manager_go.InventoryEntityType_CONTROLLER,
+			EntityId:         added.EdgeControllerId,
+			EdgeControllerId: added.EdgeControllerId,
+		})
+	}
+	return added, err
+}
+
+func (cc *controllersClient) Remove(ctx context.Context, in *grpc_inventory_go.EdgeControllerId, opts ...grpc.CallOption) (*grpc_common_go.Success, error) {
+	success, err := cc.ControllersClient.Remove(ctx, in, opts...)
+	if err == nil {
+		cc.monitor.Remove(grpc_inventory_manager_go.InventoryEntityType_CONTROLLER, in.OrganizationId, in.EdgeControllerId)
+		cc.history.Remove(in.OrganizationId, in.EdgeControllerId)
+		cc.broadcaster.Publish(&grpc_inventory_manager_go.InventoryEvent{
+			OrganizationId:   in.OrganizationId,
+			Type:             grpc_inventory_manager_go.InventoryEventType_CONTROLLER_REMOVED,
+			EntityType:       grpc_inventory_manager_go.InventoryEntityType_CONTROLLER,
+			EntityId:         in.EdgeControllerId,
+			EdgeControllerId: in.EdgeControllerId,
+		})
+	}
+	return success, err
+}
+
+func (cc *controllersClient) Update(ctx context.Context, in *grpc_inventory_go.UpdateEdgeControllerRequest, opts ...grpc.CallOption) (*grpc_inventory_go.EdgeController, error) {
+	updated, err := cc.ControllersClient.Update(ctx, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if entities.IsControllerAliveUpdate(in) {
+		cc.monitor.Alive(grpc_inventory_manager_go.InventoryEntityType_CONTROLLER, in.OrganizationId, in.EdgeControllerId,
+			in.EdgeControllerId, in.LastAliveTimestamp)
+		return updated, nil
+	}
+	event := &grpc_inventory_manager_go.InventoryEvent{
+		OrganizationId:   in.OrganizationId,
+		Type:             grpc_inventory_manager_go.InventoryEventType_CONTROLLER_UPDATED,
+		EntityType:       grpc_inventory_manager_go.InventoryEntityType_CONTROLLER,
+		EntityId:         in.EdgeControllerId,
+		EdgeControllerId: in.EdgeControllerId,
+	}
+	if in.UpdateLastOpSummary && in.LastOpSummary != nil {
+		event.Type = grpc_inventory_manager_go.InventoryEventType_OPERATION_RESULT
+		event.Operation = newOperationResult(in.LastOpSummary.OperationId, in.LastOpSummary.Timestamp,
+			in.LastOpSummary.Status, in.LastOpSummary.Info)
+	}
+	cc.broadcaster.Publish(event)
+	cc.history.Record(event)
+	return updated, nil
+}
+
+type devicesClient struct {
+	grpc_device_manager_go.DevicesClient
+	broadcaster *Broadcaster
+}
+
+// NewDevicesClient returns a device manager client that publishes the modifications of the devices.
+func NewDevicesClient(client grpc_device_manager_go.DevicesClient, broadcaster *Broadcaster) grpc_device_manager_go.DevicesClient {
+	return &devicesClient{DevicesClient: client, broadcaster: broadcaster}
+}
+
+func (dc *devicesClient) publish(eventType grpc_inventory_manager_go.InventoryEventType, organizationID string, deviceGroupID string, deviceID string) {
+	dc.broadcaster.Publish(&grpc_inventory_manager_go.InventoryEvent{
+		OrganizationId: organizationID,
+		Type:           eventType,
+		EntityType:     grpc_inventory_manager_go.InventoryEntityType_DEVICE,
+		EntityId:       entities.GetAssetDeviceID(deviceGroupID, deviceID),
+	})
+}
+
+func (dc *devicesClient) UpdateDevice(ctx context.Context, in *grpc_device_manager_go.UpdateDeviceRequest, opts ...grpc.CallOption) (*grpc_device_manager_go.Device, error) {
+	updated, err := dc.DevicesClient.UpdateDevice(ctx, in, opts...)
+	if err == nil {
+		dc.publish(grpc_inventory_manager_go.InventoryEventType_DEVICE_UPDATED, in.OrganizationId, in.DeviceGroupId, in.DeviceId)
+	}
+	return updated, err
+}
+
+func (dc *devicesClient) UpdateDeviceLocation(ctx context.Context, in *grpc_device_manager_go.UpdateDeviceLocationRequest, opts ...grpc.CallOption) (*grpc_device_manager_go.Device, error) {
+	updated, err := dc.DevicesClient.UpdateDeviceLocation(ctx, in, opts...)
+	if err == nil {
+		dc.publish(grpc_inventory_manager_go.InventoryEventType_DEVICE_UPDATED, in.OrganizationId, in.DeviceGroupId, in.DeviceId)
+	}
+	return updated, err
+}
+
+func (dc *devicesClient) RemoveDevice(ctx context.Context, in *grpc_device_go.DeviceId, opts ...grpc.CallOption) (*grpc_common_go.Success, error) {
+	success, err := dc.DevicesClient.RemoveDevice(ctx, in, opts...)
+	if err == nil {
+		dc.publish(grpc_inventory_manager_go.InventoryEventType_DEVICE_REMOVED, in.OrganizationId, in.DeviceGroupId, in.DeviceId)
+	}
+	return success, err
+}
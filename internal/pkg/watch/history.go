@@ -0,0 +1,74 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/operation"
+	"github.com/rs/zerolog/log"
+)
+
+// OperationHistory keeps the latest operation results of the edge controllers and their assets.
+type OperationHistory struct {
+	provider operation.Provider
+}
+
+// NewOperationHistory creates a history stored in the given provider.
+func NewOperationHistory(provider operation.Provider) *OperationHistory {
+	return &OperationHistory{
+		provider: provider,
+	}
+}
+
+// Record stores the operation of an OPERATION_RESULT event, discarding the oldest one if the history is full.
+func (h *OperationHistory) Record(event *grpc_inventory_manager_go.InventoryEvent) {
+	if event.Type != grpc_inventory_manager_go.InventoryEventType_OPERATION_RESULT || event.Operation == nil || event.EdgeControllerId == "" {
+		return
+	}
+	err := h.provider.Add(event.OrganizationId, event.EdgeControllerId, &grpc_inventory_manager_go.OperationRecord{
+		EntityType: event.EntityType,
+		EntityId:   event.EntityId,
+		Operation:  event.Operation,
+	})
+	if err != nil {
+		log.Warn().Str("organization_id", event.OrganizationId).Str("edge_controller_id", event.EdgeControllerId).
+			Str("trace", err.DebugReport()).Msg("cannot record the operation result")
+	}
+}
+
+// List returns the operations of a controller and its assets, the most recent first.
+func (h *OperationHistory) List(organizationID string, edgeControllerID string) []*grpc_inventory_manager_go.OperationRecord {
+	operations, err := h.provider.List(organizationID, edgeControllerID)
+	if err != nil {
+		log.Warn().Str("organization_id", organizationID).Str("edge_controller_id", edgeControllerID).
+			Str("trace", err.DebugReport()).Msg("cannot read the operation history")
+	}
+	result := make([]*grpc_inventory_manager_go.OperationRecord, 0, len(operations))
+	for i := len(operations) - 1; i >= 0; i-- {
+		result = append(result, operations[i])
+	}
+	return result
+}
+
+// Remove discards the history of a controller.
+func (h *OperationHistory) Remove(organizationID string, edgeControllerID string) {
+	err := h.provider.Remove(organizationID, edgeControllerID)
+	if err != nil {
+		log.Warn().Str("organization_id", organizationID).Str("edge_controller_id", edgeControllerID).
+			Str("trace", err.DebugReport()).Msg("cannot remove the operation history")
+	}
+}
@@ -0,0 +1,81 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/inventory-manager/internal/pkg/provider/operation"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+	"io/ioutil"
+	"os"
+)
+
+var _ = ginkgo.Describe("OperationHistory", func() {
+
+	newEvent := func(edgeControllerID string, operationID string) *grpc_inventory_manager_go.InventoryEvent {
+		return &grpc_inventory_manager_go.InventoryEvent{
+			OrganizationId:   "org",
+			Type:             grpc_inventory_manager_go.InventoryEventType_OPERATION_RESULT,
+			EntityType:       grpc_inventory_manager_go.InventoryEntityType_ASSET,
+			EntityId:         "asset",
+			EdgeControllerId: edgeControllerID,
+			Operation:        &grpc_inventory_manager_go.OperationResult{OperationId: operationID},
+		}
+	}
+
+	checkHistory := func(history *OperationHistory) {
+		history.Record(newEvent("ec1", "op1"))
+		history.Record(newEvent("ec2", "op2"))
+		history.Record(newEvent("ec1", "op3"))
+		history.Record(newEvent("ec1", "op4"))
+		history.Record(&grpc_inventory_manager_go.InventoryEvent{
+			OrganizationId:   "org",
+			Type:             grpc_inventory_manager_go.InventoryEventType_ASSET_UPDATED,
+			EdgeControllerId: "ec1",
+		})
+		operations := history.List("org", "ec1")
+		gomega.Expect(operations).To(gomega.HaveLen(2))
+		gomega.Expect(operations[0].Operation.OperationId).To(gomega.Equal("op4"))
+		gomega.Expect(operations[1].Operation.OperationId).To(gomega.Equal("op3"))
+		gomega.Expect(history.List("other", "ec1")).To(gomega.BeEmpty())
+
+		history.Remove("org", "ec1")
+		gomega.Expect(history.List("org", "ec1")).To(gomega.BeEmpty())
+		gomega.Expect(history.List("org", "ec2")).To(gomega.HaveLen(1))
+	}
+
+	ginkgo.It("should keep the latest operations of each controller", func() {
+		checkHistory(NewOperationHistory(operation.NewMemoryProvider(2)))
+	})
+
+	ginkgo.It("should keep the operations in the storage path", func() {
+		storagePath, err := ioutil.TempDir("", "operations")
+		gomega.Expect(err).To(gomega.Succeed())
+		defer os.RemoveAll(storagePath)
+		provider, pErr := operation.NewFileProvider(storagePath, 2)
+		gomega.Expect(pErr).To(gomega.Succeed())
+		checkHistory(NewOperationHistory(provider))
+
+		// a new provider reads the histories stored before a restart
+		restarted, pErr := operation.NewFileProvider(storagePath, 2)
+		gomega.Expect(pErr).To(gomega.Succeed())
+		operations := NewOperationHistory(restarted).List("org", "ec2")
+		gomega.Expect(operations).To(gomega.HaveLen(1))
+		gomega.Expect(operations[0].Operation.OperationId).To(gomega.Equal("op2"))
+	})
+})
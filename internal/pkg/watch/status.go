@@ -0,0 +1,132 @@
+/*
+ * Copyright 2019 Nalej
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package watch
+
+import (
+	"context"
+	"github.com/nalej/grpc-inventory-manager-go"
+	"github.com/nalej/inventory-manager/internal/pkg/config"
+	"github.com/nalej/inventory-manager/internal/pkg/jobs"
+	"sync"
+	"time"
+)
+
+// StatusCheckPeriod time between two checks of the entities that stopped sending alive messages.
+const StatusCheckPeriod = time.Second * 30
+
+// StatusMonitor publishes the status transitions of the assets and controllers. The status is learnt from the alive
+// messages, so the first alive received by the process is not reported as a transition.
+type StatusMonitor struct {
+	sync.Mutex
+	broadcaster         *Broadcaster
+	assetThreshold      time.Duration
+	controllerThreshold time.Duration
+	// entities indexed by entity type, organization_id and entity identifier.
+	entities map[entityKey]*trackedEntity
+}
+
+type entityKey struct {
+	entityType     grpc_inventory_manager_go.InventoryEntityType
+	organizationID string
+	entityID       string
+}
+
+type trackedEntity struct {
+	edgeControllerID string
+	lastAlive        time.Time
+	online           bool
+}
+
+func NewStatusMonitor(broadcaster *Broadcaster, cfg config.Config) *StatusMonitor {
+	return &StatusMonitor{
+		broadcaster:         broadcaster,
+		assetThreshold:      cfg.AssetThreshold,
+		controllerThreshold: cfg.ControllerThreshold,
+		entities:            make(map[entityKey]*trackedEntity, 0),
+	}
+}
+
+// Run launches the periodic check of the entities that went offline.
+func (sm *StatusMonitor) Run(group *jobs.Group) {
+	group.Every(StatusCheckPeriod, func(ctx context.Context) {
+		sm.checkOffline(time.Now())
+	})
+}
+
+// Alive registers an alive message of an entity, publishing the transition if it was offline.
+func (sm *StatusMonitor) Alive(entityType grpc_inventory_manager_go.InventoryEntityType, organizationID string, entityID string,
+	edgeControllerID string, timestamp int64) {
+	sm.Lock()
+	defer sm.Unlock()
+	key := entityKey{entityType: entityType, organizationID: organizationID, entityID: entityID}
+	entity, exists := sm.entities[key]
+	if !exists {
+		sm.entities[key] = &trackedEntity{
+			edgeControllerID: edgeControllerID,
+			lastAlive:        time.Unix(timestamp, 0),
+			online:           true,
+		}
+		return
+	}
+	entity.lastAlive = time.Unix(timestamp, 0)
+	if edgeControllerID != "" {
+		entity.edgeControllerID = edgeControllerID
+	}
+	if !entity.online {
+		entity.online = true
+		sm.publish(key, entity)
+	}
+}
+
+// Remove stops tracking an entity.
+func (sm *StatusMonitor) Remove(entityType grpc_inventory_manager_go.InventoryEntityType, organizationID string, entityID string) {
+	sm.Lock()
+	defer sm.Unlock()
+	delete(sm.entities, entityKey{entityType: entityType, organizationID: organizationID, entityID: entityID})
+}
+
+// checkOffline publishes the transitions of the entities whose last alive is older than their threshold.
+func (sm *StatusMonitor) checkOffline(now time.Time) {
+	sm.Lock()
+	defer sm.Unlock()
+	for key, entity := range sm.entities {
+		threshold := sm.assetThreshold
+		if key.entityType == grpc_inventory_manager_go.InventoryEntityType_CONTROLLER {
+			threshold = sm.controllerThreshold
+		}
+		if entity.online && entity.lastAlive.Add(threshold).Before(now) {
+			entity.online = false
+			sm.publish(key, entity)
+		}
+	}
+}
+
+// publish sends the current status of an entity. The lock must be held.
+func (sm *StatusMonitor) publish(key entityKey, entity *trackedEntity) {
+	status := grpc_inventory_manager_go.ConnectedStatus_OFFLINE
+	if entity.online {
+		status = grpc_inventory_manager_go.ConnectedStatus_ONLINE
+	}
+	sm.broadcaster.Publish(&grpc_inventory_manager_go.InventoryEvent{
+		OrganizationId:   key.organizationID,
+		Type:             grpc_inventory_manager_go.InventoryEventType_STATUS_CHANGED,
+		EntityType:       key.entityType,
+		EntityId:         key.entityID,
+		EdgeControllerId: entity.edgeControllerID,
+		Status:           status,
+	})
+}